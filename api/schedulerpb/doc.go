@@ -0,0 +1,4 @@
+// Package schedulerpb is the gRPC interface of the scheduler.
+package schedulerpb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative api/schedulerpb/scheduler.proto
@@ -0,0 +1,911 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/schedulerpb/scheduler.proto
+
+package schedulerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TaskType int32
+
+const (
+	TaskType_TASK_TYPE_UNSPECIFIED TaskType = 0
+	TaskType_TASK_TYPE_BASIC       TaskType = 1
+	TaskType_TASK_TYPE_EXTENDED    TaskType = 2
+)
+
+// Enum value maps for TaskType.
+var (
+	TaskType_name = map[int32]string{
+		0: "TASK_TYPE_UNSPECIFIED",
+		1: "TASK_TYPE_BASIC",
+		2: "TASK_TYPE_EXTENDED",
+	}
+	TaskType_value = map[string]int32{
+		"TASK_TYPE_UNSPECIFIED": 0,
+		"TASK_TYPE_BASIC":       1,
+		"TASK_TYPE_EXTENDED":    2,
+	}
+)
+
+func (x TaskType) Enum() *TaskType {
+	p := new(TaskType)
+	*p = x
+	return p
+}
+
+func (x TaskType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_schedulerpb_scheduler_proto_enumTypes[0].Descriptor()
+}
+
+func (TaskType) Type() protoreflect.EnumType {
+	return &file_api_schedulerpb_scheduler_proto_enumTypes[0]
+}
+
+func (x TaskType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskType.Descriptor instead.
+func (TaskType) EnumDescriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{0}
+}
+
+type TaskState int32
+
+const (
+	TaskState_TASK_STATE_UNSPECIFIED TaskState = 0
+	TaskState_TASK_STATE_SUSPENDED   TaskState = 1
+	TaskState_TASK_STATE_READY       TaskState = 2
+	TaskState_TASK_STATE_RUNNING     TaskState = 3
+	TaskState_TASK_STATE_WAITING     TaskState = 4
+)
+
+// Enum value maps for TaskState.
+var (
+	TaskState_name = map[int32]string{
+		0: "TASK_STATE_UNSPECIFIED",
+		1: "TASK_STATE_SUSPENDED",
+		2: "TASK_STATE_READY",
+		3: "TASK_STATE_RUNNING",
+		4: "TASK_STATE_WAITING",
+	}
+	TaskState_value = map[string]int32{
+		"TASK_STATE_UNSPECIFIED": 0,
+		"TASK_STATE_SUSPENDED":   1,
+		"TASK_STATE_READY":       2,
+		"TASK_STATE_RUNNING":     3,
+		"TASK_STATE_WAITING":     4,
+	}
+)
+
+func (x TaskState) Enum() *TaskState {
+	p := new(TaskState)
+	*p = x
+	return p
+}
+
+func (x TaskState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskState) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_schedulerpb_scheduler_proto_enumTypes[1].Descriptor()
+}
+
+func (TaskState) Type() protoreflect.EnumType {
+	return &file_api_schedulerpb_scheduler_proto_enumTypes[1]
+}
+
+func (x TaskState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskState.Descriptor instead.
+func (TaskState) EnumDescriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{1}
+}
+
+type Task struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          TaskType               `protobuf:"varint,2,opt,name=type,proto3,enum=scheduler.v1.TaskType" json:"type,omitempty"`
+	Priority      int32                  `protobuf:"varint,3,opt,name=priority,proto3" json:"priority,omitempty"`
+	State         TaskState              `protobuf:"varint,4,opt,name=state,proto3,enum=scheduler.v1.TaskState" json:"state,omitempty"`
+	Progress      int32                  `protobuf:"varint,5,opt,name=progress,proto3" json:"progress,omitempty"`
+	ProgressLimit int32                  `protobuf:"varint,6,opt,name=progress_limit,json=progressLimit,proto3" json:"progress_limit,omitempty"`
+	SleepTime     *durationpb.Duration   `protobuf:"bytes,7,opt,name=sleep_time,json=sleepTime,proto3" json:"sleep_time,omitempty"`
+	Deadline      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	// Core the task is pinned to, or -1 for any core.
+	Affinity       int32  `protobuf:"varint,9,opt,name=affinity,proto3" json:"affinity,omitempty"`
+	Events         uint64 `protobuf:"varint,10,opt,name=events,proto3" json:"events,omitempty"`
+	Activations    int32  `protobuf:"varint,11,opt,name=activations,proto3" json:"activations,omitempty"`
+	MaxActivations int32  `protobuf:"varint,12,opt,name=max_activations,json=maxActivations,proto3" json:"max_activations,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Task) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Task) GetType() TaskType {
+	if x != nil {
+		return x.Type
+	}
+	return TaskType_TASK_TYPE_UNSPECIFIED
+}
+
+func (x *Task) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Task) GetState() TaskState {
+	if x != nil {
+		return x.State
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+func (x *Task) GetProgress() int32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *Task) GetProgressLimit() int32 {
+	if x != nil {
+		return x.ProgressLimit
+	}
+	return 0
+}
+
+func (x *Task) GetSleepTime() *durationpb.Duration {
+	if x != nil {
+		return x.SleepTime
+	}
+	return nil
+}
+
+func (x *Task) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *Task) GetAffinity() int32 {
+	if x != nil {
+		return x.Affinity
+	}
+	return 0
+}
+
+func (x *Task) GetEvents() uint64 {
+	if x != nil {
+		return x.Events
+	}
+	return 0
+}
+
+func (x *Task) GetActivations() int32 {
+	if x != nil {
+		return x.Activations
+	}
+	return 0
+}
+
+func (x *Task) GetMaxActivations() int32 {
+	if x != nil {
+		return x.MaxActivations
+	}
+	return 0
+}
+
+// SubmitTaskRequest describes a new task. Unset fields select the task
+// defaults.
+type SubmitTaskRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Type           TaskType               `protobuf:"varint,1,opt,name=type,proto3,enum=scheduler.v1.TaskType" json:"type,omitempty"`
+	Priority       int32                  `protobuf:"varint,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	ProgressLimit  int32                  `protobuf:"varint,3,opt,name=progress_limit,json=progressLimit,proto3" json:"progress_limit,omitempty"`
+	SleepTime      *durationpb.Duration   `protobuf:"bytes,4,opt,name=sleep_time,json=sleepTime,proto3" json:"sleep_time,omitempty"`
+	Deadline       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	Affinity       *int32                 `protobuf:"varint,6,opt,name=affinity,proto3,oneof" json:"affinity,omitempty"`
+	MaxActivations int32                  `protobuf:"varint,7,opt,name=max_activations,json=maxActivations,proto3" json:"max_activations,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SubmitTaskRequest) Reset() {
+	*x = SubmitTaskRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTaskRequest) ProtoMessage() {}
+
+func (x *SubmitTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTaskRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTaskRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubmitTaskRequest) GetType() TaskType {
+	if x != nil {
+		return x.Type
+	}
+	return TaskType_TASK_TYPE_UNSPECIFIED
+}
+
+func (x *SubmitTaskRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetProgressLimit() int32 {
+	if x != nil {
+		return x.ProgressLimit
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetSleepTime() *durationpb.Duration {
+	if x != nil {
+		return x.SleepTime
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *SubmitTaskRequest) GetAffinity() int32 {
+	if x != nil && x.Affinity != nil {
+		return *x.Affinity
+	}
+	return 0
+}
+
+func (x *SubmitTaskRequest) GetMaxActivations() int32 {
+	if x != nil {
+		return x.MaxActivations
+	}
+	return 0
+}
+
+type GetTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskRequest) Reset() {
+	*x = GetTaskRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskRequest) ProtoMessage() {}
+
+func (x *GetTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListTasksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksRequest) Reset() {
+	*x = ListTasksRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksRequest) ProtoMessage() {}
+
+func (x *ListTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksRequest.ProtoReflect.Descriptor instead.
+func (*ListTasksRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{3}
+}
+
+type ListTasksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tasks         []*Task                `protobuf:"bytes,1,rep,name=tasks,proto3" json:"tasks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksResponse) Reset() {
+	*x = ListTasksResponse{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksResponse) ProtoMessage() {}
+
+func (x *ListTasksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksResponse.ProtoReflect.Descriptor instead.
+func (*ListTasksResponse) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListTasksResponse) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+type CancelTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTaskRequest) Reset() {
+	*x = CancelTaskRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTaskRequest) ProtoMessage() {}
+
+func (x *CancelTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTaskRequest.ProtoReflect.Descriptor instead.
+func (*CancelTaskRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ActivateTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateTaskRequest) Reset() {
+	*x = ActivateTaskRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateTaskRequest) ProtoMessage() {}
+
+func (x *ActivateTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateTaskRequest.ProtoReflect.Descriptor instead.
+func (*ActivateTaskRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ActivateTaskRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type SetEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Mask          uint64                 `protobuf:"varint,2,opt,name=mask,proto3" json:"mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetEventRequest) Reset() {
+	*x = SetEventRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetEventRequest) ProtoMessage() {}
+
+func (x *SetEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetEventRequest.ProtoReflect.Descriptor instead.
+func (*SetEventRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SetEventRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SetEventRequest) GetMask() uint64 {
+	if x != nil {
+		return x.Mask
+	}
+	return 0
+}
+
+type WatchTransitionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTransitionsRequest) Reset() {
+	*x = WatchTransitionsRequest{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTransitionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTransitionsRequest) ProtoMessage() {}
+
+func (x *WatchTransitionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTransitionsRequest.ProtoReflect.Descriptor instead.
+func (*WatchTransitionsRequest) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{8}
+}
+
+type Transition struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Seq      uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Time     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	TaskId   int64                  `protobuf:"varint,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Priority int32                  `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Unspecified for a task added to the scheduler.
+	From TaskState `protobuf:"varint,5,opt,name=from,proto3,enum=scheduler.v1.TaskState" json:"from,omitempty"`
+	To   TaskState `protobuf:"varint,6,opt,name=to,proto3,enum=scheduler.v1.TaskState" json:"to,omitempty"`
+	// Core the task runs or ran on, or -1 for queue transitions.
+	Core          int32 `protobuf:"varint,7,opt,name=core,proto3" json:"core,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transition) Reset() {
+	*x = Transition{}
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transition) ProtoMessage() {}
+
+func (x *Transition) ProtoReflect() protoreflect.Message {
+	mi := &file_api_schedulerpb_scheduler_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transition.ProtoReflect.Descriptor instead.
+func (*Transition) Descriptor() ([]byte, []int) {
+	return file_api_schedulerpb_scheduler_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Transition) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Transition) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Transition) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *Transition) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Transition) GetFrom() TaskState {
+	if x != nil {
+		return x.From
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+func (x *Transition) GetTo() TaskState {
+	if x != nil {
+		return x.To
+	}
+	return TaskState_TASK_STATE_UNSPECIFIED
+}
+
+func (x *Transition) GetCore() int32 {
+	if x != nil {
+		return x.Core
+	}
+	return 0
+}
+
+var File_api_schedulerpb_scheduler_proto protoreflect.FileDescriptor
+
+const file_api_schedulerpb_scheduler_proto_rawDesc = "" +
+	"\n" +
+	"\x1fapi/schedulerpb/scheduler.proto\x12\fscheduler.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc1\x03\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12*\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x16.scheduler.v1.TaskTypeR\x04type\x12\x1a\n" +
+	"\bpriority\x18\x03 \x01(\x05R\bpriority\x12-\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x17.scheduler.v1.TaskStateR\x05state\x12\x1a\n" +
+	"\bprogress\x18\x05 \x01(\x05R\bprogress\x12%\n" +
+	"\x0eprogress_limit\x18\x06 \x01(\x05R\rprogressLimit\x128\n" +
+	"\n" +
+	"sleep_time\x18\a \x01(\v2\x19.google.protobuf.DurationR\tsleepTime\x126\n" +
+	"\bdeadline\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x1a\n" +
+	"\baffinity\x18\t \x01(\x05R\baffinity\x12\x16\n" +
+	"\x06events\x18\n" +
+	" \x01(\x04R\x06events\x12 \n" +
+	"\vactivations\x18\v \x01(\x05R\vactivations\x12'\n" +
+	"\x0fmax_activations\x18\f \x01(\x05R\x0emaxActivations\"\xcb\x02\n" +
+	"\x11SubmitTaskRequest\x12*\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x16.scheduler.v1.TaskTypeR\x04type\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\x05R\bpriority\x12%\n" +
+	"\x0eprogress_limit\x18\x03 \x01(\x05R\rprogressLimit\x128\n" +
+	"\n" +
+	"sleep_time\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\tsleepTime\x126\n" +
+	"\bdeadline\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x1f\n" +
+	"\baffinity\x18\x06 \x01(\x05H\x00R\baffinity\x88\x01\x01\x12'\n" +
+	"\x0fmax_activations\x18\a \x01(\x05R\x0emaxActivationsB\v\n" +
+	"\t_affinity\" \n" +
+	"\x0eGetTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x12\n" +
+	"\x10ListTasksRequest\"=\n" +
+	"\x11ListTasksResponse\x12(\n" +
+	"\x05tasks\x18\x01 \x03(\v2\x12.scheduler.v1.TaskR\x05tasks\"#\n" +
+	"\x11CancelTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"%\n" +
+	"\x13ActivateTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"5\n" +
+	"\x0fSetEventRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04mask\x18\x02 \x01(\x04R\x04mask\"\x19\n" +
+	"\x17WatchTransitionsRequest\"\xed\x01\n" +
+	"\n" +
+	"Transition\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x17\n" +
+	"\atask_id\x18\x03 \x01(\x03R\x06taskId\x12\x1a\n" +
+	"\bpriority\x18\x04 \x01(\x05R\bpriority\x12+\n" +
+	"\x04from\x18\x05 \x01(\x0e2\x17.scheduler.v1.TaskStateR\x04from\x12'\n" +
+	"\x02to\x18\x06 \x01(\x0e2\x17.scheduler.v1.TaskStateR\x02to\x12\x12\n" +
+	"\x04core\x18\a \x01(\x05R\x04core*R\n" +
+	"\bTaskType\x12\x19\n" +
+	"\x15TASK_TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fTASK_TYPE_BASIC\x10\x01\x12\x16\n" +
+	"\x12TASK_TYPE_EXTENDED\x10\x02*\x87\x01\n" +
+	"\tTaskState\x12\x1a\n" +
+	"\x16TASK_STATE_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14TASK_STATE_SUSPENDED\x10\x01\x12\x14\n" +
+	"\x10TASK_STATE_READY\x10\x02\x12\x16\n" +
+	"\x12TASK_STATE_RUNNING\x10\x03\x12\x16\n" +
+	"\x12TASK_STATE_WAITING\x10\x042\xf9\x03\n" +
+	"\tScheduler\x12A\n" +
+	"\n" +
+	"SubmitTask\x12\x1f.scheduler.v1.SubmitTaskRequest\x1a\x12.scheduler.v1.Task\x12;\n" +
+	"\aGetTask\x12\x1c.scheduler.v1.GetTaskRequest\x1a\x12.scheduler.v1.Task\x12L\n" +
+	"\tListTasks\x12\x1e.scheduler.v1.ListTasksRequest\x1a\x1f.scheduler.v1.ListTasksResponse\x12A\n" +
+	"\n" +
+	"CancelTask\x12\x1f.scheduler.v1.CancelTaskRequest\x1a\x12.scheduler.v1.Task\x12E\n" +
+	"\fActivateTask\x12!.scheduler.v1.ActivateTaskRequest\x1a\x12.scheduler.v1.Task\x12=\n" +
+	"\bSetEvent\x12\x1d.scheduler.v1.SetEventRequest\x1a\x12.scheduler.v1.Task\x12U\n" +
+	"\x10WatchTransitions\x12%.scheduler.v1.WatchTransitionsRequest\x1a\x18.scheduler.v1.Transition0\x01B\x1bZ\x19scheduler/api/schedulerpbb\x06proto3"
+
+var (
+	file_api_schedulerpb_scheduler_proto_rawDescOnce sync.Once
+	file_api_schedulerpb_scheduler_proto_rawDescData []byte
+)
+
+func file_api_schedulerpb_scheduler_proto_rawDescGZIP() []byte {
+	file_api_schedulerpb_scheduler_proto_rawDescOnce.Do(func() {
+		file_api_schedulerpb_scheduler_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_schedulerpb_scheduler_proto_rawDesc), len(file_api_schedulerpb_scheduler_proto_rawDesc)))
+	})
+	return file_api_schedulerpb_scheduler_proto_rawDescData
+}
+
+var file_api_schedulerpb_scheduler_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_schedulerpb_scheduler_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_api_schedulerpb_scheduler_proto_goTypes = []any{
+	(TaskType)(0),                   // 0: scheduler.v1.TaskType
+	(TaskState)(0),                  // 1: scheduler.v1.TaskState
+	(*Task)(nil),                    // 2: scheduler.v1.Task
+	(*SubmitTaskRequest)(nil),       // 3: scheduler.v1.SubmitTaskRequest
+	(*GetTaskRequest)(nil),          // 4: scheduler.v1.GetTaskRequest
+	(*ListTasksRequest)(nil),        // 5: scheduler.v1.ListTasksRequest
+	(*ListTasksResponse)(nil),       // 6: scheduler.v1.ListTasksResponse
+	(*CancelTaskRequest)(nil),       // 7: scheduler.v1.CancelTaskRequest
+	(*ActivateTaskRequest)(nil),     // 8: scheduler.v1.ActivateTaskRequest
+	(*SetEventRequest)(nil),         // 9: scheduler.v1.SetEventRequest
+	(*WatchTransitionsRequest)(nil), // 10: scheduler.v1.WatchTransitionsRequest
+	(*Transition)(nil),              // 11: scheduler.v1.Transition
+	(*durationpb.Duration)(nil),     // 12: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),   // 13: google.protobuf.Timestamp
+}
+var file_api_schedulerpb_scheduler_proto_depIdxs = []int32{
+	0,  // 0: scheduler.v1.Task.type:type_name -> scheduler.v1.TaskType
+	1,  // 1: scheduler.v1.Task.state:type_name -> scheduler.v1.TaskState
+	12, // 2: scheduler.v1.Task.sleep_time:type_name -> google.protobuf.Duration
+	13, // 3: scheduler.v1.Task.deadline:type_name -> google.protobuf.Timestamp
+	0,  // 4: scheduler.v1.SubmitTaskRequest.type:type_name -> scheduler.v1.TaskType
+	12, // 5: scheduler.v1.SubmitTaskRequest.sleep_time:type_name -> google.protobuf.Duration
+	13, // 6: scheduler.v1.SubmitTaskRequest.deadline:type_name -> google.protobuf.Timestamp
+	2,  // 7: scheduler.v1.ListTasksResponse.tasks:type_name -> scheduler.v1.Task
+	13, // 8: scheduler.v1.Transition.time:type_name -> google.protobuf.Timestamp
+	1,  // 9: scheduler.v1.Transition.from:type_name -> scheduler.v1.TaskState
+	1,  // 10: scheduler.v1.Transition.to:type_name -> scheduler.v1.TaskState
+	3,  // 11: scheduler.v1.Scheduler.SubmitTask:input_type -> scheduler.v1.SubmitTaskRequest
+	4,  // 12: scheduler.v1.Scheduler.GetTask:input_type -> scheduler.v1.GetTaskRequest
+	5,  // 13: scheduler.v1.Scheduler.ListTasks:input_type -> scheduler.v1.ListTasksRequest
+	7,  // 14: scheduler.v1.Scheduler.CancelTask:input_type -> scheduler.v1.CancelTaskRequest
+	8,  // 15: scheduler.v1.Scheduler.ActivateTask:input_type -> scheduler.v1.ActivateTaskRequest
+	9,  // 16: scheduler.v1.Scheduler.SetEvent:input_type -> scheduler.v1.SetEventRequest
+	10, // 17: scheduler.v1.Scheduler.WatchTransitions:input_type -> scheduler.v1.WatchTransitionsRequest
+	2,  // 18: scheduler.v1.Scheduler.SubmitTask:output_type -> scheduler.v1.Task
+	2,  // 19: scheduler.v1.Scheduler.GetTask:output_type -> scheduler.v1.Task
+	6,  // 20: scheduler.v1.Scheduler.ListTasks:output_type -> scheduler.v1.ListTasksResponse
+	2,  // 21: scheduler.v1.Scheduler.CancelTask:output_type -> scheduler.v1.Task
+	2,  // 22: scheduler.v1.Scheduler.ActivateTask:output_type -> scheduler.v1.Task
+	2,  // 23: scheduler.v1.Scheduler.SetEvent:output_type -> scheduler.v1.Task
+	11, // 24: scheduler.v1.Scheduler.WatchTransitions:output_type -> scheduler.v1.Transition
+	18, // [18:25] is the sub-list for method output_type
+	11, // [11:18] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_api_schedulerpb_scheduler_proto_init() }
+func file_api_schedulerpb_scheduler_proto_init() {
+	if File_api_schedulerpb_scheduler_proto != nil {
+		return
+	}
+	file_api_schedulerpb_scheduler_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_schedulerpb_scheduler_proto_rawDesc), len(file_api_schedulerpb_scheduler_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_schedulerpb_scheduler_proto_goTypes,
+		DependencyIndexes: file_api_schedulerpb_scheduler_proto_depIdxs,
+		EnumInfos:         file_api_schedulerpb_scheduler_proto_enumTypes,
+		MessageInfos:      file_api_schedulerpb_scheduler_proto_msgTypes,
+	}.Build()
+	File_api_schedulerpb_scheduler_proto = out.File
+	file_api_schedulerpb_scheduler_proto_goTypes = nil
+	file_api_schedulerpb_scheduler_proto_depIdxs = nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"scheduler/internal/clock"
+	"scheduler/internal/scheduler"
+	"slices"
+	"time"
+)
+
+var (
+	// errUsage reports invalid flags or arguments. The flag set has already
+	// printed the usage.
+	errUsage           = errors.New("invalid usage")
+	errUnknownPolicy   = errors.New("unknown policy")
+	errUnknownFormat   = errors.New("unknown output format")
+	errVirtualIdle     = errors.New("a virtual clock needs idle ticks to stop")
+	errUnknownOverflow = errors.New("unknown dump overflow")
+	errDroppedEvents   = errors.New("trace outputs miss dropped events")
+)
+
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: scheduler %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks that exactly nArgs positional arguments are
+// left.
+func parse(fs *flag.FlagSet, args []string, nArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() != nArgs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// schedulerFlags are the scheduler parameters shared by the commands that
+// run a scheduler.
+type schedulerFlags struct {
+	maxReady       int
+	readyLimit     int
+	priorityLevels int
+	tick           time.Duration
+	idleTicks      int
+	policy         string
+	slice          time.Duration
+	cores          int
+	partitioned    bool
+	dumpBuffer     int
+	dumpOverflow   string
+	journal        string
+	virtual        bool
+	logLevel       slog.Level
+}
+
+func (f *schedulerFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.maxReady, "max-ready", scheduler.DefaultMaxReadyTasks, "capacity of all ready queues together")
+	fs.IntVar(&f.readyLimit, "ready-limit", scheduler.DefaultReadyLimit, "number of ready tasks below which suspended tasks are admitted")
+	fs.IntVar(&f.priorityLevels, "priority-levels", scheduler.DefaultPriorityLevels, "number of priority levels")
+	fs.DurationVar(&f.tick, "tick", scheduler.DefaultTickDuration, "idle tick duration")
+	fs.IntVar(&f.idleTicks, "idle-ticks", scheduler.DefaultIdleTicks, "idle ticks after which the scheduler stops, 0 to run until interrupted")
+	fs.StringVar(&f.policy, "policy", "fp", "scheduling policy: fp (fixed priority), np (non-preemptive), rr (round robin) or edf")
+	fs.DurationVar(&f.slice, "slice", 100*time.Millisecond, "time slice of the rr policy")
+	fs.IntVar(&f.cores, "cores", scheduler.DefaultCores, "number of cores")
+	fs.BoolVar(&f.partitioned, "partitioned", false, "give every core its own ready queues")
+	fs.IntVar(&f.dumpBuffer, "dump-buffer", scheduler.DefaultDumpBuffer, "number of trace events waiting for the outputs")
+	fs.StringVar(&f.dumpOverflow, "dump-overflow", "block", "what a full dump buffer does: block the scheduler or drop events")
+	fs.StringVar(&f.journal, "journal", "", "append the transition journal to this file")
+	fs.BoolVar(&f.virtual, "virtual", false, "run on a virtual clock starting at the Unix epoch, taking no wall time")
+	fs.TextVar(&f.logLevel, "log-level", slog.LevelInfo, "level of the messages logged to stderr: debug, info, warn or error")
+}
+
+// options returns the scheduler options logging to stderr and a function that
+// closes the files they use.
+func (f *schedulerFlags) options(stderr io.Writer) ([]scheduler.Option, func() error, error) {
+	policy, err := parsePolicy(f.policy, f.slice)
+	if err != nil {
+		return nil, nil, err
+	}
+	overflow, err := parseOverflow(f.dumpOverflow)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []scheduler.Option{
+		scheduler.WithMaxReadyTasks(f.maxReady),
+		scheduler.WithReadyLimit(f.readyLimit),
+		scheduler.WithPriorityLevels(f.priorityLevels),
+		scheduler.WithTickDuration(f.tick),
+		scheduler.WithIdleExit(f.idleTicks),
+		scheduler.WithPolicy(policy),
+		scheduler.WithCores(f.cores),
+		scheduler.WithDumpBuffer(f.dumpBuffer),
+		scheduler.WithDumpOverflow(overflow),
+		scheduler.WithLogger(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: f.logLevel}))),
+	}
+	if f.partitioned {
+		opts = append(opts, scheduler.WithPartitionedQueues())
+	}
+	if f.virtual {
+		if f.idleTicks == 0 {
+			return nil, nil, errVirtualIdle
+		}
+		opts = append(opts, scheduler.WithClock(clock.NewVirtual(time.Unix(0, 0).UTC())))
+	}
+	closeFn := func() error { return nil }
+	if f.journal != "" {
+		j, err := os.OpenFile(f.journal, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, scheduler.WithJournal(j))
+		closeFn = j.Close
+	}
+	return opts, closeFn, nil
+}
+
+func parsePolicy(name string, slice time.Duration) (scheduler.Policy, error) {
+	switch name {
+	case "fp":
+		return scheduler.FixedPriority{}, nil
+	case "np":
+		return scheduler.NonPreemptive{}, nil
+	case "rr":
+		return scheduler.RoundRobin{Slice: slice}, nil
+	case "edf":
+		return scheduler.EDF{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownPolicy, name)
+	}
+}
+
+func parseOverflow(name string) (scheduler.DumpOverflow, error) {
+	switch name {
+	case "block":
+		return scheduler.DumpBlock, nil
+	case "drop":
+		return scheduler.DumpDrop, nil
+	}
+	return 0, fmt.Errorf("%w: %q", errUnknownOverflow, name)
+}
+
+func checkFormat(format string, formats ...string) error {
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("%w: %q", errUnknownFormat, format)
+	}
+	return nil
+}
@@ -1,35 +1,74 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"log/slog"
+	"io"
 	"os"
-	"scheduler/internal/generator"
-	"scheduler/internal/scheduler"
+	"os/signal"
 )
 
-func init() {
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
+// command is a subcommand of the CLI. Commands write their results to stdout
+// and logs to stderr.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string, stdout, stderr io.Writer) error
 }
 
-func main() {
-	s := scheduler.New()
+var commands = []command{
+	{name: "run", usage: "run the tasks of a tasks file", run: runCommand},
+	{name: "simulate", usage: "run randomly generated tasks", run: simulateCommand},
+	{name: "replay", usage: "print or convert a recorded trace", run: replayCommand},
+	{name: "validate", usage: "check a recorded trace against the scheduler invariants", run: validateCommand},
+}
 
-	s.Run()
+// errFailed reports a command that has already printed why it failed.
+var errFailed = errors.New("failed")
 
-	go func() {
-		for i := 0; i < 10; i++ {
-			t, err := generator.GenerateTask()
-			if err != nil {
-				log.Printf("failed to create task: %v\n", err)
-				continue
-			}
-			s.AddNewTask(t)
-		}
-	}()
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(execute(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	<-s.StopChan
-	fmt.Println("Симуляция завершена.")
+// execute runs the command named by args[0] and returns the exit code.
+func execute(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(ctx, args[1:], stdout, stderr)
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			return 2
+		case errors.Is(err, errFailed):
+			return 1
+		default:
+			fmt.Fprintf(stderr, "scheduler %s: %v\n", c.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "scheduler: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
 
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: scheduler <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "scheduler <command> -h" for the flags of a command.`)
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"scheduler/internal/validate"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fast keeps scheduler runs of the tests short.
+var fast = []string{"-tick", "5ms", "-idle-ticks", "2"}
+
+func execTest(t *testing.T, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := execute(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExecute_Usage(t *testing.T) {
+	code, _, stderr := execTest(t)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "simulate")
+
+	code, _, stderr = execTest(t, "fly")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "fly"`)
+
+	code, _, _ = execTest(t, "run")
+	assert.Equal(t, 2, code)
+	code, _, _ = execTest(t, "replay", "a.jsonl", "b.jsonl")
+	assert.Equal(t, 2, code)
+	code, _, _ = execTest(t, "validate", "-h")
+	assert.Equal(t, 0, code)
+
+	code, _, stderr = execTest(t, "simulate", "-policy", "lottery")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errUnknownPolicy.Error())
+	code, _, stderr = execTest(t, "simulate", "-format", "xml")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errUnknownFormat.Error())
+}
+
+func TestExecute_RunReplayValidate(t *testing.T) {
+	dir := t.TempDir()
+	tasksPath := filepath.Join(dir, "tasks.yaml")
+	tracePath := filepath.Join(dir, "trace.jsonl")
+	chromePath := filepath.Join(dir, "trace.json")
+	assert.NoError(t, os.WriteFile(tasksPath, []byte(`
+tasks:
+  - name: setter
+    type: basic
+    priority: 1
+    arrival: 30ms
+    unit_time: 1ms
+  - name: waiter
+    type: extended
+    priority: 2
+    work_units: 4
+    unit_time: 1ms
+`), 0o644))
+
+	args := append([]string{"run", "-tasks", tasksPath, "-format", "json", "-trace", tracePath, "-trace-queues", "-chrome", chromePath}, fast...)
+	code, stdout, stderr := execTest(t, args...)
+	assert.Equal(t, 0, code, stderr)
+	var rep report
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &rep))
+	assert.Len(t, rep.Tasks, 2)
+	// The waiter arrives first and waits halfway for the setter.
+	assert.Equal(t, task.Extended, rep.Tasks[0].Type)
+	assert.Equal(t, 4, rep.Tasks[0].Progress)
+	assert.Empty(t, rep.Left)
+
+	f, err := os.Open(tracePath)
+	assert.NoError(t, err)
+	defer f.Close()
+	events, err := trace.ReadJSONL(f)
+	assert.NoError(t, err)
+	assert.Len(t, events, rep.Transitions)
+	assert.NotNil(t, events[0].Queues)
+	assert.FileExists(t, chromePath)
+
+	code, stdout, _ = execTest(t, "replay", tracePath)
+	assert.Equal(t, 0, code)
+	assert.Len(t, strings.Split(strings.TrimSpace(stdout), "\n"), len(events)+1)
+	assert.Contains(t, stdout, "task ready -> running")
+
+	code, stdout, _ = execTest(t, "validate", tracePath)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "0 violations")
+
+	// A trace that lost the admission of the first task.
+	broken := filepath.Join(dir, "broken.jsonl")
+	out, err := os.Create(broken)
+	assert.NoError(t, err)
+	assert.Equal(t, task.Ready, events[1].To)
+	assert.NoError(t, trace.WriteJSONL(out, append(events[:1:1], events[2:]...)))
+	assert.NoError(t, out.Close())
+	code, stdout, _ = execTest(t, "validate", "-format", "json", broken)
+	assert.Equal(t, 1, code)
+	var res struct {
+		Violations []validate.Violation `json:"violations"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &res))
+	assert.NotEmpty(t, res.Violations)
+	assert.Equal(t, "sequence", res.Violations[0].Invariant)
+	assert.Equal(t, "event 2 is missing", res.Violations[0].Message)
+
+	code, stdout, _ = execTest(t, "validate", "-invariants", "transitions,ready-position", "-format", "json", broken)
+	assert.Equal(t, 1, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &res))
+	assert.Equal(t, events[1].TaskID, res.Violations[0].TaskID)
+	assert.Equal(t, "transitions", res.Violations[0].Invariant)
+	code, _, stderr = execTest(t, "validate", "-invariants", "fairness", broken)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, validate.ErrUnknownInvariant.Error())
+}
+
+func TestExecute_RunInvalidTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"tasks": [{"name": "p", "type": "periodic"}]}`), 0o644))
+
+	code, _, stderr := execTest(t, "run", "-tasks", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, task.ErrInvalidType.Error())
+}
+
+func TestExecute_SimulateIsSeeded(t *testing.T) {
+	simulate := func() report {
+		args := append([]string{"simulate", "-count", "4", "-seed", "42", "-task-sleep", "1ms", "-format", "json",
+			"-types", "basic=3,extended=1", "-mix", "-arrivals", "bursty:2:1ms:20ms", "-work", "uniform:1:3"}, fast...)
+		code, stdout, stderr := execTest(t, args...)
+		assert.Equal(t, 0, code, stderr)
+		var rep report
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &rep))
+		return rep
+	}
+
+	first, second := simulate(), simulate()
+	assert.Equal(t, int64(42), *first.Seed)
+	assert.Len(t, first.Tasks, 4)
+	extended := 0
+	for i := range first.Tasks {
+		assert.Equal(t, first.Tasks[i].Type, second.Tasks[i].Type)
+		assert.Equal(t, first.Tasks[i].Priority, second.Tasks[i].Priority)
+		assert.Equal(t, first.Tasks[i].ProgressLimit, second.Tasks[i].ProgressLimit)
+		assert.LessOrEqual(t, first.Tasks[i].ProgressLimit, 3)
+		if first.Tasks[i].Type == task.Extended {
+			extended++
+		}
+	}
+	assert.Equal(t, 1, extended)
+}
+
+func TestExecute_SimulateInvalidDistribution(t *testing.T) {
+	for _, args := range [][]string{
+		{"-types", "basic"},
+		{"-priorities", "high=1"},
+		{"-arrivals", "poisson"},
+		{"-arrivals", "bursty:x:1ms:1ms"},
+		{"-work", "uniform:3"},
+	} {
+		code, _, stderr := execTest(t, append([]string{"simulate"}, args...)...)
+		assert.Equal(t, 1, code, args)
+		assert.Contains(t, stderr, errInvalidDistribution.Error(), args)
+	}
+
+	code, _, stderr := execTest(t, "simulate", "-seed", "5", "-count", "1", "-priorities", "9=1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "seed 5")
+}
+
+func TestExecute_SimulateVirtual(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	wall := time.Now()
+	code, _, stderr := execTest(t, "simulate", "-count", "3", "-seed", "7", "-task-sleep", "1s", "-virtual", "-trace", path)
+	assert.Equal(t, 0, code, stderr)
+	assert.Less(t, time.Since(wall), time.Second)
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	events, err := trace.ReadJSONL(f)
+	assert.NoError(t, err)
+	epoch := time.Unix(0, 0).UTC()
+	assert.True(t, events[0].Time.Equal(epoch))
+	// Every task does at least one work unit of a second.
+	assert.GreaterOrEqual(t, events[len(events)-1].Time.Sub(epoch), 3*time.Second)
+
+	code, _, stderr = execTest(t, "simulate", "-count", "1", "-virtual", "-log-level", "debug")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "msg=\"task transition\"")
+	code, _, stderr = execTest(t, "simulate", "-count", "1", "-virtual", "-log-level", "warn")
+	assert.Equal(t, 0, code, stderr)
+	assert.Empty(t, stderr)
+	code, _, _ = execTest(t, "simulate", "-log-level", "loud")
+	assert.Equal(t, 2, code)
+
+	code, _, stderr = execTest(t, "simulate", "-virtual", "-idle-ticks", "0")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errVirtualIdle.Error())
+	code, _, stderr = execTest(t, "simulate", "-dump-overflow", "sometimes")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errUnknownOverflow.Error())
+}
+
+func TestExecute_SimulateEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	args := []string{"simulate", "-count", "20", "-seed", "3", "-task-sleep", "1s", "-format", "json"}
+	code, stdout, stderr := execTest(t, append(args, "-engine", "sim", "-trace", path, "-trace-queues")...)
+	assert.Equal(t, 0, code, stderr)
+	var simulated report
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &simulated))
+	assert.Len(t, simulated.Tasks, 20)
+	assert.Empty(t, simulated.Left)
+
+	// The simulation takes the steps of a live run on a virtual clock.
+	code, stdout, stderr = execTest(t, append(args, "-virtual")...)
+	assert.Equal(t, 0, code, stderr)
+	var live report
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &live))
+	assert.Equal(t, live.Transitions, simulated.Transitions)
+
+	code, stdout, _ = execTest(t, "validate", path)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "0 violations")
+
+	code, _, stderr = execTest(t, "simulate", "-engine", "fast")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errUnknownEngine.Error())
+	code, _, stderr = execTest(t, "simulate", "-engine", "sim", "-http", "localhost:0")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errSimServe.Error())
+}
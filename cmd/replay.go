@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"scheduler/internal/trace"
+	"text/tabwriter"
+)
+
+func replayCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("replay", "<trace.jsonl | ->", stderr)
+	format := fs.String("format", "text", "output format: text (a timeline), jsonl or chrome")
+	out := fs.String("o", "", "write the output to this file instead of stdout")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "text", "jsonl", "chrome"); err != nil {
+		return err
+	}
+
+	events, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "jsonl":
+		return trace.WriteJSONL(w, events)
+	case "chrome":
+		return trace.WriteChrome(w, events)
+	default:
+		return writeTimeline(w, events)
+	}
+}
+
+// readTrace reads a JSON Lines trace from path, or from stdin for "-".
+func readTrace(path string) ([]trace.Event, error) {
+	if path == "-" {
+		return trace.ReadJSONL(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events, err := trace.ReadJSONL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return events, nil
+}
+
+// writeTimeline writes one line per event with its time since the first.
+func writeTimeline(w io.Writer, events []trace.Event) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEQ\tTIME\tCORE\tTRANSITION")
+	for _, e := range events {
+		core := "-"
+		if e.Core != trace.NoCore {
+			core = fmt.Sprint(e.Core)
+		}
+		fmt.Fprintf(tw, "%d\t+%s\t%s\t%s | p=%d\n", e.Seq, e.Time.Sub(events[0].Time), core, e, e.Priority)
+	}
+	return tw.Flush()
+}
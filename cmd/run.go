@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/taskset"
+	"scheduler/internal/trace"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFlags select where the results of a scheduler run go.
+type outputFlags struct {
+	format   string
+	trace    string
+	queues   bool
+	chrome   string
+	http     string
+	grpc     string
+	shutdown time.Duration
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", "text", "report format: text or json")
+	fs.StringVar(&f.trace, "trace", "", "write the trace as JSON Lines to this file")
+	fs.BoolVar(&f.queues, "trace-queues", false, "write a copy of all queues with every -trace event, for the queue checks of validate")
+	fs.StringVar(&f.chrome, "chrome", "", "write a Chrome trace of the run to this file")
+	fs.StringVar(&f.http, "http", "", "serve the HTTP control plane on this address and run until interrupted")
+	fs.StringVar(&f.grpc, "grpc", "", "serve the gRPC service on this address and run until interrupted")
+	fs.DurationVar(&f.shutdown, "shutdown-timeout", 5*time.Second, "time the scheduler gets to stop once interrupted")
+}
+
+// report is the result of a scheduler run.
+type report struct {
+	Seed        *int64          `json:"seed,omitempty"`
+	Tasks       []task.Snapshot `json:"tasks"`
+	Transitions int             `json:"transitions"`
+	Dropped     uint64          `json:"dropped"`
+	// Left lists the tasks still queued when the scheduler stopped.
+	Left string `json:"left,omitempty"`
+}
+
+func (r report) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(r)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tPRIORITY\tSTATE\tPROGRESS")
+	for _, t := range r.Tasks {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%d/%d\n", t.ID, t.Type, t.Priority, t.State, t.Progress, t.ProgressLimit)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "transitions: %d, dropped: %d\n", r.Transitions, r.Dropped)
+	if r.Seed != nil {
+		fmt.Fprintf(w, "seed: %d\n", *r.Seed)
+	}
+	if r.Left != "" {
+		fmt.Fprintf(w, "left: %s\n", r.Left)
+	}
+	return nil
+}
+
+// countSink counts events. The scheduler calls Dump from one goroutine and
+// the count is read after it has stopped.
+type countSink struct {
+	n int
+}
+
+func (c *countSink) Dump(trace.Event) {
+	c.n++
+}
+
+// collectSink keeps all events.
+type collectSink struct {
+	events []trace.Event
+}
+
+func (c *collectSink) Dump(e trace.Event) {
+	c.events = append(c.events, e)
+}
+
+func runCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("run", "", stderr)
+	var sf schedulerFlags
+	var of outputFlags
+	sf.register(fs)
+	of.register(fs)
+	tasksFile := fs.String("tasks", "", "YAML or JSON task-set file with the scenario to run (required)")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(of.format, "text", "json"); err != nil {
+		return err
+	}
+	if *tasksFile == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	opts, closeJournal, err := sf.options(stderr)
+	if err != nil {
+		return err
+	}
+	defer closeJournal()
+	set, err := taskset.LoadFile(*tasksFile)
+	if err != nil {
+		return err
+	}
+	feed, err := taskset.NewFeed(set)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *tasksFile, err)
+	}
+	return runScheduler(ctx, opts, &of, feedSource{feed}, report{}, stdout)
+}
+
+// source adds the tasks of a run to the scheduler.
+type source interface {
+	// start adds the tasks that arrive at once and starts adding the others.
+	start(ctx context.Context, s *scheduler.Scheduler) error
+	// wait returns once all tasks have been added.
+	wait() error
+	tasks() []*task.Task
+}
+
+// feedSource adds the tasks of a task set at their arrival times.
+type feedSource struct {
+	feed *taskset.Feed
+}
+
+func (f feedSource) start(ctx context.Context, s *scheduler.Scheduler) error {
+	f.feed.Run(ctx, s)
+	select {
+	case <-f.feed.DoneChan:
+		return f.feed.Err()
+	default:
+		return nil
+	}
+}
+
+// wait ignores the arrivals an interrupted run did not get to.
+func (f feedSource) wait() error {
+	<-f.feed.DoneChan
+	if err := f.feed.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+func (f feedSource) tasks() []*task.Task {
+	return f.feed.All()
+}
+
+// traceOutputs are the sinks of a run: the event count and the -trace and
+// -chrome outputs.
+type traceOutputs struct {
+	sinks     []scheduler.DumpSink
+	count     *countSink
+	file      *os.File
+	writer    *scheduler.WriterSink
+	collected *collectSink
+}
+
+func (f *outputFlags) outputs() (*traceOutputs, error) {
+	out := &traceOutputs{count: &countSink{}}
+	out.sinks = []scheduler.DumpSink{out.count}
+	if f.trace != "" {
+		file, err := os.Create(f.trace)
+		if err != nil {
+			return nil, err
+		}
+		out.file = file
+		out.writer = scheduler.NewWriterSink(file)
+		if f.queues {
+			out.sinks = append(out.sinks, scheduler.IncludeQueues(out.writer))
+		} else {
+			out.sinks = append(out.sinks, out.writer)
+		}
+	}
+	if f.chrome != "" {
+		out.collected = &collectSink{}
+		out.sinks = append(out.sinks, out.collected)
+	}
+	return out, nil
+}
+
+func (o *traceOutputs) close() {
+	if o.file != nil {
+		o.file.Close()
+	}
+}
+
+// finish writes the Chrome trace once the run is over and adds the tasks and
+// the transitions to rep.
+func (o *traceOutputs) finish(of *outputFlags, tasks []*task.Task, rep *report) error {
+	if o.writer != nil && o.writer.Err() != nil {
+		return o.writer.Err()
+	}
+	if o.collected != nil {
+		if err := writeChrome(of.chrome, o.collected.events); err != nil {
+			return err
+		}
+	}
+	rep.Tasks = make([]task.Snapshot, 0, len(tasks))
+	for _, t := range tasks {
+		rep.Tasks = append(rep.Tasks, t.Snapshot())
+	}
+	rep.Transitions = o.count.n
+	return nil
+}
+
+// runScheduler runs the tasks of src until the scheduler stops on its own or
+// ctx is done and writes the report of the run.
+func runScheduler(ctx context.Context, opts []scheduler.Option, of *outputFlags, src source, rep report, stdout io.Writer) error {
+	out, err := of.outputs()
+	if err != nil {
+		return err
+	}
+	defer out.close()
+	sinks := out.sinks
+	events := scheduler.NewBroadcastSink()
+	serving := of.http != "" || of.grpc != ""
+	if serving {
+		sinks = append(sinks, events)
+		opts = append(opts, scheduler.WithRunForever())
+	}
+	s, err := scheduler.New(append(opts, scheduler.WithDumpSink(scheduler.MultiSink(sinks...)))...)
+	if err != nil {
+		return err
+	}
+	ctx, stopSource := context.WithCancel(ctx)
+	defer stopSource()
+	if err := src.start(ctx, s); err != nil {
+		return err
+	}
+	if serving {
+		stop, err := serve(s, events, of.http, of.grpc)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	s.Run(ctx)
+	select {
+	case <-s.StopChan:
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), of.shutdown)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); errors.Is(err, scheduler.ErrTasksLeft) {
+		rep.Left = err.Error()
+	} else if err != nil {
+		return err
+	}
+	stopSource()
+	if err := src.wait(); err != nil {
+		return err
+	}
+
+	if err := out.finish(of, src.tasks(), &rep); err != nil {
+		return err
+	}
+	rep.Dropped = s.DroppedDumps()
+	if err := rep.write(stdout, of.format); err != nil {
+		return err
+	}
+	// validate would report the gaps of such traces as violations.
+	if rep.Dropped > 0 && (of.trace != "" || of.chrome != "") {
+		return fmt.Errorf("%w: %d", errDroppedEvents, rep.Dropped)
+	}
+	return nil
+}
+
+func writeChrome(path string, events []trace.Event) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := trace.WriteChrome(f, events); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
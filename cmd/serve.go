@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"scheduler/api/schedulerpb"
+	"scheduler/internal/grpcapi"
+	"scheduler/internal/httpapi"
+	"scheduler/internal/scheduler"
+
+	"google.golang.org/grpc"
+)
+
+// serve starts the control planes of s on the addresses that are set and
+// returns a function that stops them.
+func serve(s *scheduler.Scheduler, events *scheduler.BroadcastSink, httpAddr, grpcAddr string) (func(), error) {
+	stops := make([]func(), 0, 2)
+	stop := func() {
+		for _, fn := range stops {
+			fn()
+		}
+	}
+	if httpAddr != "" {
+		lis, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return nil, err
+		}
+		srv := &http.Server{Handler: httpapi.New(s, events)}
+		go func() {
+			if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.Config().Logger.Error("http server stopped", "error", err)
+			}
+		}()
+		stops = append(stops, func() { srv.Close() })
+	}
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		srv := grpc.NewServer()
+		schedulerpb.RegisterSchedulerServer(srv, grpcapi.New(s, events))
+		go func() {
+			if err := srv.Serve(lis); err != nil {
+				s.Config().Logger.Error("grpc server stopped", "error", err)
+			}
+		}()
+		stops = append(stops, srv.Stop)
+	}
+	return stop, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"scheduler/internal/generator"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/sim"
+	"scheduler/internal/task"
+	"scheduler/internal/taskset"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidDistribution = errors.New("invalid distribution")
+	errUnknownEngine       = errors.New("unknown engine")
+	errSimServe            = errors.New("the sim engine cannot serve the control plane")
+)
+
+// generatorFlags select the distributions of the simulate command.
+type generatorFlags struct {
+	types      string
+	priorities string
+	mix        bool
+	arrivals   string
+	work       string
+}
+
+func (f *generatorFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.types, "types", "basic=1,extended=1", "weights of the task types")
+	fs.StringVar(&f.priorities, "priorities", "0=1,1=1,2=1,3=1", "weights of the task priorities")
+	fs.BoolVar(&f.mix, "mix", false, "draw types and priorities as exact mixes of their weights")
+	fs.StringVar(&f.arrivals, "arrivals", "once", "arrival process: once, periodic:<gap>, poisson:<mean gap> or bursty:<size>:<gap within>:<gap between>")
+	fs.StringVar(&f.work, "work", fmt.Sprintf("fixed:%d", task.DefaultProgressLimit), "work units: fixed:<n>, uniform:<min>:<max> or exp:<mean>")
+}
+
+func (f *generatorFlags) options() ([]generator.Option, error) {
+	types, err := parseWeights(f.types, func(s string) (task.TaskType, error) {
+		return task.TaskType(s), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := parseWeights(f.priorities, func(s string) (task.TaskPriority, error) {
+		p, err := strconv.Atoi(s)
+		return task.TaskPriority(p), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	arrivals, err := parseArrivals(f.arrivals)
+	if err != nil {
+		return nil, err
+	}
+	work, err := parseWork(f.work)
+	if err != nil {
+		return nil, err
+	}
+	opts := []generator.Option{
+		generator.WithTypes(types...),
+		generator.WithPriorities(priorities...),
+		generator.WithArrivals(arrivals),
+		generator.WithWork(work),
+	}
+	if f.mix {
+		opts = append(opts, generator.WithFixedMix())
+	}
+	return opts, nil
+}
+
+// parseWeights parses value=weight pairs separated by commas.
+func parseWeights[T any](s string, parseValue func(string) (T, error)) ([]generator.Weighted[T], error) {
+	res := make([]generator.Weighted[T], 0)
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", errInvalidDistribution, s)
+		}
+		v, err := parseValue(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", errInvalidDistribution, s)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", errInvalidDistribution, s)
+		}
+		res = append(res, generator.Weighted[T]{Value: v, Weight: w})
+	}
+	return res, nil
+}
+
+func parseArrivals(s string) (generator.Arrivals, error) {
+	kind, args, _ := strings.Cut(s, ":")
+	fields := strings.Split(args, ":")
+	durations := func(n int) ([]time.Duration, bool) {
+		if len(fields) != n {
+			return nil, false
+		}
+		res := make([]time.Duration, n)
+		for i, f := range fields {
+			d, err := time.ParseDuration(f)
+			if err != nil {
+				return nil, false
+			}
+			res[i] = d
+		}
+		return res, true
+	}
+	switch kind {
+	case "once":
+		if args == "" {
+			return generator.Periodic{}, nil
+		}
+	case "periodic":
+		if d, ok := durations(1); ok {
+			return generator.Periodic{Period: d[0]}, nil
+		}
+	case "poisson":
+		if d, ok := durations(1); ok {
+			return generator.Poisson{Mean: d[0]}, nil
+		}
+	case "bursty":
+		size, err := strconv.Atoi(fields[0])
+		fields = fields[1:]
+		if d, ok := durations(2); ok && err == nil {
+			return generator.Bursty{Size: size, Within: d[0], Between: d[1]}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", errInvalidDistribution, s)
+}
+
+func parseWork(s string) (generator.WorkLength, error) {
+	kind, args, _ := strings.Cut(s, ":")
+	fields := strings.Split(args, ":")
+	switch kind {
+	case "fixed":
+		if n, err := strconv.Atoi(args); err == nil {
+			return generator.FixedWork(n), nil
+		}
+	case "uniform":
+		if len(fields) == 2 {
+			lo, err1 := strconv.Atoi(fields[0])
+			hi, err2 := strconv.Atoi(fields[1])
+			if err1 == nil && err2 == nil {
+				return generator.UniformWork{Min: lo, Max: hi}, nil
+			}
+		}
+	case "exp":
+		if mean, err := strconv.ParseFloat(args, 64); err == nil {
+			return generator.ExponentialWork{Mean: mean}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", errInvalidDistribution, s)
+}
+
+func simulateCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("simulate", "", stderr)
+	var sf schedulerFlags
+	var of outputFlags
+	var gf generatorFlags
+	sf.register(fs)
+	of.register(fs)
+	gf.register(fs)
+	count := fs.Int("count", 10, "number of tasks")
+	seed := fs.Int64("seed", 0, "seed of the task generator, random if 0")
+	sleepTime := fs.Duration("task-sleep", task.DefaultTaskSleepTime, "duration of a work unit")
+	engine := fs.String("engine", "live", "live runs the scheduler, sim simulates it without goroutines or wall time")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := checkFormat(of.format, "text", "json"); err != nil {
+		return err
+	}
+	switch {
+	case *engine != "live" && *engine != "sim":
+		return fmt.Errorf("%w: %q", errUnknownEngine, *engine)
+	case *engine == "sim" && (of.http != "" || of.grpc != ""):
+		return errSimServe
+	}
+	opts, closeJournal, err := sf.options(stderr)
+	if err != nil {
+		return err
+	}
+	defer closeJournal()
+	genOpts, err := gf.options()
+	if err != nil {
+		return err
+	}
+
+	if *seed == 0 {
+		*seed = generator.NewSeed()
+	}
+	g, err := generator.New(*seed, genOpts...)
+	if err != nil {
+		return err
+	}
+	generated, err := g.Take(*count, task.WithSleepTime(*sleepTime))
+	if err != nil {
+		return err
+	}
+	arrivals := make([]taskset.Arrival, 0, len(generated))
+	for _, a := range generated {
+		arrivals = append(arrivals, taskset.Arrival{At: a.At, Task: a.Task})
+	}
+	if *engine == "sim" {
+		err = runSimulation(opts, &of, arrivals, report{Seed: seed}, stdout)
+	} else {
+		err = runScheduler(ctx, opts, &of, feedSource{taskset.NewFeedOf(arrivals)}, report{Seed: seed}, stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("seed %d: %w", *seed, err)
+	}
+	return nil
+}
+
+// runSimulation simulates the arrivals from the Unix epoch on and writes the
+// report of the run.
+func runSimulation(opts []scheduler.Option, of *outputFlags, arrivals []taskset.Arrival, rep report, stdout io.Writer) error {
+	out, err := of.outputs()
+	if err != nil {
+		return err
+	}
+	defer out.close()
+	s, err := sim.New(time.Unix(0, 0).UTC(), append(opts, scheduler.WithDumpSink(scheduler.MultiSink(out.sinks...)))...)
+	if err != nil {
+		return err
+	}
+	tasks := make([]*task.Task, 0, len(arrivals))
+	for _, a := range arrivals {
+		if err := s.AddTask(a.Task, a.At); err != nil {
+			return err
+		}
+		tasks = append(tasks, a.Task)
+	}
+	if err := s.Run(); errors.Is(err, scheduler.ErrTasksLeft) {
+		rep.Left = err.Error()
+	} else if err != nil {
+		return err
+	}
+	if err := out.finish(of, tasks, &rep); err != nil {
+		return err
+	}
+	return rep.write(stdout, of.format)
+}
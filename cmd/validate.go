@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/validate"
+	"strings"
+)
+
+func validateCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", "<trace.jsonl | ->", stderr)
+	format := fs.String("format", "text", "output format: text or json")
+	maxReady := fs.Int("max-ready", scheduler.DefaultMaxReadyTasks, "capacity of all ready queues together")
+	invariants := fs.String("invariants", invariantNames(validate.Default(0)), "comma-separated invariants to check, out of "+invariantNames(validate.All(0)))
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "text", "json"); err != nil {
+		return err
+	}
+
+	checked, err := validate.Named(*maxReady, strings.Split(*invariants, ",")...)
+	if err != nil {
+		return err
+	}
+
+	events, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	res := validate.Trace(events, checked...)
+	if *format == "json" {
+		if err := json.NewEncoder(stdout).Encode(struct {
+			Events     int                  `json:"events"`
+			Violations []validate.Violation `json:"violations"`
+		}{len(events), res}); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range res {
+			fmt.Fprintln(stdout, v)
+		}
+		fmt.Fprintf(stdout, "%d events, %d violations\n", len(events), len(res))
+	}
+	if len(res) > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func invariantNames(invariants []validate.Invariant) string {
+	names := make([]string, 0, len(invariants))
+	for _, inv := range invariants {
+		names = append(names, inv.Name)
+	}
+	return strings.Join(names, ",")
+}
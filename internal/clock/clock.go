@@ -0,0 +1,55 @@
+// Package clock abstracts the time the scheduler and its tasks run on, so a
+// scenario can run on a virtual clock instead of the wall clock.
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+	// Busy and Idle track the goroutines a virtual clock waits for before it
+	// advances. A goroutine calls Idle before it blocks or exits; whoever
+	// wakes a blocked goroutine, or starts a new one, calls Busy on its
+	// behalf before it can block itself. The real clock ignores both.
+	Busy()
+	Idle()
+}
+
+// Timer fires once on C. Stop has to be called on timers nobody receives
+// from any more.
+type Timer interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the wall clock.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (Real) Busy() {}
+
+func (Real) Idle() {}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() {
+	t.t.Stop()
+}
@@ -0,0 +1,142 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Virtual is a discrete-event clock. It jumps to the next timer as soon as
+// every goroutine it tracks is idle and fires timers due at the same time one
+// by one, in the order they were created, so runs on it take no wall time
+// and repeat exactly.
+type Virtual struct {
+	mu     sync.Mutex
+	now    time.Time
+	busy   int
+	seq    uint64
+	timers timerHeap
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+// Sleep blocks until the clock has advanced by d. A zero d lets every other
+// goroutine due now run first.
+func (v *Virtual) Sleep(d time.Duration) {
+	t := v.NewTimer(d)
+	v.Idle()
+	<-t.C()
+}
+
+func (v *Virtual) NewTimer(d time.Duration) Timer {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.seq++
+	t := &virtualTimer{v: v, at: v.now.Add(max(d, 0)), seq: v.seq, c: make(chan time.Time, 1), index: -1}
+	heap.Push(&v.timers, t)
+	return t
+}
+
+func (v *Virtual) Busy() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.busy++
+}
+
+func (v *Virtual) Idle() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.busy--
+	v.advance()
+}
+
+// advance fires the next timer once nothing is busy. The receiver of the
+// timer becomes busy. It is called with mu held.
+func (v *Virtual) advance() {
+	if v.busy > 0 || len(v.timers) == 0 {
+		return
+	}
+	t := heap.Pop(&v.timers).(*virtualTimer)
+	if t.at.After(v.now) {
+		v.now = t.at
+	}
+	v.busy++
+	t.fired = true
+	t.c <- v.now
+}
+
+type virtualTimer struct {
+	v     *Virtual
+	at    time.Time
+	seq   uint64
+	c     chan time.Time
+	index int
+	fired bool
+}
+
+func (t *virtualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop removes a pending timer. A fired timer nobody received from gives
+// back the busy count it handed out.
+func (t *virtualTimer) Stop() {
+	v := t.v
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if t.index >= 0 {
+		heap.Remove(&v.timers, t.index)
+		return
+	}
+	if !t.fired {
+		return
+	}
+	select {
+	case <-t.c:
+		v.busy--
+		v.advance()
+	default:
+	}
+}
+
+type timerHeap []*virtualTimer
+
+func (h timerHeap) Len() int {
+	return len(h)
+}
+
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*virtualTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,75 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtual_Sleep(t *testing.T) {
+	start := time.Unix(0, 0)
+	v := NewVirtual(start)
+
+	var mu sync.Mutex
+	order := make([]string, 0)
+	var wg sync.WaitGroup
+	sleeper := func(name string, d time.Duration, n int) {
+		defer wg.Done()
+		defer v.Idle()
+		for i := 0; i < n; i++ {
+			v.Sleep(d)
+			mu.Lock()
+			order = append(order, name+"@"+v.Now().Sub(start).String())
+			mu.Unlock()
+		}
+	}
+	wg.Add(2)
+	v.Busy()
+	v.Busy()
+	go sleeper("a", 3*time.Second, 2)
+	go sleeper("b", 2*time.Second, 3)
+
+	begin := time.Now()
+	wg.Wait()
+	assert.Less(t, time.Since(begin), time.Second)
+	assert.Equal(t, []string{"b@2s", "a@3s", "b@4s", "a@6s", "b@6s"}, order)
+}
+
+func TestVirtual_Timer(t *testing.T) {
+	v := NewVirtual(time.Unix(0, 0))
+	v.Busy()
+	stopped := v.NewTimer(time.Second)
+	abandoned := v.NewTimer(2 * time.Second)
+	kept := v.NewTimer(3 * time.Second)
+	stopped.Stop()
+
+	// The abandoned timer fires first and stays busy until stopped.
+	v.Idle()
+	assert.Equal(t, time.Unix(2, 0), v.Now())
+	select {
+	case <-kept.C():
+		t.Fatal("timer fired while the clock was busy")
+	default:
+	}
+	abandoned.Stop()
+	assert.Equal(t, time.Unix(3, 0), <-kept.C())
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+}
+
+func TestReal(t *testing.T) {
+	var c Clock = Real{}
+	c.Busy()
+	c.Idle()
+	before := c.Now()
+	c.Sleep(time.Millisecond)
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	timer.Stop()
+	assert.GreaterOrEqual(t, c.Now().Sub(before), 2*time.Millisecond)
+}
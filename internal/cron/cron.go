@@ -0,0 +1,329 @@
+// Package cron submits tasks to a scheduler on cron schedules. It runs on the
+// clock of the scheduler, so schedules can be played on a virtual clock.
+package cron
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"scheduler/internal/clock"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultTolerance is how late a firing may be handled before it counts as
+// missed.
+const DefaultTolerance = time.Second
+
+// Missed tells what a job does with the firings it missed, such as while the
+// process was down. On-time firings always submit a task.
+type Missed int
+
+const (
+	// Skip drops missed firings.
+	Skip Missed = iota
+	// RunOnce submits a single task for all missed firings.
+	RunOnce
+	// RunAll submits a task for every missed firing.
+	RunAll
+)
+
+// Template describes the task a job submits at every firing.
+type Template struct {
+	Type     task.TaskType
+	Priority task.TaskPriority
+	Options  []task.Option
+}
+
+// New creates a task from the template.
+func (t Template) New() (*task.Task, error) {
+	return task.New(t.Type, t.Priority, task.Suspended, t.Options...)
+}
+
+// Entry describes a job. Last is the time the job handled its firings up
+// to; Next is its next firing, zero if there is none.
+type Entry struct {
+	Name      string
+	Spec      string
+	Missed    Missed
+	Last      time.Time
+	Next      time.Time
+	Submitted int
+}
+
+type job struct {
+	spec      string
+	schedule  *Schedule
+	template  Template
+	missed    Missed
+	last      time.Time
+	submitted int
+}
+
+type Option func(*Cron)
+
+// WithLocation sets the time zone of expressions without a CRON_TZ prefix.
+// It defaults to the local time zone.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Cron) {
+		c.loc = loc
+	}
+}
+
+// WithTolerance sets how late a firing may be handled before it counts as
+// missed.
+func WithTolerance(d time.Duration) Option {
+	return func(c *Cron) {
+		c.tolerance = d
+	}
+}
+
+type JobOption func(*job)
+
+// WithMissed sets what the job does with missed firings. It defaults to Skip.
+func WithMissed(m Missed) JobOption {
+	return func(j *job) {
+		j.missed = m
+	}
+}
+
+// WithLast makes the job handle the firings after last, such as the time the
+// job last fired before a restart. It defaults to the time the job is added.
+func WithLast(last time.Time) JobOption {
+	return func(j *job) {
+		j.last = last
+	}
+}
+
+// Cron submits the tasks of its jobs to a scheduler.
+type Cron struct {
+	s         *scheduler.Scheduler
+	clock     clock.Clock
+	logger    *slog.Logger
+	loc       *time.Location
+	tolerance time.Duration
+	mu        sync.Mutex
+	jobs      map[string]*job
+	running   bool
+	wake      chan struct{}
+	done      chan struct{}
+}
+
+func New(s *scheduler.Scheduler, opts ...Option) (*Cron, error) {
+	cfg := s.Config()
+	c := Cron{s: s, clock: cfg.Clock, logger: cfg.Logger, loc: time.Local, tolerance: DefaultTolerance}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.tolerance < 0 {
+		return nil, ErrInvalidTolerance
+	}
+	c.jobs = make(map[string]*job)
+	c.wake = make(chan struct{}, 1)
+	c.done = make(chan struct{})
+	return &c, nil
+}
+
+// Add adds a job that submits a task made from template whenever the cron
+// expression spec fires.
+func (c *Cron) Add(name, spec string, template Template, opts ...JobOption) error {
+	schedule, err := Parse(spec, c.loc)
+	if err != nil {
+		return err
+	}
+	j := job{spec: spec, schedule: schedule, template: template, last: c.clock.Now()}
+	for _, opt := range opts {
+		opt(&j)
+	}
+	if j.missed < Skip || j.missed > RunAll {
+		return ErrInvalidMissed
+	}
+	c.mu.Lock()
+	if _, ok := c.jobs[name]; ok {
+		c.mu.Unlock()
+		return ErrJobExists
+	}
+	c.jobs[name] = &j
+	c.mu.Unlock()
+	c.wakeUp()
+	return nil
+}
+
+// Remove removes a job.
+func (c *Cron) Remove(name string) error {
+	c.mu.Lock()
+	if _, ok := c.jobs[name]; !ok {
+		c.mu.Unlock()
+		return ErrUnknownJob
+	}
+	delete(c.jobs, name)
+	c.mu.Unlock()
+	c.wakeUp()
+	return nil
+}
+
+// Entries returns the jobs ordered by their next firing. Jobs that do not
+// fire any more come last.
+func (c *Cron) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]Entry, 0, len(c.jobs))
+	for name, j := range c.jobs {
+		res = append(res, Entry{
+			Name:      name,
+			Spec:      j.spec,
+			Missed:    j.missed,
+			Last:      j.last,
+			Next:      j.schedule.Next(j.last),
+			Submitted: j.submitted,
+		})
+	}
+	slices.SortFunc(res, func(a, b Entry) int {
+		if a.Next.IsZero() != b.Next.IsZero() {
+			if a.Next.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(a.Next.Compare(b.Next), cmp.Compare(a.Name, b.Name))
+	})
+	return res
+}
+
+// Run submits the tasks of the jobs until ctx is cancelled. It holds the
+// scheduler meanwhile, so it does not stop when idle. Done is closed once
+// Run has stopped.
+func (c *Cron) Run(ctx context.Context) {
+	release := c.s.Hold()
+	c.mu.Lock()
+	c.running = true
+	c.mu.Unlock()
+	c.clock.Busy()
+	go func() {
+		defer close(c.done)
+		defer release()
+		c.run(ctx)
+	}()
+}
+
+func (c *Cron) Done() <-chan struct{} {
+	return c.done
+}
+
+// wakeUp makes a running cron look at its jobs again. It is marked busy
+// before it can take the wake-up.
+func (c *Cron) wakeUp() {
+	c.mu.Lock()
+	running := c.running
+	c.mu.Unlock()
+	if !running {
+		return
+	}
+	c.clock.Busy()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+		c.clock.Idle()
+	}
+}
+
+func (c *Cron) run(ctx context.Context) {
+	defer c.clock.Idle()
+	for {
+		c.fire(c.clock.Now())
+		var timer clock.Timer
+		if next, ok := c.next(); ok {
+			timer = c.clock.NewTimer(next.Sub(c.clock.Now()))
+		}
+		c.clock.Idle()
+		select {
+		case <-ctx.Done():
+			c.clock.Busy()
+			if timer != nil {
+				timer.Stop()
+			}
+			select {
+			case <-c.wake:
+				c.clock.Idle()
+			default:
+			}
+			return
+		case <-c.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-timerC(timer):
+		}
+	}
+}
+
+// next returns the earliest firing of all jobs.
+func (c *Cron) next() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res time.Time
+	for _, j := range c.jobs {
+		if t := j.schedule.Next(j.last); !t.IsZero() && (res.IsZero() || t.Before(res)) {
+			res = t
+		}
+	}
+	return res, !res.IsZero()
+}
+
+// fire submits the tasks of the firings up to now, job by job in the order
+// of their names. Firings more than the tolerance before now are missed.
+func (c *Cron) fire(now time.Time) {
+	cutoff := now.Add(-c.tolerance)
+	c.mu.Lock()
+	submits := make([]string, 0)
+	templates := make([]Template, 0)
+	for _, name := range slices.Sorted(maps.Keys(c.jobs)) {
+		j := c.jobs[name]
+		missed := 0
+		t := j.schedule.Next(j.last)
+		for ; !t.IsZero() && t.Before(cutoff); t = j.schedule.Next(t) {
+			missed++
+		}
+		runs := 0
+		switch j.missed {
+		case RunOnce:
+			runs = min(missed, 1)
+		case RunAll:
+			runs = missed
+		}
+		if missed > 0 {
+			c.logger.Info("cron job missed firings", "job", name, "missed", missed, "runs", runs)
+		}
+		for ; !t.IsZero() && !t.After(now); t = j.schedule.Next(t) {
+			runs++
+		}
+		j.last = now
+		j.submitted += runs
+		for range runs {
+			submits = append(submits, name)
+			templates = append(templates, j.template)
+		}
+	}
+	c.mu.Unlock()
+
+	for i, tmpl := range templates {
+		t, err := tmpl.New()
+		if err == nil {
+			err = c.s.AddNewTask(t)
+		}
+		if err != nil {
+			c.logger.Warn("cron job submission failed", "job", submits[i], "error", err)
+		}
+	}
+}
+
+func timerC(t clock.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C()
+}
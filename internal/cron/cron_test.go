@@ -0,0 +1,99 @@
+package cron
+
+import (
+	"context"
+	"scheduler/internal/clock"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var template = Template{Type: task.Basic, Priority: task.P1, Options: []task.Option{task.WithProgressLimit(1), task.WithSleepTime(10 * time.Millisecond)}}
+
+func TestCron_Run(t *testing.T) {
+	ring := scheduler.NewRingSink(scheduler.DefaultRingSize)
+	s, err := scheduler.New(scheduler.WithClock(clock.NewVirtual(start)), scheduler.WithDumpSink(ring))
+	assert.NoError(t, err)
+	c, err := New(s, WithLocation(time.UTC))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Add("every-10s", "*/10 * * * * *", template))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Run(context.Background())
+	c.Run(ctx)
+	assert.Eventually(t, func() bool {
+		return c.Entries()[0].Submitted >= 3
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-c.Done()
+	// The scheduler stops once the cron no longer holds it.
+	<-s.StopChan
+
+	added := make([]time.Time, 0)
+	for _, e := range ring.Events() {
+		if e.From == "" {
+			added = append(added, e.Time)
+		}
+	}
+	assert.Equal(t, c.Entries()[0].Submitted, len(added))
+	assert.Equal(t, []time.Time{start.Add(10 * time.Second), start.Add(20 * time.Second), start.Add(30 * time.Second)}, added[:3])
+}
+
+func TestCron_Missed(t *testing.T) {
+	for m, want := range map[Missed]int{Skip: 1, RunOnce: 2, RunAll: 3} {
+		clk := clock.NewVirtual(start.Add(3 * time.Hour))
+		s, err := scheduler.New(scheduler.WithClock(clk))
+		assert.NoError(t, err)
+		c, err := New(s, WithLocation(time.UTC))
+		assert.NoError(t, err)
+		// Down since start: the firings at 1h and 2h are missed, 3h is on time.
+		assert.NoError(t, c.Add("hourly", "0 * * * *", template, WithMissed(m), WithLast(start)))
+
+		c.fire(clk.Now())
+		assert.Len(t, s.Tasks(), want, m)
+		assert.Equal(t, []Entry{{
+			Name:      "hourly",
+			Spec:      "0 * * * *",
+			Missed:    m,
+			Last:      clk.Now(),
+			Next:      start.Add(4 * time.Hour),
+			Submitted: want,
+		}}, c.Entries())
+	}
+}
+
+func TestCron_Jobs(t *testing.T) {
+	s, err := scheduler.New(scheduler.WithClock(clock.NewVirtual(start)))
+	assert.NoError(t, err)
+	_, err = New(s, WithTolerance(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidTolerance)
+	c, err := New(s, WithLocation(time.UTC))
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Add("daily", "@daily", template))
+	assert.NoError(t, c.Add("never", "0 0 30 2 *", template))
+	assert.NoError(t, c.Add("often", "*/5 * * * *", template))
+	assert.ErrorIs(t, c.Add("daily", "@daily", template), ErrJobExists)
+	assert.ErrorIs(t, c.Add("bad", "61 * * * *", template), ErrInvalidField)
+	assert.ErrorIs(t, c.Add("bad", "@daily", template, WithMissed(RunAll+1)), ErrInvalidMissed)
+
+	names := func() []string {
+		res := make([]string, 0)
+		for _, e := range c.Entries() {
+			res = append(res, e.Name)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"often", "daily", "never"}, names())
+	assert.Equal(t, start.Add(5*time.Minute), c.Entries()[0].Next)
+	assert.True(t, c.Entries()[2].Next.IsZero())
+
+	assert.NoError(t, c.Remove("often"))
+	assert.ErrorIs(t, c.Remove("often"), ErrUnknownJob)
+	assert.Equal(t, []string{"daily", "never"}, names())
+}
@@ -0,0 +1,13 @@
+package cron
+
+import "errors"
+
+var (
+	ErrInvalidFieldCount = errors.New("cron expression must have five or six fields")
+	ErrInvalidField      = errors.New("invalid cron field")
+	ErrInvalidZone       = errors.New("invalid cron time zone")
+	ErrJobExists         = errors.New("job already exists")
+	ErrUnknownJob        = errors.New("no job with such name")
+	ErrInvalidTolerance  = errors.New("tolerance must not be negative")
+	ErrInvalidMissed     = errors.New("unknown missed run policy")
+)
@@ -0,0 +1,230 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Each field is a bit set of the values
+// it matches.
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domStar and dowStar tell whether the day fields start with * or are ?.
+	// If both are restricted, a day matching either of them matches, as in cron.
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is both 0 and 7.
+	dows = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a cron expression in loc. Five fields are minute, hour, day of
+// month, month and day of week; six fields start with the second. A
+// CRON_TZ=<zone> or TZ=<zone> prefix overrides loc. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly stand for the usual expressions.
+func Parse(expr string, loc *time.Location) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		zone, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(zone, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidZone, err)
+		}
+		loc, spec = l, strings.TrimSpace(rest)
+	}
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFieldCount, expr)
+	}
+
+	s := Schedule{loc: loc}
+	var err error
+	for i, f := range []struct {
+		dst *uint64
+		b   bounds
+	}{{&s.second, seconds}, {&s.minute, minutes}, {&s.hour, hours}, {&s.dom, doms}, {&s.month, months}, {&s.dow, dows}} {
+		if *f.dst, err = parseField(fields[i], f.b); err != nil {
+			return nil, err
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = isStar(fields[3])
+	s.dowStar = isStar(fields[5])
+	return &s, nil
+}
+
+// isStar tells whether a day field counts as unrestricted. As in cron, a
+// field starting with * does even with a step.
+func isStar(field string) bool {
+	return strings.HasPrefix(field, "*") || field == "?"
+}
+
+// parseField parses a comma separated list of *, single values and ranges,
+// each with an optional /step.
+func parseField(field string, b bounds) (uint64, error) {
+	var res uint64
+	for _, term := range strings.Split(field, ",") {
+		bad := fmt.Errorf("%w: %q", ErrInvalidField, term)
+		rng, step, stepped := strings.Cut(term, "/")
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			lo, err1 = b.value(from)
+			hi, err2 = b.value(to)
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, bad
+			}
+		default:
+			v, err := b.value(rng)
+			if err != nil {
+				return 0, bad
+			}
+			lo, hi = v, v
+			if stepped {
+				hi = b.max
+			}
+		}
+		n := uint64(1)
+		if stepped {
+			v, err := strconv.ParseUint(step, 10, 8)
+			if err != nil || v == 0 {
+				return 0, bad
+			}
+			n = v
+		}
+		for v := uint64(lo); v <= uint64(hi); v += n {
+			res |= 1 << v
+		}
+	}
+	return res, nil
+}
+
+func (b bounds) value(s string) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(v) < b.min || uint(v) > b.max {
+		return 0, ErrInvalidField
+	}
+	return uint(v), nil
+}
+
+// Location returns the time zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// Next returns the first time after t the schedule fires, or the zero time
+// if it does not fire within five years, as for February 30.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + 5
+	// Moving a field up resets the fields below it, once.
+	reset := false
+wrap:
+	for t.Year() <= limit {
+		for !has(s.month, int(t.Month())) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.loc)
+			}
+			t = t.AddDate(0, 1, 0)
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+		for !s.dayMatches(t) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+			}
+			t = t.AddDate(0, 0, 1)
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+		for !has(s.hour, t.Hour()) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc)
+			}
+			t = t.Add(time.Hour)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+		for !has(s.minute, t.Minute()) {
+			if !reset {
+				reset = true
+				t = t.Truncate(time.Minute)
+			}
+			t = t.Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+		for !has(s.second, t.Second()) {
+			t = t.Add(time.Second)
+			if t.Second() == 0 {
+				continue wrap
+			}
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
@@ -0,0 +1,89 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Monday.
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(2024, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", at(1, 1, 0, 1, 0)},
+		{"*/15 * * * * *", at(1, 1, 0, 0, 15)},
+		{"5-10/5 * * * * *", at(1, 1, 0, 0, 5)},
+		{"30 9 * * mon-fri", at(1, 1, 9, 30, 0)},
+		{"0 0 * * 7", at(1, 7, 0, 0, 0)},
+		{"0 0 1 jan,JUL *", at(7, 1, 0, 0, 0)},
+		{"0 0 29 2 *", at(2, 29, 0, 0, 0)},
+		// Restricted days of month and week match either.
+		{"0 12 13 * fri", at(1, 5, 12, 0, 0)},
+		{"0 0 ? * sat", at(1, 6, 0, 0, 0)},
+		// Days starting with * are unrestricted, so both must match.
+		{"0 0 */2 * sat", at(1, 13, 0, 0, 0)},
+		{"0 0 13 * */2", at(1, 13, 0, 0, 0)},
+		{"@hourly", at(1, 1, 1, 0, 0)},
+		{"@weekly", at(1, 7, 0, 0, 0)},
+		{"CRON_TZ=America/New_York 0 9 * * *", at(1, 1, 14, 0, 0)},
+		{"TZ=Asia/Tokyo 0 9 * * *", at(1, 2, 0, 0, 0)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr, time.UTC)
+		assert.NoError(t, err, tt.expr)
+		assert.True(t, tt.want.Equal(s.Next(from)), "%s: %s", tt.expr, s.Next(from))
+	}
+
+	s, err := Parse("0 */20 * * * *", time.UTC)
+	assert.NoError(t, err)
+	next := from.Add(time.Minute)
+	for _, want := range []time.Time{at(1, 1, 0, 20, 0), at(1, 1, 0, 40, 0), at(1, 1, 1, 0, 0)} {
+		next = s.Next(next)
+		assert.Equal(t, want, next)
+	}
+
+	// Fractions of a second are not firings.
+	assert.Equal(t, at(1, 1, 0, 20, 0), s.Next(from.Add(19*time.Minute+59*time.Second+time.Millisecond)))
+}
+
+func TestParse_Location(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	s, err := Parse("0 9 * * *", tokyo)
+	assert.NoError(t, err)
+	assert.Equal(t, tokyo, s.Location())
+	s, err = Parse("CRON_TZ=UTC 0 9 * * *", tokyo)
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, s.Location())
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  error
+	}{
+		{"* * * *", ErrInvalidFieldCount},
+		{"* * * * * * *", ErrInvalidFieldCount},
+		{"@often", ErrInvalidFieldCount},
+		{"60 * * * *", ErrInvalidField},
+		{"* * 0 * *", ErrInvalidField},
+		{"*/0 * * * *", ErrInvalidField},
+		{"5-1 * * * *", ErrInvalidField},
+		{"* * * foo *", ErrInvalidField},
+		{"1,,2 * * * *", ErrInvalidField},
+		{"TZ=Nowhere/City * * * * *", ErrInvalidZone},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr, time.UTC)
+		assert.ErrorIs(t, err, tt.err, tt.expr)
+	}
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"scheduler/internal/task"
+)
+
+type Config struct {
+	Types      []Weighted[task.TaskType]
+	Priorities []Weighted[task.TaskPriority]
+	// FixedMix draws types and priorities as exact mixes of their weights
+	// instead of independently.
+	FixedMix bool
+	Arrivals Arrivals
+	// Work is nil to keep the work units of the task options.
+	Work WorkLength
+}
+
+type Option func(*Config)
+
+// DefaultConfig draws all types and priorities with equal weights, arriving
+// at once, as GenerateTask does.
+func DefaultConfig() Config {
+	return Config{
+		Types:      []Weighted[task.TaskType]{{task.Basic, 1}, {task.Extended, 1}},
+		Priorities: []Weighted[task.TaskPriority]{{task.P0, 1}, {task.P1, 1}, {task.P2, 1}, {task.P3, 1}},
+		Arrivals:   Periodic{},
+	}
+}
+
+func WithTypes(weights ...Weighted[task.TaskType]) Option {
+	return func(c *Config) {
+		c.Types = weights
+	}
+}
+
+func WithPriorities(weights ...Weighted[task.TaskPriority]) Option {
+	return func(c *Config) {
+		c.Priorities = weights
+	}
+}
+
+func WithFixedMix() Option {
+	return func(c *Config) {
+		c.FixedMix = true
+	}
+}
+
+func WithArrivals(a Arrivals) Option {
+	return func(c *Config) {
+		c.Arrivals = a
+	}
+}
+
+func WithWork(w WorkLength) Option {
+	return func(c *Config) {
+		c.Work = w
+	}
+}
+
+func (c Config) Validate() error {
+	if !validWeights(c.Types) || !validWeights(c.Priorities) {
+		return ErrInvalidWeights
+	}
+	switch a := c.Arrivals.(type) {
+	case nil:
+		return ErrInvalidArrivals
+	case Periodic:
+		if a.Period < 0 {
+			return ErrInvalidArrivals
+		}
+	case Poisson:
+		if a.Mean <= 0 {
+			return ErrInvalidArrivals
+		}
+	case Bursty:
+		if a.Size <= 0 || a.Within < 0 || a.Between < 0 {
+			return ErrInvalidArrivals
+		}
+	}
+	switch w := c.Work.(type) {
+	case FixedWork:
+		if w <= 0 {
+			return ErrInvalidWork
+		}
+	case UniformWork:
+		if w.Min <= 0 || w.Max < w.Min {
+			return ErrInvalidWork
+		}
+	case ExponentialWork:
+		if w.Mean < 1 {
+			return ErrInvalidWork
+		}
+	}
+	return nil
+}
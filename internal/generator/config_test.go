@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"scheduler/internal/task"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected error
+	}{
+		{name: "Default", opts: nil},
+		{name: "Fixed mix", opts: []Option{WithFixedMix(), WithArrivals(Bursty{Size: 3, Between: 1})}},
+		{name: "No types", opts: []Option{WithTypes()}, expected: ErrInvalidWeights},
+		{name: "Zero weights", opts: []Option{WithPriorities(Weighted[task.TaskPriority]{task.P1, 0})}, expected: ErrInvalidWeights},
+		{name: "Negative weight", opts: []Option{WithTypes(Weighted[task.TaskType]{task.Basic, 2}, Weighted[task.TaskType]{task.Extended, -1})}, expected: ErrInvalidWeights},
+		{name: "No arrivals", opts: []Option{WithArrivals(nil)}, expected: ErrInvalidArrivals},
+		{name: "Negative period", opts: []Option{WithArrivals(Periodic{Period: -1})}, expected: ErrInvalidArrivals},
+		{name: "Zero Poisson mean", opts: []Option{WithArrivals(Poisson{})}, expected: ErrInvalidArrivals},
+		{name: "Empty bursts", opts: []Option{WithArrivals(Bursty{})}, expected: ErrInvalidArrivals},
+		{name: "No work", opts: []Option{WithWork(FixedWork(0))}, expected: ErrInvalidWork},
+		{name: "Empty range", opts: []Option{WithWork(UniformWork{Min: 3, Max: 2})}, expected: ErrInvalidWork},
+		{name: "Short mean", opts: []Option{WithWork(ExponentialWork{Mean: 0.5})}, expected: ErrInvalidWork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(1, tt.opts...)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Weighted is a value drawn with a probability proportional to Weight.
+type Weighted[T any] struct {
+	Value  T
+	Weight int
+}
+
+// chooser draws values by weight. In a fixed mix it draws without
+// replacement from a bag holding Weight copies of every value, so every
+// round of the bag yields exactly the weighted mix.
+type chooser[T any] struct {
+	weights []Weighted[T]
+	total   int
+	fixed   bool
+	bag     []T
+}
+
+func newChooser[T any](weights []Weighted[T], fixed bool) *chooser[T] {
+	c := chooser[T]{weights: weights, fixed: fixed}
+	for _, w := range weights {
+		c.total += w.Weight
+	}
+	return &c
+}
+
+func (c *chooser[T]) draw(r *rand.Rand) T {
+	if c.fixed {
+		if len(c.bag) == 0 {
+			for _, w := range c.weights {
+				for i := 0; i < w.Weight; i++ {
+					c.bag = append(c.bag, w.Value)
+				}
+			}
+		}
+		i := r.Intn(len(c.bag))
+		v := c.bag[i]
+		c.bag[i] = c.bag[len(c.bag)-1]
+		c.bag = c.bag[:len(c.bag)-1]
+		return v
+	}
+	n := r.Intn(c.total)
+	for _, w := range c.weights {
+		if n < w.Weight {
+			return w.Value
+		}
+		n -= w.Weight
+	}
+	panic("unreachable")
+}
+
+func validWeights[T any](weights []Weighted[T]) bool {
+	total := 0
+	for _, w := range weights {
+		if w.Weight < 0 {
+			return false
+		}
+		total += w.Weight
+	}
+	return total > 0
+}
+
+// Arrivals is an arrival process. Gap returns the time between the arrivals
+// of task i-1 and task i, for i > 0.
+type Arrivals interface {
+	Gap(r *rand.Rand, i int) time.Duration
+}
+
+// Periodic arrivals are Period apart. A zero period makes all tasks arrive
+// at once.
+type Periodic struct {
+	Period time.Duration
+}
+
+func (p Periodic) Gap(*rand.Rand, int) time.Duration {
+	return p.Period
+}
+
+// Poisson arrivals have exponentially distributed gaps with mean Mean.
+type Poisson struct {
+	Mean time.Duration
+}
+
+func (p Poisson) Gap(r *rand.Rand, _ int) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(p.Mean))
+}
+
+// Bursty arrivals come in bursts of Size tasks Within apart, with Between
+// from the last task of a burst to the first of the next.
+type Bursty struct {
+	Size    int
+	Within  time.Duration
+	Between time.Duration
+}
+
+func (b Bursty) Gap(_ *rand.Rand, i int) time.Duration {
+	if i%b.Size == 0 {
+		return b.Between
+	}
+	return b.Within
+}
+
+// WorkLength is a distribution of the work units of a task. Units returns
+// at least one.
+type WorkLength interface {
+	Units(r *rand.Rand) int
+}
+
+// FixedWork gives every task the same number of work units.
+type FixedWork int
+
+func (f FixedWork) Units(*rand.Rand) int {
+	return int(f)
+}
+
+// UniformWork draws the work units uniformly from [Min, Max].
+type UniformWork struct {
+	Min, Max int
+}
+
+func (u UniformWork) Units(r *rand.Rand) int {
+	return u.Min + r.Intn(u.Max-u.Min+1)
+}
+
+// ExponentialWork draws the work units from an exponential distribution with
+// mean Mean, rounded down and shifted to start at one unit.
+type ExponentialWork struct {
+	Mean float64
+}
+
+func (e ExponentialWork) Units(r *rand.Rand) int {
+	return 1 + int(r.ExpFloat64()*(e.Mean-1))
+}
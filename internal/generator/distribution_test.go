@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooser(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	weights := []Weighted[string]{{"a", 3}, {"b", 0}, {"c", 1}}
+
+	counts := make(map[string]int)
+	c := newChooser(weights, false)
+	for i := 0; i < 4000; i++ {
+		counts[c.draw(r)]++
+	}
+	assert.Zero(t, counts["b"])
+	assert.InDelta(t, 3000, counts["a"], 150)
+
+	// Every round of a fixed mix has exactly the weighted counts.
+	c = newChooser(weights, true)
+	for round := 0; round < 3; round++ {
+		counts = make(map[string]int)
+		for i := 0; i < 4; i++ {
+			counts[c.draw(r)]++
+		}
+		assert.Equal(t, map[string]int{"a": 3, "c": 1}, counts)
+	}
+}
+
+func TestArrivals(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	assert.Equal(t, time.Second, Periodic{Period: time.Second}.Gap(r, 1))
+
+	b := Bursty{Size: 3, Within: time.Millisecond, Between: time.Second}
+	gaps := make([]time.Duration, 0)
+	for i := 1; i < 7; i++ {
+		gaps = append(gaps, b.Gap(r, i))
+	}
+	assert.Equal(t, []time.Duration{time.Millisecond, time.Millisecond, time.Second, time.Millisecond, time.Millisecond, time.Second}, gaps)
+
+	var sum time.Duration
+	p := Poisson{Mean: 10 * time.Millisecond}
+	for i := 1; i <= 2000; i++ {
+		gap := p.Gap(r, i)
+		assert.GreaterOrEqual(t, gap, time.Duration(0))
+		sum += gap
+	}
+	assert.InDelta(t, float64(10*time.Millisecond), float64(sum/2000), float64(time.Millisecond))
+}
+
+func TestWorkLength(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	assert.Equal(t, 4, FixedWork(4).Units(r))
+
+	u := UniformWork{Min: 2, Max: 4}
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		seen[u.Units(r)] = true
+	}
+	assert.Equal(t, map[int]bool{2: true, 3: true, 4: true}, seen)
+
+	e := ExponentialWork{Mean: 1}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 1, e.Units(r))
+	}
+	e = ExponentialWork{Mean: 6}
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, e.Units(r), 1)
+	}
+}
@@ -0,0 +1,10 @@
+package generator
+
+import "errors"
+
+var (
+	ErrInvalidWeights  = errors.New("weights must not be negative and not all zero")
+	ErrInvalidArrivals = errors.New("invalid arrival process")
+	ErrInvalidWork     = errors.New("invalid work length distribution")
+	ErrInvalidSeed     = errors.New("invalid generator seed")
+)
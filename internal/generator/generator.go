@@ -1,24 +1,128 @@
 package generator
 
 import (
-	"log"
+	"fmt"
 	"math/rand"
+	"os"
 	"scheduler/internal/task"
+	"strconv"
+	"time"
 )
 
-func GenerateTask() (*task.Task, error) {
+func GenerateTask(opts ...task.Option) (*task.Task, error) {
+	return generate(rand.Intn, opts...)
+}
+
+// GenerateTaskWith draws the task from r, so a seeded r gives the same types
+// and priorities every run.
+func GenerateTaskWith(r *rand.Rand, opts ...task.Option) (*task.Task, error) {
+	return generate(r.Intn, opts...)
+}
+
+func generate(intn func(int) int, opts ...task.Option) (*task.Task, error) {
 	types := []task.TaskType{task.Basic, task.Extended}
 	priorities := []task.TaskPriority{task.P0, task.P1, task.P2, task.P3}
 
-	typeIndex := rand.Intn(len(types))
-	priorityIndex := rand.Intn(len(priorities))
+	typeIndex := intn(len(types))
+	priorityIndex := intn(len(priorities))
 
-	t, err := task.New(types[typeIndex], priorities[priorityIndex], task.Suspended)
+	t, err := task.New(types[typeIndex], priorities[priorityIndex], task.Suspended, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("Generated task | ID=%d | type=%s | p=%d", t.ID, t.GetType(), t.GetPriority())
-
 	return t, nil
 }
+
+// SeedEnv names the environment variable that fixes the seed of test runs.
+const SeedEnv = "GENERATOR_SEED"
+
+// NewSeed returns a seed for a run that does not need a fixed one.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// TestSeed returns the seed in SeedEnv, or a new one if it is unset, so a
+// failed test run can be replayed with the seed it printed.
+func TestSeed() (int64, error) {
+	v := os.Getenv(SeedEnv)
+	if v == "" {
+		return NewSeed(), nil
+	}
+	seed, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s=%s", ErrInvalidSeed, SeedEnv, v)
+	}
+	return seed, nil
+}
+
+// Generator draws tasks and their arrival times from a seeded source. The
+// same seed and options give the same tasks in the same order.
+type Generator struct {
+	seed       int64
+	r          *rand.Rand
+	cfg        Config
+	types      *chooser[task.TaskType]
+	priorities *chooser[task.TaskPriority]
+	n          int
+	at         time.Duration
+}
+
+// Arrival is a generated task and the time after the first arrival it
+// arrives at.
+type Arrival struct {
+	At   time.Duration
+	Task *task.Task
+}
+
+func New(seed int64, opts ...Option) (*Generator, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{
+		seed:       seed,
+		r:          rand.New(rand.NewSource(seed)),
+		cfg:        cfg,
+		types:      newChooser(cfg.Types, cfg.FixedMix),
+		priorities: newChooser(cfg.Priorities, cfg.FixedMix),
+	}, nil
+}
+
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// Next draws the next task. opts apply after the drawn work units.
+func (g *Generator) Next(opts ...task.Option) (Arrival, error) {
+	if g.n > 0 {
+		g.at += g.cfg.Arrivals.Gap(g.r, g.n)
+	}
+	g.n++
+	tType := g.types.draw(g.r)
+	priority := g.priorities.draw(g.r)
+	if g.cfg.Work != nil {
+		opts = append([]task.Option{task.WithProgressLimit(g.cfg.Work.Units(g.r))}, opts...)
+	}
+	t, err := task.New(tType, priority, task.Suspended, opts...)
+	if err != nil {
+		return Arrival{}, fmt.Errorf("seed %d: %w", g.seed, err)
+	}
+	return Arrival{At: g.at, Task: t}, nil
+}
+
+// Take draws the next n tasks.
+func (g *Generator) Take(n int, opts ...task.Option) ([]Arrival, error) {
+	res := make([]Arrival, 0, n)
+	for i := 0; i < n; i++ {
+		a, err := g.Next(opts...)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
@@ -1,8 +1,11 @@
 package generator
 
 import (
+	"fmt"
+	"math/rand"
 	"scheduler/internal/task"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,3 +28,81 @@ func TestGenerateTask(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateTaskWith(t *testing.T) {
+	draw := func(seed int64) []string {
+		r := rand.New(rand.NewSource(seed))
+		res := make([]string, 0, 20)
+		for i := 0; i < 20; i++ {
+			generatedTask, err := GenerateTaskWith(r)
+			assert.NoError(t, err)
+			res = append(res, fmt.Sprintf("%s/%d", generatedTask.GetType(), generatedTask.GetPriority()))
+		}
+		return res
+	}
+
+	assert.Equal(t, draw(42), draw(42))
+	assert.NotEqual(t, draw(42), draw(43))
+}
+
+func TestGenerator(t *testing.T) {
+	draw := func(seed int64) []string {
+		g, err := New(seed,
+			WithPriorities(Weighted[task.TaskPriority]{task.P1, 1}, Weighted[task.TaskPriority]{task.P3, 2}),
+			WithArrivals(Poisson{Mean: time.Second}),
+			WithWork(UniformWork{Min: 1, Max: 10}))
+		assert.NoError(t, err)
+		assert.Equal(t, seed, g.Seed())
+		arrivals, err := g.Take(20, task.WithSleepTime(time.Millisecond))
+		assert.NoError(t, err)
+		res := make([]string, 0, len(arrivals))
+		for i, a := range arrivals {
+			if i == 0 {
+				assert.Zero(t, a.At)
+			} else {
+				assert.GreaterOrEqual(t, a.At, arrivals[i-1].At)
+			}
+			assert.Contains(t, []task.TaskPriority{task.P1, task.P3}, a.Task.GetPriority())
+			res = append(res, fmt.Sprintf("%s/%d/%d/%s", a.Task.GetType(), a.Task.GetPriority(), a.Task.GetProgressLimit(), a.At))
+		}
+		return res
+	}
+
+	assert.Equal(t, draw(42), draw(42))
+	assert.NotEqual(t, draw(42), draw(43))
+}
+
+func TestGenerator_FixedMix(t *testing.T) {
+	g, err := New(7, WithFixedMix(), WithTypes(Weighted[task.TaskType]{task.Basic, 3}, Weighted[task.TaskType]{task.Extended, 1}))
+	assert.NoError(t, err)
+	arrivals, err := g.Take(8)
+	assert.NoError(t, err)
+	extended := 0
+	for _, a := range arrivals {
+		assert.Zero(t, a.At)
+		if a.Task.GetType() == task.Extended {
+			extended++
+		}
+	}
+	assert.Equal(t, 2, extended)
+
+	_, err = g.Next(task.WithProgressLimit(0))
+	assert.ErrorIs(t, err, task.ErrInvalidProgressLimit)
+	assert.ErrorContains(t, err, "seed 7")
+}
+
+func TestTestSeed(t *testing.T) {
+	t.Setenv(SeedEnv, "1234")
+	seed, err := TestSeed()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), seed)
+
+	t.Setenv(SeedEnv, "abc")
+	_, err = TestSeed()
+	assert.ErrorIs(t, err, ErrInvalidSeed)
+
+	t.Setenv(SeedEnv, "")
+	seed, err = TestSeed()
+	assert.NoError(t, err)
+	assert.NotZero(t, seed)
+}
@@ -0,0 +1,196 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"scheduler/api/schedulerpb"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SubscriberBuffer is the number of transitions a WatchTransitions client may
+// lag behind before it misses some.
+const SubscriberBuffer = 64
+
+// Server implements the scheduler gRPC service.
+type Server struct {
+	schedulerpb.UnimplementedSchedulerServer
+	s      *scheduler.Scheduler
+	events *scheduler.BroadcastSink
+}
+
+// New returns a server for s. Transitions are streamed from events, which
+// must be among the dump sinks of s; with nil events, streaming is disabled.
+func New(s *scheduler.Scheduler, events *scheduler.BroadcastSink) *Server {
+	return &Server{s: s, events: events}
+}
+
+var (
+	types = map[task.TaskType]schedulerpb.TaskType{
+		task.Basic:    schedulerpb.TaskType_TASK_TYPE_BASIC,
+		task.Extended: schedulerpb.TaskType_TASK_TYPE_EXTENDED,
+	}
+	states = map[task.TaskState]schedulerpb.TaskState{
+		task.Suspended: schedulerpb.TaskState_TASK_STATE_SUSPENDED,
+		task.Ready:     schedulerpb.TaskState_TASK_STATE_READY,
+		task.Running:   schedulerpb.TaskState_TASK_STATE_RUNNING,
+		task.Waiting:   schedulerpb.TaskState_TASK_STATE_WAITING,
+	}
+)
+
+func newTask(s task.Snapshot) *schedulerpb.Task {
+	res := &schedulerpb.Task{
+		Id:             int64(s.ID),
+		Type:           types[s.Type],
+		Priority:       int32(s.Priority),
+		State:          states[s.State],
+		Progress:       int32(s.Progress),
+		ProgressLimit:  int32(s.ProgressLimit),
+		SleepTime:      durationpb.New(s.SleepTime),
+		Affinity:       int32(s.Affinity),
+		Events:         uint64(s.Events),
+		Activations:    int32(s.Activations),
+		MaxActivations: int32(s.MaxActivations),
+	}
+	if !s.Deadline.IsZero() {
+		res.Deadline = timestamppb.New(s.Deadline)
+	}
+	return res
+}
+
+func newTransition(e trace.Event) *schedulerpb.Transition {
+	return &schedulerpb.Transition{
+		Seq:      e.Seq,
+		Time:     timestamppb.New(e.Time),
+		TaskId:   int64(e.TaskID),
+		Priority: int32(e.Priority),
+		From:     states[e.From],
+		To:       states[e.To],
+		Core:     int32(e.Core),
+	}
+}
+
+func options(req *schedulerpb.SubmitTaskRequest) []task.Option {
+	opts := make([]task.Option, 0)
+	if req.ProgressLimit != 0 {
+		opts = append(opts, task.WithProgressLimit(int(req.ProgressLimit)))
+	}
+	if req.SleepTime != nil {
+		opts = append(opts, task.WithSleepTime(req.SleepTime.AsDuration()))
+	}
+	if req.Deadline != nil {
+		opts = append(opts, task.WithDeadline(req.Deadline.AsTime()))
+	}
+	if req.Affinity != nil {
+		opts = append(opts, task.WithAffinity(int(*req.Affinity)))
+	}
+	if req.MaxActivations != 0 {
+		opts = append(opts, task.WithMaxActivations(int(req.MaxActivations)))
+	}
+	return opts
+}
+
+func (srv *Server) SubmitTask(ctx context.Context, req *schedulerpb.SubmitTaskRequest) (*schedulerpb.Task, error) {
+	var tType task.TaskType
+	for k, v := range types {
+		if v == req.Type {
+			tType = k
+		}
+	}
+	affinity := task.AnyCore
+	if req.Affinity != nil {
+		affinity = int(*req.Affinity)
+	}
+	if err := srv.s.Config().CheckTask(task.TaskPriority(req.Priority), affinity); err != nil {
+		return nil, statusOf(err)
+	}
+	t, err := task.New(tType, task.TaskPriority(req.Priority), task.Suspended, options(req)...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := srv.s.AddNewTask(t); err != nil {
+		return nil, statusOf(err)
+	}
+	return newTask(t.Snapshot()), nil
+}
+
+func (srv *Server) GetTask(ctx context.Context, req *schedulerpb.GetTaskRequest) (*schedulerpb.Task, error) {
+	t, err := srv.s.Task(int(req.Id))
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return newTask(t.Snapshot()), nil
+}
+
+func (srv *Server) ListTasks(ctx context.Context, req *schedulerpb.ListTasksRequest) (*schedulerpb.ListTasksResponse, error) {
+	res := &schedulerpb.ListTasksResponse{}
+	for _, t := range srv.s.Tasks() {
+		res.Tasks = append(res.Tasks, newTask(t.Snapshot()))
+	}
+	return res, nil
+}
+
+func (srv *Server) CancelTask(ctx context.Context, req *schedulerpb.CancelTaskRequest) (*schedulerpb.Task, error) {
+	if err := srv.s.CancelTask(int(req.Id)); err != nil {
+		return nil, statusOf(err)
+	}
+	return srv.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: req.Id})
+}
+
+func (srv *Server) ActivateTask(ctx context.Context, req *schedulerpb.ActivateTaskRequest) (*schedulerpb.Task, error) {
+	if err := srv.s.ActivateTask(int(req.Id)); err != nil {
+		return nil, statusOf(err)
+	}
+	return srv.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: req.Id})
+}
+
+func (srv *Server) SetEvent(ctx context.Context, req *schedulerpb.SetEventRequest) (*schedulerpb.Task, error) {
+	if err := srv.s.SetEvent(int(req.Id), task.EventMask(req.Mask)); err != nil {
+		return nil, statusOf(err)
+	}
+	return srv.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: req.Id})
+}
+
+// WatchTransitions sends every transition until the client goes away or the
+// scheduler stops.
+func (srv *Server) WatchTransitions(req *schedulerpb.WatchTransitionsRequest, stream schedulerpb.Scheduler_WatchTransitionsServer) error {
+	if srv.events == nil {
+		return status.Error(codes.Unimplemented, "transition streaming is disabled")
+	}
+	events, cancel := srv.events.Subscribe(SubscriberBuffer)
+	defer cancel()
+	// The headers tell the client that it gets transitions from now on.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(newTransition(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func statusOf(err error) error {
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, scheduler.ErrTaskSuspended), errors.Is(err, scheduler.ErrTaskNotQueued), errors.Is(err, task.ErrNotExtended), errors.Is(err, task.ErrActivationLimit):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,219 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"scheduler/api/schedulerpb"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestClient serves srv over an in-memory connection.
+func newTestClient(t *testing.T, srv *Server) schedulerpb.SchedulerClient {
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	schedulerpb.RegisterSchedulerServer(g, srv)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return schedulerpb.NewSchedulerClient(conn)
+}
+
+func newTestScheduler(t *testing.T, opts ...scheduler.Option) (schedulerpb.SchedulerClient, *scheduler.Scheduler) {
+	events := scheduler.NewBroadcastSink()
+	s, err := scheduler.New(append([]scheduler.Option{scheduler.WithDumpSink(events)}, opts...)...)
+	assert.NoError(t, err)
+	return newTestClient(t, New(s, events)), s
+}
+
+func TestServer_Tasks(t *testing.T) {
+	client, _ := newTestScheduler(t)
+	ctx := context.Background()
+
+	created, err := client.SubmitTask(ctx, &schedulerpb.SubmitTaskRequest{
+		Type:      schedulerpb.TaskType_TASK_TYPE_EXTENDED,
+		Priority:  int32(task.P2),
+		SleepTime: durationpb.New(10 * time.Millisecond),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, schedulerpb.TaskType_TASK_TYPE_EXTENDED, created.Type)
+	assert.Equal(t, schedulerpb.TaskState_TASK_STATE_SUSPENDED, created.State)
+	assert.Equal(t, 10*time.Millisecond, created.SleepTime.AsDuration())
+	assert.Equal(t, int32(task.AnyCore), created.Affinity)
+	assert.Nil(t, created.Deadline)
+
+	got, err := client.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: created.Id})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(created, got))
+
+	list, err := client.ListTasks(ctx, &schedulerpb.ListTasksRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Tasks, 1)
+	assert.True(t, proto.Equal(created, list.Tasks[0]))
+
+	_, err = client.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: -1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SubmitInvalidTask(t *testing.T) {
+	client, _ := newTestScheduler(t)
+	one := int32(1)
+
+	tests := []struct {
+		name string
+		req  *schedulerpb.SubmitTaskRequest
+	}{
+		{name: "Unspecified type", req: &schedulerpb.SubmitTaskRequest{}},
+		{name: "Invalid sleep time", req: &schedulerpb.SubmitTaskRequest{Type: schedulerpb.TaskType_TASK_TYPE_BASIC, SleepTime: durationpb.New(-time.Second)}},
+		{name: "Priority above scheduler levels", req: &schedulerpb.SubmitTaskRequest{Type: schedulerpb.TaskType_TASK_TYPE_BASIC, Priority: int32(task.P3) + 1}},
+		{name: "Affinity above scheduler cores", req: &schedulerpb.SubmitTaskRequest{Type: schedulerpb.TaskType_TASK_TYPE_BASIC, Affinity: &one}},
+	}
+	// Rejected tasks take no ID.
+	next := task.NextID()
+	defer func() { assert.Equal(t, next, task.NextID()) }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.SubmitTask(context.Background(), tt.req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestServer_CancelTask(t *testing.T) {
+	client, s := newTestScheduler(t)
+	ctx := context.Background()
+	tsk, err := task.New(task.Basic, task.P1, task.Suspended)
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(tsk))
+
+	cancelled, err := client.CancelTask(ctx, &schedulerpb.CancelTaskRequest{Id: int64(tsk.ID)})
+	assert.NoError(t, err)
+	assert.Equal(t, schedulerpb.TaskState_TASK_STATE_SUSPENDED, cancelled.State)
+	assert.Equal(t, int32(0), cancelled.Progress)
+
+	_, err = client.CancelTask(ctx, &schedulerpb.CancelTaskRequest{Id: int64(tsk.ID)})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.CancelTask(ctx, &schedulerpb.CancelTaskRequest{Id: -1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ActivateTask(t *testing.T) {
+	client, _ := newTestScheduler(t)
+	ctx := context.Background()
+
+	created, err := client.SubmitTask(ctx, &schedulerpb.SubmitTaskRequest{Type: schedulerpb.TaskType_TASK_TYPE_BASIC, Priority: int32(task.P1), MaxActivations: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), created.MaxActivations)
+
+	got, err := client.ActivateTask(ctx, &schedulerpb.ActivateTaskRequest{Id: created.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), got.Activations)
+
+	_, err = client.ActivateTask(ctx, &schedulerpb.ActivateTaskRequest{Id: created.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, task.ErrActivationLimit.Error(), status.Convert(err).Message())
+	_, err = client.ActivateTask(ctx, &schedulerpb.ActivateTaskRequest{Id: -1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SetEvent(t *testing.T) {
+	client, s := newTestScheduler(t, scheduler.WithTickDuration(10*time.Millisecond), scheduler.WithRunForever())
+	ctx := context.Background()
+	basic, err := task.New(task.Basic, task.P0, task.Suspended, task.WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	waiter, err := task.NewWithFunc(task.Extended, task.P1, func(ctx task.Context) error {
+		_, err := ctx.WaitEvent(0b10)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(basic))
+	assert.NoError(t, s.AddNewTask(waiter))
+
+	_, err = client.SetEvent(ctx, &schedulerpb.SetEventRequest{Id: int64(basic.ID), Mask: 0b10})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.SetEvent(ctx, &schedulerpb.SetEventRequest{Id: int64(waiter.ID), Mask: 0b10})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	state := func(id int) schedulerpb.TaskState {
+		got, err := client.GetTask(ctx, &schedulerpb.GetTaskRequest{Id: int64(id)})
+		assert.NoError(t, err)
+		return got.GetState()
+	}
+	s.Run(ctx)
+	assert.Eventually(t, func() bool {
+		return state(waiter.ID) == schedulerpb.TaskState_TASK_STATE_WAITING
+	}, time.Second, 10*time.Millisecond)
+
+	got, err := client.SetEvent(ctx, &schedulerpb.SetEventRequest{Id: int64(waiter.ID), Mask: 0b10})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0b10), got.Events)
+	assert.Eventually(t, func() bool {
+		return state(waiter.ID) == schedulerpb.TaskState_TASK_STATE_SUSPENDED && state(basic.ID) == schedulerpb.TaskState_TASK_STATE_SUSPENDED
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
+func TestServer_WatchTransitions(t *testing.T) {
+	client, s := newTestScheduler(t, scheduler.WithTickDuration(10*time.Millisecond))
+	ctx := context.Background()
+
+	stream, err := client.WatchTransitions(ctx, &schedulerpb.WatchTransitionsRequest{})
+	assert.NoError(t, err)
+	// The stream is set up once its headers arrive.
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	created, err := client.SubmitTask(ctx, &schedulerpb.SubmitTaskRequest{
+		Type:      schedulerpb.TaskType_TASK_TYPE_BASIC,
+		Priority:  int32(task.P1),
+		SleepTime: durationpb.New(time.Millisecond),
+	})
+	assert.NoError(t, err)
+	s.Run(ctx)
+
+	// The stream ends once the scheduler has stopped.
+	transitions := make([][2]schedulerpb.TaskState, 0)
+	for {
+		tr, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		assert.Equal(t, created.Id, tr.TaskId)
+		transitions = append(transitions, [2]schedulerpb.TaskState{tr.From, tr.To})
+	}
+	assert.Equal(t, [][2]schedulerpb.TaskState{
+		{schedulerpb.TaskState_TASK_STATE_UNSPECIFIED, schedulerpb.TaskState_TASK_STATE_SUSPENDED},
+		{schedulerpb.TaskState_TASK_STATE_SUSPENDED, schedulerpb.TaskState_TASK_STATE_READY},
+		{schedulerpb.TaskState_TASK_STATE_READY, schedulerpb.TaskState_TASK_STATE_RUNNING},
+		{schedulerpb.TaskState_TASK_STATE_RUNNING, schedulerpb.TaskState_TASK_STATE_SUSPENDED},
+	}, transitions)
+}
+
+func TestServer_StreamingDisabled(t *testing.T) {
+	s, err := scheduler.New()
+	assert.NoError(t, err)
+	client := newTestClient(t, New(s, nil))
+
+	stream, err := client.WatchTransitions(context.Background(), &schedulerpb.WatchTransitionsRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,341 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"scheduler/internal/metrics"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"strconv"
+	"time"
+)
+
+// SubscriberBuffer is the number of transitions an event stream client may
+// lag behind before it misses some.
+const SubscriberBuffer = 64
+
+// Server exposes a running scheduler over HTTP/JSON.
+type Server struct {
+	s      *scheduler.Scheduler
+	events *scheduler.BroadcastSink
+	mux    *http.ServeMux
+}
+
+// New returns a server for s. Transitions are streamed from events, which
+// must be among the dump sinks of s; with nil events, streaming is disabled.
+func New(s *scheduler.Scheduler, events *scheduler.BroadcastSink) *Server {
+	srv := &Server{s: s, events: events, mux: http.NewServeMux()}
+	srv.mux.HandleFunc("POST /tasks", srv.submitTask)
+	srv.mux.HandleFunc("GET /tasks", srv.listTasks)
+	srv.mux.HandleFunc("GET /tasks/{id}", srv.getTask)
+	srv.mux.HandleFunc("PUT /tasks/{id}/priority", srv.setPriority)
+	srv.mux.HandleFunc("POST /tasks/{id}/events", srv.setEvent)
+	srv.mux.HandleFunc("POST /tasks/{id}/activate", srv.activateTask)
+	srv.mux.HandleFunc("GET /queues", srv.getQueues)
+	srv.mux.HandleFunc("GET /transitions", srv.streamTransitions)
+	srv.mux.Handle("GET /metrics", metrics.Handler(s))
+	srv.mux.Handle("GET /metrics/stats", metrics.StatsHandler(s))
+	return srv
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+// Task is the JSON form of a task.
+type Task struct {
+	ID             int               `json:"id"`
+	Type           task.TaskType     `json:"type"`
+	Priority       task.TaskPriority `json:"priority"`
+	State          task.TaskState    `json:"state"`
+	Progress       int               `json:"progress"`
+	ProgressLimit  int               `json:"progress_limit"`
+	SleepTime      string            `json:"sleep_time"`
+	Deadline       time.Time         `json:"deadline,omitzero"`
+	Affinity       int               `json:"affinity"`
+	Events         task.EventMask    `json:"events"`
+	Activations    int               `json:"activations"`
+	MaxActivations int               `json:"max_activations"`
+}
+
+func newTask(s task.Snapshot) Task {
+	return Task{
+		ID:             s.ID,
+		Type:           s.Type,
+		Priority:       s.Priority,
+		State:          s.State,
+		Progress:       s.Progress,
+		ProgressLimit:  s.ProgressLimit,
+		SleepTime:      s.SleepTime.String(),
+		Deadline:       s.Deadline,
+		Affinity:       s.Affinity,
+		Events:         s.Events,
+		Activations:    s.Activations,
+		MaxActivations: s.MaxActivations,
+	}
+}
+
+// SubmitRequest describes a new task. Zero values select the task defaults.
+type SubmitRequest struct {
+	Type           task.TaskType     `json:"type"`
+	Priority       task.TaskPriority `json:"priority"`
+	ProgressLimit  int               `json:"progress_limit,omitempty"`
+	SleepTime      string            `json:"sleep_time,omitempty"`
+	Deadline       time.Time         `json:"deadline,omitzero"`
+	Affinity       *int              `json:"affinity,omitempty"`
+	MaxActivations int               `json:"max_activations,omitempty"`
+}
+
+func (r SubmitRequest) options() ([]task.Option, error) {
+	opts := make([]task.Option, 0)
+	if r.ProgressLimit != 0 {
+		opts = append(opts, task.WithProgressLimit(r.ProgressLimit))
+	}
+	if r.SleepTime != "" {
+		d, err := time.ParseDuration(r.SleepTime)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, task.WithSleepTime(d))
+	}
+	if !r.Deadline.IsZero() {
+		opts = append(opts, task.WithDeadline(r.Deadline))
+	}
+	if r.Affinity != nil {
+		opts = append(opts, task.WithAffinity(*r.Affinity))
+	}
+	if r.MaxActivations != 0 {
+		opts = append(opts, task.WithMaxActivations(r.MaxActivations))
+	}
+	return opts, nil
+}
+
+type PriorityRequest struct {
+	Priority task.TaskPriority `json:"priority"`
+}
+
+type EventRequest struct {
+	Mask task.EventMask `json:"mask"`
+}
+
+type Queues struct {
+	Running   []*Task  `json:"running"`
+	Ready     [][]Task `json:"ready"`
+	Suspended []Task   `json:"suspended"`
+	Waiting   [][]Task `json:"waiting"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (srv *Server) submitTask(w http.ResponseWriter, r *http.Request) {
+	var req SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	affinity := task.AnyCore
+	if req.Affinity != nil {
+		affinity = *req.Affinity
+	}
+	if err := srv.s.Config().CheckTask(req.Priority, affinity); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	t, err := task.New(req.Type, req.Priority, task.Suspended, opts...)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := srv.s.AddNewTask(t); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", t.ID))
+	writeJSON(w, http.StatusCreated, newTask(t.Snapshot()))
+}
+
+func (srv *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	res := make([]Task, 0)
+	for _, t := range srv.s.Tasks() {
+		res = append(res, newTask(t.Snapshot()))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := srv.task(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newTask(t.Snapshot()))
+}
+
+func (srv *Server) setPriority(w http.ResponseWriter, r *http.Request) {
+	t, ok := srv.task(w, r)
+	if !ok {
+		return
+	}
+	var req PriorityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := srv.s.SetTaskPriority(t.ID, req.Priority); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTask(t.Snapshot()))
+}
+
+func (srv *Server) setEvent(w http.ResponseWriter, r *http.Request) {
+	t, ok := srv.task(w, r)
+	if !ok {
+		return
+	}
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := srv.s.SetEvent(t.ID, req.Mask); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTask(t.Snapshot()))
+}
+
+func (srv *Server) activateTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := srv.task(w, r)
+	if !ok {
+		return
+	}
+	if err := srv.s.ActivateTask(t.ID); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTask(t.Snapshot()))
+}
+
+func (srv *Server) getQueues(w http.ResponseWriter, r *http.Request) {
+	q := srv.s.Queues()
+	res := Queues{
+		Running:   make([]*Task, len(q.Running)),
+		Ready:     tasksByPriority(q.Ready),
+		Suspended: tasks(q.Suspended),
+		Waiting:   tasksByPriority(q.Waiting),
+	}
+	for i, v := range q.Running {
+		if v != nil {
+			t := newTask(*v)
+			res.Running[i] = &t
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// streamTransitions sends every transition as a Server-Sent Event until the
+// client goes away or the scheduler stops.
+func (srv *Server) streamTransitions(w http.ResponseWriter, r *http.Request) {
+	if srv.events == nil {
+		writeError(w, http.StatusNotFound, errors.New("transition streaming is disabled"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	events, cancel := srv.events.Subscribe(SubscriberBuffer)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes e without its queues, which clients fetch from /queues.
+func writeEvent(w http.ResponseWriter, e trace.Event) error {
+	e.Queues = nil
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: transition\ndata: %s\n\n", e.Seq, data)
+	return err
+}
+
+func (srv *Server) task(w http.ResponseWriter, r *http.Request) (*task.Task, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	t, err := srv.s.Task(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return t, true
+}
+
+func tasks(snaps []task.Snapshot) []Task {
+	res := make([]Task, len(snaps))
+	for i, v := range snaps {
+		res[i] = newTask(v)
+	}
+	return res
+}
+
+func tasksByPriority(snaps [][]task.Snapshot) [][]Task {
+	res := make([][]Task, len(snaps))
+	for i, v := range snaps {
+		res[i] = tasks(v)
+	}
+	return res
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		return http.StatusNotFound
+	case errors.Is(err, scheduler.ErrTaskSuspended), errors.Is(err, task.ErrNotExtended), errors.Is(err, task.ErrActivationLimit):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
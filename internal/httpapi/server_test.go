@@ -0,0 +1,236 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, opts ...scheduler.Option) (*httptest.Server, *scheduler.Scheduler, *scheduler.BroadcastSink) {
+	events := scheduler.NewBroadcastSink()
+	opts = append([]scheduler.Option{scheduler.WithDumpSink(events)}, opts...)
+	s, err := scheduler.New(opts...)
+	assert.NoError(t, err)
+	srv := httptest.NewServer(New(s, events))
+	t.Cleanup(srv.Close)
+	return srv, s, events
+}
+
+func do(t *testing.T, method, url string, body any, res any) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		assert.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	if res != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+	}
+	return resp
+}
+
+func TestServer_Tasks(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	var created Task
+	resp := do(t, http.MethodPost, srv.URL+"/tasks", SubmitRequest{Type: task.Extended, Priority: task.P2, SleepTime: "10ms"}, &created)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf("/tasks/%d", created.ID), resp.Header.Get("Location"))
+	assert.Equal(t, task.Extended, created.Type)
+	assert.Equal(t, task.Suspended, created.State)
+	assert.Equal(t, "10ms", created.SleepTime)
+	assert.Equal(t, task.AnyCore, created.Affinity)
+
+	var got Task
+	resp = do(t, http.MethodGet, fmt.Sprintf("%s/tasks/%d", srv.URL, created.ID), nil, &got)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, created, got)
+
+	var list []Task
+	do(t, http.MethodGet, srv.URL+"/tasks", nil, &list)
+	assert.Equal(t, []Task{created}, list)
+
+	var errResp errorResponse
+	resp = do(t, http.MethodGet, srv.URL+"/tasks/-1", nil, &errResp)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, scheduler.ErrUnknownTask.Error(), errResp.Error)
+	resp = do(t, http.MethodGet, srv.URL+"/tasks/abc", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_SubmitInvalidTask(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	one := 1
+
+	tests := []struct {
+		name string
+		body any
+	}{
+		{name: "Not JSON", body: "task"},
+		{name: "Invalid type", body: SubmitRequest{Type: "periodic"}},
+		{name: "Invalid sleep time", body: SubmitRequest{Type: task.Basic, SleepTime: "soon"}},
+		{name: "Priority above scheduler levels", body: SubmitRequest{Type: task.Basic, Priority: task.P3 + 1}},
+		{name: "Affinity above scheduler cores", body: SubmitRequest{Type: task.Basic, Affinity: &one}},
+	}
+	// Rejected tasks take no ID.
+	next := task.NextID()
+	defer func() { assert.Equal(t, next, task.NextID()) }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errResp errorResponse
+			resp := do(t, http.MethodPost, srv.URL+"/tasks", tt.body, &errResp)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.NotEmpty(t, errResp.Error)
+		})
+	}
+}
+
+func TestServer_SetPriority(t *testing.T) {
+	srv, s, _ := newTestServer(t)
+	tsk, err := task.New(task.Basic, task.P0, task.Suspended)
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(tsk))
+
+	var got Task
+	resp := do(t, http.MethodPut, fmt.Sprintf("%s/tasks/%d/priority", srv.URL, tsk.ID), PriorityRequest{Priority: task.P3}, &got)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, task.TaskPriority(task.P3), got.Priority)
+
+	resp = do(t, http.MethodPut, fmt.Sprintf("%s/tasks/%d/priority", srv.URL, tsk.ID), PriorityRequest{Priority: 42}, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_ActivateTask(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	var created Task
+	do(t, http.MethodPost, srv.URL+"/tasks", SubmitRequest{Type: task.Basic, Priority: task.P1, MaxActivations: 2}, &created)
+	assert.Equal(t, 2, created.MaxActivations)
+
+	url := fmt.Sprintf("%s/tasks/%d/activate", srv.URL, created.ID)
+	var got Task
+	resp := do(t, http.MethodPost, url, nil, &got)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, got.Activations)
+
+	var errResp errorResponse
+	resp = do(t, http.MethodPost, url, nil, &errResp)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, task.ErrActivationLimit.Error(), errResp.Error)
+	resp = do(t, http.MethodPost, srv.URL+"/tasks/-1/activate", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_SetEvent(t *testing.T) {
+	srv, s, _ := newTestServer(t, scheduler.WithTickDuration(10*time.Millisecond), scheduler.WithRunForever())
+	basic, err := task.New(task.Basic, task.P0, task.Suspended, task.WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	waiter, err := task.NewWithFunc(task.Extended, task.P1, func(ctx task.Context) error {
+		_, err := ctx.WaitEvent(0b10)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(basic))
+	assert.NoError(t, s.AddNewTask(waiter))
+
+	url := fmt.Sprintf("%s/tasks/%d/events", srv.URL, waiter.ID)
+	resp := do(t, http.MethodPost, url, EventRequest{Mask: 0b10}, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = do(t, http.MethodPost, fmt.Sprintf("%s/tasks/%d/events", srv.URL, basic.ID), EventRequest{Mask: 0b10}, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	state := func(id int) task.TaskState {
+		var got Task
+		do(t, http.MethodGet, fmt.Sprintf("%s/tasks/%d", srv.URL, id), nil, &got)
+		return got.State
+	}
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		return state(waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+
+	var queues Queues
+	do(t, http.MethodGet, srv.URL+"/queues", nil, &queues)
+	assert.Equal(t, waiter.ID, queues.Waiting[task.P1][0].ID)
+	assert.Len(t, queues.Running, 1)
+
+	var got Task
+	resp = do(t, http.MethodPost, url, EventRequest{Mask: 0b10}, &got)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, task.EventMask(0b10), got.Events)
+	assert.Eventually(t, func() bool {
+		return state(waiter.ID) == task.Suspended && state(basic.ID) == task.Suspended
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
+
+func TestServer_StreamTransitions(t *testing.T) {
+	srv, s, _ := newTestServer(t, scheduler.WithTickDuration(10*time.Millisecond))
+
+	resp, err := http.Get(srv.URL + "/transitions")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var created Task
+	do(t, http.MethodPost, srv.URL+"/tasks", SubmitRequest{Type: task.Basic, Priority: task.P1, SleepTime: "1ms"}, &created)
+	s.Run(context.Background())
+
+	// The stream ends once the scheduler has stopped.
+	transitions := make([]string, 0)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e trace.Event
+		assert.NoError(t, json.Unmarshal([]byte(data), &e))
+		assert.Equal(t, created.ID, e.TaskID)
+		transitions = append(transitions, fmt.Sprintf("%s -> %s", e.From, e.To))
+	}
+	assert.Equal(t, []string{" -> suspended", "suspended -> ready", "ready -> running", "running -> suspended"}, transitions)
+}
+
+func TestServer_StreamingDisabled(t *testing.T) {
+	s, err := scheduler.New()
+	assert.NoError(t, err)
+	srv := httptest.NewServer(New(s, nil))
+	defer srv.Close()
+
+	resp := do(t, http.MethodGet, srv.URL+"/transitions", nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	do(t, http.MethodPost, srv.URL+"/tasks", SubmitRequest{Type: task.Basic, Priority: task.P1}, nil)
+
+	get := func(path string) string {
+		resp, err := http.Get(srv.URL + path)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return string(body)
+	}
+	assert.Contains(t, get("/metrics"), `scheduler_transitions_total{from="",to="suspended"} 1`+"\n")
+	assert.Contains(t, get("/metrics/stats"), "scheduler_tasks_added_total 1\n")
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"cmp"
+	"io"
+	"maps"
+	"net/http"
+	"scheduler/internal/scheduler"
+	"slices"
+	"strconv"
+)
+
+// OpenMetricsType is the OpenMetrics text exposition format.
+const OpenMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Handler serves the queue lengths and counters of s in the OpenMetrics
+// format, for scraping often.
+func Handler(s *scheduler.Scheduler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", OpenMetricsType)
+		WriteOpenMetrics(w, s.QueueLengths(), s.Counts())
+	})
+}
+
+func WriteOpenMetrics(w io.Writer, q scheduler.QueueLengths, c scheduler.Counts) error {
+	pw := newWriter(w)
+	pw.family("scheduler_ready_tasks", "gauge", "Tasks in the ready queues by priority.")
+	for p, n := range q.Ready {
+		pw.sample("scheduler_ready_tasks", priority(p), float64(n))
+	}
+	pw.family("scheduler_waiting_tasks", "gauge", "Tasks waiting for events by priority.")
+	for p, n := range q.Waiting {
+		pw.sample("scheduler_waiting_tasks", priority(p), float64(n))
+	}
+	pw.family("scheduler_suspended_tasks", "gauge", "Tasks waiting to be admitted to the ready queues.")
+	pw.sample("scheduler_suspended_tasks", nil, float64(q.Suspended))
+
+	pw.family("scheduler_transitions", "counter", "Task state transitions; from is empty for new tasks.")
+	transitions := slices.SortedFunc(maps.Keys(c.Transitions), func(a, b scheduler.Transition) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+	for _, tr := range transitions {
+		pw.sample("scheduler_transitions_total", []string{"from", string(tr.From), "to", string(tr.To)}, float64(c.Transitions[tr]))
+	}
+
+	h := c.RunDurations
+	pw.family("scheduler_run_duration_seconds", "histogram", "Time tasks ran on a core before leaving it.")
+	var cumulative uint64
+	for i, n := range h.Counts {
+		cumulative += n
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = strconv.FormatFloat(h.Bounds[i].Seconds(), 'g', -1, 64)
+		}
+		pw.sample("scheduler_run_duration_seconds_bucket", []string{"le", le}, float64(cumulative))
+	}
+	pw.sample("scheduler_run_duration_seconds_sum", nil, h.Sum.Seconds())
+	pw.sample("scheduler_run_duration_seconds_count", nil, float64(h.Count))
+
+	pw.family("scheduler_idle_ticks", "counter", "Ticks a core found no task to run.")
+	for i, n := range c.IdleTicks {
+		pw.sample("scheduler_idle_ticks_total", []string{"core", strconv.Itoa(i)}, float64(n))
+	}
+	pw.printf("# EOF\n")
+	return pw.flush()
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	q := scheduler.QueueLengths{Ready: []int{2, 0}, Waiting: []int{0, 1}, Suspended: 3}
+	c := scheduler.Counts{
+		Transitions: map[scheduler.Transition]uint64{
+			{From: task.Ready, To: task.Running}: 4,
+			{To: task.Suspended}:                 5,
+		},
+		RunDurations: scheduler.Histogram{
+			Bounds: []time.Duration{10 * time.Millisecond, time.Second},
+			Counts: []uint64{1, 2, 1},
+			Sum:    3500 * time.Millisecond,
+			Count:  4,
+		},
+		IdleTicks: []uint64{7},
+	}
+	var b strings.Builder
+	assert.NoError(t, WriteOpenMetrics(&b, q, c))
+
+	assert.Equal(t, `# HELP scheduler_ready_tasks Tasks in the ready queues by priority.
+# TYPE scheduler_ready_tasks gauge
+scheduler_ready_tasks{priority="0"} 2
+scheduler_ready_tasks{priority="1"} 0
+# HELP scheduler_waiting_tasks Tasks waiting for events by priority.
+# TYPE scheduler_waiting_tasks gauge
+scheduler_waiting_tasks{priority="0"} 0
+scheduler_waiting_tasks{priority="1"} 1
+# HELP scheduler_suspended_tasks Tasks waiting to be admitted to the ready queues.
+# TYPE scheduler_suspended_tasks gauge
+scheduler_suspended_tasks 3
+# HELP scheduler_transitions Task state transitions; from is empty for new tasks.
+# TYPE scheduler_transitions counter
+scheduler_transitions_total{from="",to="suspended"} 5
+scheduler_transitions_total{from="ready",to="running"} 4
+# HELP scheduler_run_duration_seconds Time tasks ran on a core before leaving it.
+# TYPE scheduler_run_duration_seconds histogram
+scheduler_run_duration_seconds_bucket{le="0.01"} 1
+scheduler_run_duration_seconds_bucket{le="1"} 3
+scheduler_run_duration_seconds_bucket{le="+Inf"} 4
+scheduler_run_duration_seconds_sum 3.5
+scheduler_run_duration_seconds_count 4
+# HELP scheduler_idle_ticks Ticks a core found no task to run.
+# TYPE scheduler_idle_ticks counter
+scheduler_idle_ticks_total{core="0"} 7
+# EOF
+`, b.String())
+}
+
+func TestHandler(t *testing.T) {
+	s, err := scheduler.New(scheduler.WithPriorityLevels(2))
+	assert.NoError(t, err)
+	tk, err := task.New(task.Basic, task.P1, task.Suspended)
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(tk))
+	srv := httptest.NewServer(Handler(s))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, OpenMetricsType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "scheduler_suspended_tasks 1\n")
+	assert.Contains(t, string(body), `scheduler_ready_tasks{priority="1"} 0`+"\n")
+}
@@ -0,0 +1,101 @@
+// Package metrics exposes the metrics of a scheduler in the Prometheus and
+// OpenMetrics text formats.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"scheduler/internal/scheduler"
+	"strconv"
+)
+
+// ContentType is the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// StatsHandler serves the stats of s.
+func StatsHandler(s *scheduler.Scheduler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		WriteStats(w, s.Stats())
+	})
+}
+
+// WriteStats writes the aggregate stats; the metrics of single tasks are left
+// out to keep the number of series bounded.
+func WriteStats(w io.Writer, st scheduler.Stats) error {
+	pw := newWriter(w)
+	pw.family("scheduler_tasks_added_total", "counter", "Tasks added to the scheduler.")
+	pw.sample("scheduler_tasks_added_total", nil, float64(st.Added))
+	pw.family("scheduler_tasks_completed_total", "counter", "Tasks run to completion by priority.")
+	for p, ps := range st.Priorities {
+		pw.sample("scheduler_tasks_completed_total", priority(p), float64(ps.Completed))
+	}
+	pw.family("scheduler_throughput_tasks_per_second", "gauge", "Completed tasks per second since the first task was added by priority.")
+	for p, ps := range st.Priorities {
+		pw.sample("scheduler_throughput_tasks_per_second", priority(p), ps.Throughput)
+	}
+	pw.family("scheduler_preemptions_total", "counter", "Running tasks preempted by a task of higher priority.")
+	pw.sample("scheduler_preemptions_total", nil, float64(st.Preemptions))
+	pw.family("scheduler_cpu_utilization_ratio", "gauge", "Share of core time spent running tasks.")
+	pw.sample("scheduler_cpu_utilization_ratio", nil, st.Utilization)
+	pw.summary("scheduler_response_time_seconds", "Time from adding a task to its first run.", st.ResponseTime)
+	pw.summary("scheduler_ready_time_seconds", "Time tasks spent in the ready queues.", st.ReadyTime)
+	pw.summary("scheduler_waiting_time_seconds", "Time tasks spent waiting for events.", st.WaitingTime)
+	pw.summary("scheduler_completion_time_seconds", "Time from adding a task to its completion.", st.CompletionTime)
+	return pw.flush()
+}
+
+// writer writes metric families and keeps the first error.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w)}
+}
+
+func (w *writer) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
+
+func (w *writer) family(name, typ, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample with labels given as name and value pairs.
+func (w *writer) sample(name string, labels []string, v float64) {
+	w.printf("%s", name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		w.printf("%s%s=%q", sep, labels[i], labels[i+1])
+	}
+	if len(labels) > 0 {
+		w.printf("}")
+	}
+	w.printf(" %s\n", strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (w *writer) summary(name, help string, s scheduler.Summary) {
+	w.family(name, "summary", help)
+	w.sample(name+"_sum", nil, s.Sum.Seconds())
+	w.sample(name+"_count", nil, float64(s.Count))
+}
+
+func (w *writer) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func priority(p int) []string {
+	return []string{"priority", strconv.Itoa(p)}
+}
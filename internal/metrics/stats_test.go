@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"scheduler/internal/scheduler"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStats(t *testing.T) {
+	st := scheduler.Stats{
+		Added:          3,
+		Preemptions:    1,
+		Utilization:    0.75,
+		ResponseTime:   scheduler.Summary{Count: 2, Sum: 1500 * time.Millisecond},
+		CompletionTime: scheduler.Summary{Count: 1, Sum: 4 * time.Second},
+		Priorities:     []scheduler.PriorityStats{{Completed: 1, Throughput: 0.25}, {}},
+	}
+	var b strings.Builder
+	assert.NoError(t, WriteStats(&b, st))
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE scheduler_tasks_added_total counter",
+		"scheduler_tasks_added_total 3",
+		`scheduler_tasks_completed_total{priority="0"} 1`,
+		`scheduler_tasks_completed_total{priority="1"} 0`,
+		`scheduler_throughput_tasks_per_second{priority="0"} 0.25`,
+		"scheduler_preemptions_total 1",
+		"scheduler_cpu_utilization_ratio 0.75",
+		"# TYPE scheduler_response_time_seconds summary",
+		"scheduler_response_time_seconds_sum 1.5",
+		"scheduler_response_time_seconds_count 2",
+		"scheduler_waiting_time_seconds_count 0",
+		"scheduler_completion_time_seconds_sum 4",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	s, err := scheduler.New()
+	assert.NoError(t, err)
+	srv := httptest.NewServer(StatsHandler(s))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "scheduler_tasks_added_total 0\n")
+}
@@ -0,0 +1,285 @@
+package scheduler
+
+import (
+	"context"
+	"maps"
+	"math"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"slices"
+	"time"
+)
+
+// SystemCounter is the counter every scheduler has. It ticks every
+// TickDuration of the scheduler.
+const SystemCounter = "system"
+
+// DefaultCounterMaxValue is the value the system counter wraps after.
+const DefaultCounterMaxValue uint64 = math.MaxUint32
+
+// AlarmAction is what an alarm does when it expires. It runs on the alarm
+// goroutine of the scheduler, so it must not block.
+type AlarmAction func(s *Scheduler) error
+
+// ActivateAction activates the task with the given ID. A cancelled task whose
+// body has not stopped yet is activated once it has, without blocking the
+// alarm.
+func ActivateAction(id int) AlarmAction {
+	return func(s *Scheduler) error {
+		return s.activateTask(id, false)
+	}
+}
+
+// EventAction sets events of the extended task with the given ID.
+func EventAction(id int, mask task.EventMask) AlarmAction {
+	return func(s *Scheduler) error {
+		return s.SetEvent(id, mask)
+	}
+}
+
+// CallbackAction calls fn.
+func CallbackAction(fn func()) AlarmAction {
+	return func(*Scheduler) error {
+		fn()
+		return nil
+	}
+}
+
+// counter counts ticks of the scheduler clock from its start on. Its value
+// wraps after max.
+type counter struct {
+	tick  time.Duration
+	max   uint64
+	start time.Time
+}
+
+func (c *counter) ticks(now time.Time) uint64 {
+	return uint64(now.Sub(c.start) / c.tick)
+}
+
+func (c *counter) timeOf(ticks uint64) time.Time {
+	return c.start.Add(time.Duration(ticks) * c.tick)
+}
+
+// alarm expires once its counter has counted at ticks, and every cycle ticks
+// after if cycle is set.
+type alarm struct {
+	counter *counter
+	action  AlarmAction
+	set     bool
+	at      uint64
+	cycle   uint64
+}
+
+// AddCounter adds a counter that ticks every tick of the scheduler clock from
+// now on and wraps after max.
+func (s *Scheduler) AddCounter(name string, tick time.Duration, max uint64) error {
+	if tick <= 0 || max == 0 {
+		return ErrInvalidCounter
+	}
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	if _, ok := s.counters[name]; ok {
+		return ErrCounterExists
+	}
+	s.counters[name] = &counter{tick: tick, max: max, start: s.cfg.Clock.Now()}
+	return nil
+}
+
+// GetCounterValue returns the current value of a counter.
+func (s *Scheduler) GetCounterValue(name string) (uint64, error) {
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	c, ok := s.counters[name]
+	if !ok {
+		return 0, ErrUnknownCounter
+	}
+	return c.ticks(s.cfg.Clock.Now()) % (c.max + 1), nil
+}
+
+// AddAlarm adds an alarm on a counter. The alarm does nothing until it is set.
+func (s *Scheduler) AddAlarm(name, counter string, action AlarmAction) error {
+	if action == nil {
+		return ErrInvalidAlarmAction
+	}
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	c, ok := s.counters[counter]
+	if !ok {
+		return ErrUnknownCounter
+	}
+	if _, ok := s.alarms[name]; ok {
+		return ErrAlarmExists
+	}
+	s.alarms[name] = &alarm{counter: c, action: action}
+	return nil
+}
+
+// SetRelAlarm sets an alarm to expire increment ticks from now and then every
+// cycle ticks. A zero cycle makes a single-shot alarm.
+func (s *Scheduler) SetRelAlarm(name string, increment, cycle uint64) error {
+	return s.setAlarm(name, cycle, func(c *counter, now uint64) (uint64, bool) {
+		return now + increment, increment > 0 && increment <= c.max
+	})
+}
+
+// SetAbsAlarm sets an alarm to expire when its counter next reaches start and
+// then every cycle ticks. A zero cycle makes a single-shot alarm.
+func (s *Scheduler) SetAbsAlarm(name string, start, cycle uint64) error {
+	return s.setAlarm(name, cycle, func(c *counter, now uint64) (uint64, bool) {
+		d := (start + c.max + 1 - now%(c.max+1)) % (c.max + 1)
+		if d == 0 {
+			d = c.max + 1
+		}
+		return now + d, start <= c.max
+	})
+}
+
+func (s *Scheduler) setAlarm(name string, cycle uint64, expiry func(c *counter, now uint64) (uint64, bool)) error {
+	s.aMu.Lock()
+	a, ok := s.alarms[name]
+	if !ok {
+		s.aMu.Unlock()
+		return ErrUnknownAlarm
+	}
+	at, valid := expiry(a.counter, a.counter.ticks(s.cfg.Clock.Now()))
+	switch {
+	case !valid || cycle > a.counter.max:
+		s.aMu.Unlock()
+		return ErrInvalidAlarmValue
+	case a.set:
+		s.aMu.Unlock()
+		return ErrAlarmInUse
+	}
+	a.set, a.at, a.cycle = true, at, cycle
+	s.aMu.Unlock()
+	s.wakeAlarms()
+	return nil
+}
+
+// CancelAlarm unsets an alarm.
+func (s *Scheduler) CancelAlarm(name string) error {
+	s.aMu.Lock()
+	a, ok := s.alarms[name]
+	if !ok {
+		s.aMu.Unlock()
+		return ErrUnknownAlarm
+	}
+	if !a.set {
+		s.aMu.Unlock()
+		return ErrAlarmNotSet
+	}
+	a.set = false
+	s.aMu.Unlock()
+	s.wakeAlarms()
+	return nil
+}
+
+// GetAlarm returns the ticks left before an alarm expires.
+func (s *Scheduler) GetAlarm(name string) (uint64, error) {
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	a, ok := s.alarms[name]
+	if !ok {
+		return 0, ErrUnknownAlarm
+	}
+	if !a.set {
+		return 0, ErrAlarmNotSet
+	}
+	return a.at - min(a.at, a.counter.ticks(s.cfg.Clock.Now())), nil
+}
+
+// alarmsSet reports whether an alarm is set, which keeps the scheduler from
+// stopping when idle.
+func (s *Scheduler) alarmsSet() bool {
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	for _, a := range s.alarms {
+		if a.set {
+			return true
+		}
+	}
+	return false
+}
+
+// wakeAlarms makes the alarm goroutine look at the alarms again. It is marked
+// busy before it can take the wake-up.
+func (s *Scheduler) wakeAlarms() {
+	s.cfg.Clock.Busy()
+	select {
+	case s.alarmWake <- struct{}{}:
+	default:
+		s.cfg.Clock.Idle()
+	}
+}
+
+// runAlarms waits for the next alarm to expire until ctx is cancelled.
+func (s *Scheduler) runAlarms(ctx context.Context) {
+	defer s.wg.Done()
+	clk := s.cfg.Clock
+	defer clk.Idle()
+	for {
+		var timer clock.Timer
+		if next, ok := s.nextAlarm(); ok {
+			timer = clk.NewTimer(next.Sub(clk.Now()))
+		}
+		clk.Idle()
+		select {
+		case <-ctx.Done():
+			clk.Busy()
+			stopTimer(timer)
+			select {
+			case <-s.alarmWake:
+				clk.Idle()
+			default:
+			}
+			return
+		case <-s.alarmWake:
+			stopTimer(timer)
+		case <-timerC(timer):
+			s.expireAlarms()
+		}
+	}
+}
+
+func (s *Scheduler) nextAlarm() (time.Time, bool) {
+	s.aMu.Lock()
+	defer s.aMu.Unlock()
+	var res time.Time
+	for _, a := range s.alarms {
+		if t := a.counter.timeOf(a.at); a.set && (res.IsZero() || t.Before(res)) {
+			res = t
+		}
+	}
+	return res, !res.IsZero()
+}
+
+// expireAlarms runs the actions of the alarms due, in the order of their
+// names. Cyclic alarms are set again before their actions run, to their
+// first expiry after now: cycles missed meanwhile are skipped.
+func (s *Scheduler) expireAlarms() {
+	now := s.cfg.Clock.Now()
+	s.aMu.Lock()
+	names := make([]string, 0)
+	actions := make([]AlarmAction, 0)
+	for _, name := range slices.Sorted(maps.Keys(s.alarms)) {
+		a := s.alarms[name]
+		if !a.set || a.counter.timeOf(a.at).After(now) {
+			continue
+		}
+		if a.cycle > 0 {
+			a.at += (a.counter.ticks(now)-a.at)/a.cycle*a.cycle + a.cycle
+		} else {
+			a.set = false
+		}
+		names = append(names, name)
+		actions = append(actions, a.action)
+	}
+	s.aMu.Unlock()
+	for i, action := range actions {
+		s.cfg.Logger.Debug("alarm expired", "alarm", names[i])
+		if err := action(s); err != nil {
+			s.cfg.Logger.Warn("alarm action failed", "alarm", names[i], "error", err)
+		}
+	}
+}
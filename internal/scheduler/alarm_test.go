@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_AlarmErrors(t *testing.T) {
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})))
+	assert.NoError(t, err)
+	nop := CallbackAction(func() {})
+
+	assert.ErrorIs(t, s.AddCounter("c", 0, 10), ErrInvalidCounter)
+	assert.ErrorIs(t, s.AddCounter("c", time.Second, 0), ErrInvalidCounter)
+	assert.NoError(t, s.AddCounter("c", time.Second, 9))
+	assert.ErrorIs(t, s.AddCounter(SystemCounter, time.Second, 9), ErrCounterExists)
+	_, err = s.GetCounterValue("x")
+	assert.ErrorIs(t, err, ErrUnknownCounter)
+
+	assert.ErrorIs(t, s.AddAlarm("a", "x", nop), ErrUnknownCounter)
+	assert.ErrorIs(t, s.AddAlarm("a", "c", nil), ErrInvalidAlarmAction)
+	assert.NoError(t, s.AddAlarm("a", "c", nop))
+	assert.ErrorIs(t, s.AddAlarm("a", "c", nop), ErrAlarmExists)
+
+	assert.ErrorIs(t, s.SetRelAlarm("x", 1, 0), ErrUnknownAlarm)
+	assert.ErrorIs(t, s.SetRelAlarm("a", 0, 0), ErrInvalidAlarmValue)
+	assert.ErrorIs(t, s.SetRelAlarm("a", 10, 0), ErrInvalidAlarmValue)
+	assert.ErrorIs(t, s.SetRelAlarm("a", 1, 10), ErrInvalidAlarmValue)
+	assert.ErrorIs(t, s.SetAbsAlarm("a", 10, 0), ErrInvalidAlarmValue)
+	_, err = s.GetAlarm("a")
+	assert.ErrorIs(t, err, ErrAlarmNotSet)
+	assert.ErrorIs(t, s.CancelAlarm("a"), ErrAlarmNotSet)
+	assert.ErrorIs(t, s.CancelAlarm("x"), ErrUnknownAlarm)
+
+	assert.NoError(t, s.SetRelAlarm("a", 9, 0))
+	assert.ErrorIs(t, s.SetAbsAlarm("a", 1, 0), ErrAlarmInUse)
+	left, err := s.GetAlarm("a")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(9), left)
+	assert.NoError(t, s.CancelAlarm("a"))
+
+	// The counter is at zero, so reaching zero again takes a whole round.
+	assert.NoError(t, s.SetAbsAlarm("a", 0, 0))
+	left, _ = s.GetAlarm("a")
+	assert.Equal(t, uint64(10), left)
+	assert.NoError(t, s.CancelAlarm("a"))
+	assert.NoError(t, s.SetAbsAlarm("a", 3, 0))
+	left, _ = s.GetAlarm("a")
+	assert.Equal(t, uint64(3), left)
+}
+
+func TestScheduler_CyclicAlarms(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, err := New(WithClock(clock.NewVirtual(start)))
+	assert.NoError(t, err)
+
+	periodic := newPolicyTask(t, task.P1, task.WithProgressLimit(1))
+	assert.NoError(t, s.AddNewTask(periodic))
+	waiter, err := task.NewWithFunc(task.Extended, task.P2, func(ctx task.Context) error {
+		for range 2 {
+			if _, err := ctx.WaitEvent(0b10); err != nil {
+				return err
+			}
+			ctx.ClearEvent(0b10)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(waiter))
+
+	assert.NoError(t, s.AddAlarm("activate", SystemCounter, ActivateAction(periodic.ID)))
+	assert.NoError(t, s.AddAlarm("event", SystemCounter, EventAction(waiter.ID, 0b10)))
+	expired := 0
+	assert.NoError(t, s.AddAlarm("stop", SystemCounter, CallbackAction(func() {
+		switch expired++; expired {
+		case 5:
+			s.CancelAlarm("activate")
+		case 6:
+			s.CancelAlarm("event")
+			s.CancelAlarm("stop")
+		}
+	})))
+	assert.NoError(t, s.SetRelAlarm("activate", 1, 1))
+	assert.NoError(t, s.SetRelAlarm("stop", 1, 1))
+	assert.NoError(t, s.SetAbsAlarm("event", 3, 3))
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	// The alarms keep the scheduler running until the callback cancels them.
+	activations := make([]time.Time, 0)
+	releases := make([]time.Time, 0)
+	runs := 0
+	for _, e := range dumps(s) {
+		if e.TaskID == waiter.ID && e.From == task.Waiting {
+			releases = append(releases, e.Time)
+		}
+		if e.TaskID == periodic.ID && e.From == task.Suspended && e.To == task.Suspended {
+			activations = append(activations, e.Time)
+		}
+		if e.TaskID == periodic.ID && e.From == task.Running && e.To == task.Suspended {
+			runs++
+		}
+	}
+	tick := DefaultTickDuration
+	assert.Equal(t, []time.Time{start.Add(tick), start.Add(2 * tick), start.Add(3 * tick), start.Add(4 * tick), start.Add(5 * tick)}, activations)
+	assert.Equal(t, 6, runs)
+	assert.Equal(t, 6, expired)
+	assert.Equal(t, []time.Time{start.Add(3 * tick), start.Add(6 * tick)}, releases)
+	assert.Equal(t, task.Suspended, waiter.GetState())
+}
+
+func TestScheduler_AlarmSkipsMissedCycles(t *testing.T) {
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})))
+	assert.NoError(t, err)
+	expired := 0
+	assert.NoError(t, s.AddAlarm("a", SystemCounter, CallbackAction(func() { expired++ })))
+	assert.NoError(t, s.SetRelAlarm("a", 1, 2))
+
+	// The alarms are looked at four ticks late.
+	c := s.counters[SystemCounter]
+	c.start = c.start.Add(-5 * DefaultTickDuration)
+	s.expireAlarms()
+	assert.Equal(t, 1, expired)
+	left, err := s.GetAlarm("a")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), left)
+}
+
+func TestScheduler_ActivateActionDoesNotBlock(t *testing.T) {
+	s, err := New(WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	runs := make(chan struct{}, 2)
+	release := make(chan struct{})
+	waiter, err := task.NewWithFunc(task.Extended, task.P1, func(ctx task.Context) error {
+		runs <- struct{}{}
+		// The body takes its time to stop once cancelled.
+		defer func() { <-release }()
+		_, err := ctx.WaitEvent(0b10)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(waiter))
+
+	s.Run(context.Background())
+	<-runs
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.CancelTask(waiter.ID))
+
+	assert.NoError(t, ActivateAction(waiter.ID)(s))
+	assert.Equal(t, task.TaskState(""), queuedState(s, waiter.ID))
+	close(release)
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("activated task did not run again")
+	}
+	assert.NoError(t, s.SetEvent(waiter.ID, 0b10))
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
@@ -0,0 +1,235 @@
+package scheduler
+
+import (
+	"io"
+	"log/slog"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"time"
+)
+
+const (
+	DefaultTickDuration   time.Duration = 100 * time.Millisecond
+	DefaultMaxReadyTasks  int           = 5
+	DefaultReadyLimit     int           = DefaultMaxReadyTasks - 2
+	DefaultIdleTicks      int           = 10
+	DefaultPriorityLevels int           = task.P3 + 1
+	DefaultCores          int           = 1
+	DefaultDumpBuffer     int           = 256
+	DefaultRingSize       int           = 1024
+)
+
+type Config struct {
+	// MaxReadyTasks is the capacity of all ready queues together. Tasks
+	// released from waiting while the ready queues are full stay waiting
+	// until there is room. Preempted tasks go back to ready at once and may
+	// exceed it until the next dispatch.
+	MaxReadyTasks int
+	// ReadyLimit is the number of ready tasks below which suspended tasks
+	// are admitted. It leaves room for preempted and released tasks.
+	ReadyLimit     int
+	PriorityLevels int
+	TickDuration   time.Duration
+	// IdleTicks is the number of idle ticks after which the scheduler stops
+	// on its own. Zero means it runs until cancelled.
+	IdleTicks int
+	Policy    Policy
+	// Cores is the number of tasks that run at the same time.
+	Cores int
+	// Partitioned gives every core its own ready queues. Tasks without
+	// affinity are assigned to cores round-robin when added.
+	Partitioned bool
+	// Journal receives every task transition as a JSON line right after it
+	// happens, so Recover can rebuild the queues after a crash. Nil disables
+	// journaling.
+	Journal io.Writer
+	// DumpSink receives the events of all transitions. NopSink disables
+	// tracing.
+	DumpSink DumpSink
+	// DumpBuffer is the number of events waiting for the sink.
+	DumpBuffer int
+	// DumpOverflow tells what happens to an event that does not fit in the
+	// dump buffer. By default the transition waits for the sink, so traces
+	// are complete. Runs on a virtual clock always wait, so they repeat
+	// exactly.
+	DumpOverflow DumpOverflow
+	// Clock drives work units, time slices, idle ticks and event times. A
+	// virtual clock makes runs on a single core repeat exactly; it skips
+	// idle ticks at once, so it needs IdleTicks to stop.
+	Clock clock.Clock
+	// Logger receives the messages of the scheduler and of the tasks added
+	// without a logger of their own. The default discards them.
+	Logger *slog.Logger
+}
+
+// DumpOverflow is what the scheduler does with an event that does not fit in
+// the dump buffer.
+type DumpOverflow int
+
+const (
+	// DumpBlock waits for room in the dump buffer, so the sink gets every
+	// event and a slow sink slows down the scheduler.
+	DumpBlock DumpOverflow = iota
+	// DumpDrop drops the event, counts it in DroppedDumps and logs a warning.
+	DumpDrop
+)
+
+type Option func(*Config)
+
+func DefaultConfig() Config {
+	return Config{
+		MaxReadyTasks:  DefaultMaxReadyTasks,
+		ReadyLimit:     DefaultReadyLimit,
+		PriorityLevels: DefaultPriorityLevels,
+		TickDuration:   DefaultTickDuration,
+		IdleTicks:      DefaultIdleTicks,
+		Policy:         FixedPriority{},
+		Cores:          DefaultCores,
+		DumpSink:       NewRingSink(DefaultRingSize),
+		DumpBuffer:     DefaultDumpBuffer,
+		Clock:          clock.Real{},
+		Logger:         slog.New(slog.DiscardHandler),
+	}
+}
+
+func WithMaxReadyTasks(n int) Option {
+	return func(c *Config) {
+		c.MaxReadyTasks = n
+	}
+}
+
+func WithReadyLimit(n int) Option {
+	return func(c *Config) {
+		c.ReadyLimit = n
+	}
+}
+
+func WithPriorityLevels(n int) Option {
+	return func(c *Config) {
+		c.PriorityLevels = n
+	}
+}
+
+func WithTickDuration(d time.Duration) Option {
+	return func(c *Config) {
+		c.TickDuration = d
+	}
+}
+
+func WithIdleExit(ticks int) Option {
+	return func(c *Config) {
+		c.IdleTicks = ticks
+	}
+}
+
+func WithRunForever() Option {
+	return func(c *Config) {
+		c.IdleTicks = 0
+	}
+}
+
+func WithPolicy(p Policy) Option {
+	return func(c *Config) {
+		c.Policy = p
+	}
+}
+
+func WithCores(n int) Option {
+	return func(c *Config) {
+		c.Cores = n
+	}
+}
+
+func WithPartitionedQueues() Option {
+	return func(c *Config) {
+		c.Partitioned = true
+	}
+}
+
+func WithJournal(w io.Writer) Option {
+	return func(c *Config) {
+		c.Journal = w
+	}
+}
+
+func WithDumpSink(sink DumpSink) Option {
+	return func(c *Config) {
+		c.DumpSink = sink
+	}
+}
+
+func WithDumpBuffer(n int) Option {
+	return func(c *Config) {
+		c.DumpBuffer = n
+	}
+}
+
+func WithDumpOverflow(o DumpOverflow) Option {
+	return func(c *Config) {
+		c.DumpOverflow = o
+	}
+}
+
+func WithClock(clk clock.Clock) Option {
+	return func(c *Config) {
+		c.Clock = clk
+	}
+}
+
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+func (c Config) Validate() error {
+	if c.MaxReadyTasks <= 0 {
+		return ErrInvalidMaxReadyTasks
+	}
+	if c.ReadyLimit <= 0 || c.ReadyLimit > c.MaxReadyTasks {
+		return ErrInvalidReadyLimit
+	}
+	if c.PriorityLevels <= 0 || c.PriorityLevels > task.MaxPriorityLevels {
+		return ErrInvalidPriorityLevels
+	}
+	if c.TickDuration <= 0 {
+		return ErrInvalidTickDuration
+	}
+	if c.IdleTicks < 0 {
+		return ErrInvalidIdleTicks
+	}
+	if c.Policy == nil {
+		return ErrInvalidPolicy
+	}
+	if c.Cores <= 0 {
+		return ErrInvalidCores
+	}
+	if c.DumpSink == nil {
+		return ErrInvalidDumpSink
+	}
+	if c.DumpBuffer <= 0 {
+		return ErrInvalidDumpBuffer
+	}
+	if c.DumpOverflow != DumpBlock && c.DumpOverflow != DumpDrop {
+		return ErrInvalidDumpOverflow
+	}
+	if c.Clock == nil {
+		return ErrInvalidClock
+	}
+	if c.Logger == nil {
+		return ErrInvalidLogger
+	}
+	return nil
+}
+
+// CheckTask tells whether a task of priority and affinity fits c. Servers
+// call it before task.New, so a rejected task takes no ID.
+func (c Config) CheckTask(priority task.TaskPriority, affinity int) error {
+	if int(priority) >= c.PriorityLevels {
+		return ErrInvalidPriority
+	}
+	if affinity >= c.Cores {
+		return ErrInvalidAffinity
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected error
+	}{
+		{
+			name:     "Defaults",
+			opts:     nil,
+			expected: nil,
+		},
+		{
+			name:     "Custom valid config",
+			opts:     []Option{WithMaxReadyTasks(10), WithReadyLimit(8), WithPriorityLevels(2), WithTickDuration(time.Millisecond), WithRunForever()},
+			expected: nil,
+		},
+		{
+			name:     "Zero max ready tasks",
+			opts:     []Option{WithMaxReadyTasks(0)},
+			expected: ErrInvalidMaxReadyTasks,
+		},
+		{
+			name:     "Ready limit above max ready tasks",
+			opts:     []Option{WithReadyLimit(DefaultMaxReadyTasks + 1)},
+			expected: ErrInvalidReadyLimit,
+		},
+		{
+			name:     "Zero ready limit",
+			opts:     []Option{WithReadyLimit(0)},
+			expected: ErrInvalidReadyLimit,
+		},
+		{
+			name:     "Zero priority levels",
+			opts:     []Option{WithPriorityLevels(0)},
+			expected: ErrInvalidPriorityLevels,
+		},
+		{
+			name:     "Too many priority levels",
+			opts:     []Option{WithPriorityLevels(task.MaxPriorityLevels + 1)},
+			expected: ErrInvalidPriorityLevels,
+		},
+		{
+			name:     "Negative tick duration",
+			opts:     []Option{WithTickDuration(-time.Second)},
+			expected: ErrInvalidTickDuration,
+		},
+		{
+			name:     "Nil dump sink",
+			opts:     []Option{WithDumpSink(nil)},
+			expected: ErrInvalidDumpSink,
+		},
+		{
+			name:     "Zero dump buffer",
+			opts:     []Option{WithDumpBuffer(0)},
+			expected: ErrInvalidDumpBuffer,
+		},
+		{
+			name:     "Unknown dump overflow",
+			opts:     []Option{WithDumpOverflow(DumpDrop + 1)},
+			expected: ErrInvalidDumpOverflow,
+		},
+		{
+			name:     "Zero cores",
+			opts:     []Option{WithCores(0)},
+			expected: ErrInvalidCores,
+		},
+		{
+			name:     "Negative idle ticks",
+			opts:     []Option{WithIdleExit(-1)},
+			expected: ErrInvalidIdleTicks,
+		},
+		{
+			name:     "Nil policy",
+			opts:     []Option{WithPolicy(nil)},
+			expected: ErrInvalidPolicy,
+		},
+		{
+			name:     "Nil clock",
+			opts:     []Option{WithClock(nil)},
+			expected: ErrInvalidClock,
+		},
+		{
+			name:     "Nil logger",
+			opts:     []Option{WithLogger(nil)},
+			expected: ErrInvalidLogger,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.opts...)
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+				assert.Nil(t, s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, s)
+		})
+	}
+}
+
+func TestConfig_CheckTask(t *testing.T) {
+	cfg := DefaultConfig()
+	WithPriorityLevels(2)(&cfg)
+	assert.NoError(t, cfg.CheckTask(task.P1, task.AnyCore))
+	assert.NoError(t, cfg.CheckTask(task.P0, 0))
+	assert.ErrorIs(t, cfg.CheckTask(task.P2, task.AnyCore), ErrInvalidPriority)
+	assert.ErrorIs(t, cfg.CheckTask(task.P0, 1), ErrInvalidAffinity)
+}
+
+func TestNew_AppliesOptions(t *testing.T) {
+	s, err := New(WithPriorityLevels(2), WithReadyLimit(1), WithIdleExit(3))
+	assert.NoError(t, err)
+
+	cfg := s.Config()
+	assert.Equal(t, 2, cfg.PriorityLevels)
+	assert.Equal(t, 1, cfg.ReadyLimit)
+	assert.Equal(t, 3, cfg.IdleTicks)
+	assert.Len(t, s.readyQueues, 1)
+	assert.Len(t, s.readyQueues[0].queues, 2)
+	assert.Len(t, s.waitingQueues.queues, 2)
+	assert.Len(t, s.cores, DefaultCores)
+
+	tsk, err := task.New(task.Basic, task.P2, task.Suspended)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.AddNewTask(tsk), ErrInvalidPriority)
+
+	s, err = New(WithCores(3), WithPartitionedQueues())
+	assert.NoError(t, err)
+	assert.Len(t, s.cores, 3)
+	assert.Len(t, s.readyQueues, 3)
+
+	tsk, err = task.New(task.Basic, task.P0, task.Suspended, task.WithAffinity(3))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.AddNewTask(tsk), ErrInvalidAffinity)
+}
+
+func TestScheduler_RunForever(t *testing.T) {
+	s, err := New(WithTickDuration(time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	s.Run(context.Background())
+	time.Sleep(time.Millisecond * 50)
+
+	select {
+	case <-s.StopChan:
+		t.Fatal("scheduler stopped on its own while configured to run forever")
+	default:
+	}
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
+
+func TestScheduler_IdleExit(t *testing.T) {
+	s, err := New(WithTickDuration(time.Millisecond), WithIdleExit(5))
+	assert.NoError(t, err)
+
+	s.Run(context.Background())
+	select {
+	case <-s.StopChan:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after idle ticks")
+	}
+}
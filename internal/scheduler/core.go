@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"scheduler/internal/task"
+	"sync"
+)
+
+// core is an execution slot running at most one task at a time.
+type core struct {
+	id            int
+	mu            sync.Mutex
+	current       *task.Task
+	interruptChan chan struct{}
+	idle          int
+	idleTotal     uint64
+}
+
+func newCore(id int) *core {
+	return &core{id: id, interruptChan: make(chan struct{}, 1)}
+}
+
+func (c *core) running() *task.Task {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+func (c *core) setCurrent(t *task.Task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = t
+	if t != nil {
+		c.idle = 0
+	}
+}
+
+func (c *core) canRun(t *task.Task) bool {
+	a := t.GetAffinity()
+	return a == task.AnyCore || a == c.id
+}
+
+// tick counts an idle tick and returns the number of idle ticks in a row.
+func (c *core) tick() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.idle++
+	c.idleTotal++
+	return c.idle
+}
+
+func (c *core) idleTicks() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idle
+}
+
+// totalIdleTicks counts all idle ticks, not only those in a row.
+func (c *core) totalIdleTicks() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idleTotal
+}
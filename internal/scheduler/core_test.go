@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"scheduler/internal/validate"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSpinTask returns a task that keeps the core until stop is closed.
+func newSpinTask(t *testing.T, priority task.TaskPriority, stop chan struct{}, opts ...task.Option) *task.Task {
+	tsk, err := task.NewWithFunc(task.Basic, priority, func(ctx task.Context) error {
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-ctx.Done():
+				ctx.Yield()
+			}
+		}
+	}, opts...)
+	assert.NoError(t, err)
+	return tsk
+}
+
+// coresOf returns the cores every task was seen running on.
+func coresOf(dumps []trace.Event) map[int]map[int]bool {
+	res := make(map[int]map[int]bool)
+	for _, dump := range dumps {
+		if dump.To != task.Running {
+			continue
+		}
+		if res[dump.TaskID] == nil {
+			res[dump.TaskID] = make(map[int]bool)
+		}
+		res[dump.TaskID][dump.Core] = true
+	}
+	return res
+}
+
+func maxRunning(dumps []trace.Event) int {
+	res := 0
+	for _, dump := range dumps {
+		n := 0
+		for _, t := range dump.Queues.Running {
+			if t != nil {
+				n++
+			}
+		}
+		res = max(res, n)
+	}
+	return res
+}
+
+func TestScheduler_MultiCore(t *testing.T) {
+	s, err := New(WithCores(2), WithTickDuration(10*time.Millisecond), withQueueDumps())
+	assert.NoError(t, err)
+
+	tasks := make([]*task.Task, 0, 4)
+	for i := 0; i < 4; i++ {
+		tsk := newPolicyTask(t, task.P1)
+		s.AddNewTask(tsk)
+		tasks = append(tasks, tsk)
+	}
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	assert.Equal(t, 2, maxRunning(dumps(s)))
+	assert.Empty(t, validate.Trace(dumps(s), validate.Default(DefaultMaxReadyTasks)...))
+	for _, v := range tasks {
+		assert.Equal(t, task.Suspended, v.GetState())
+	}
+}
+
+func TestScheduler_Affinity(t *testing.T) {
+	s, err := New(WithCores(2), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	pinned := make([]*task.Task, 0, 3)
+	for i := 0; i < 3; i++ {
+		tsk := newPolicyTask(t, task.P1, task.WithAffinity(1))
+		s.AddNewTask(tsk)
+		pinned = append(pinned, tsk)
+	}
+	free := newPolicyTask(t, task.P0)
+	s.AddNewTask(free)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	cores := coresOf(dumps(s))
+	for _, v := range pinned {
+		assert.Equal(t, map[int]bool{1: true}, cores[v.ID])
+	}
+	// Core 0 is idle while the pinned tasks run, so it takes the free task.
+	assert.Equal(t, map[int]bool{0: true}, cores[free.ID])
+}
+
+func TestScheduler_PartitionedQueues(t *testing.T) {
+	s, err := New(WithCores(2), WithPartitionedQueues(), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	tasks := make([]*task.Task, 0, 4)
+	for i := 0; i < 4; i++ {
+		tsk := newPolicyTask(t, task.P1)
+		s.AddNewTask(tsk)
+		tasks = append(tasks, tsk)
+	}
+	pinned := newPolicyTask(t, task.P1, task.WithAffinity(1))
+	s.AddNewTask(pinned)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	cores := coresOf(dumps(s))
+	for i, v := range tasks {
+		assert.Equal(t, map[int]bool{i % 2: true}, cores[v.ID])
+	}
+	assert.Equal(t, map[int]bool{1: true}, cores[pinned.ID])
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
+
+func TestScheduler_PreemptsLowestPriorityCore(t *testing.T) {
+	s, err := New(WithCores(2), WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	stop := make(chan struct{})
+	low := newSpinTask(t, task.P1, stop)
+	mid := newSpinTask(t, task.P2, stop)
+	s.AddNewTask(low)
+	s.AddNewTask(mid)
+
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		return queuedState(s, low.ID) == task.Running && queuedState(s, mid.ID) == task.Running
+	}, time.Second, 10*time.Millisecond)
+
+	high := newPolicyTask(t, task.P3)
+	s.AddNewTask(high)
+	assert.Eventually(t, func() bool {
+		return queuedState(s, high.ID) == "" && queuedState(s, low.ID) == task.Running
+	}, time.Second, 10*time.Millisecond)
+	close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Eventually(t, func() bool {
+		return queuedState(s, low.ID) == "" && queuedState(s, mid.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(ctx))
+
+	preempted := make([]int, 0)
+	for _, dump := range dumps(s) {
+		if dump.From == task.Running && dump.To == task.Ready {
+			preempted = append(preempted, dump.TaskID)
+		}
+	}
+	assert.Equal(t, []int{low.ID}, preempted)
+}
@@ -0,0 +1,234 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"scheduler/internal/trace"
+	"sync"
+)
+
+// DumpSink receives scheduler events. The scheduler calls Dump from a single
+// goroutine, in event order. Dump must not call back into the scheduler.
+// Sinks that implement io.Closer are closed once the scheduler has stopped and
+// all events are delivered.
+type DumpSink interface {
+	Dump(e trace.Event)
+}
+
+// QueueSink is a DumpSink that wants a copy of all queues with every event,
+// as the queue checks of the validate package do. The copy is taken under the
+// queue locks and grows with the number of tasks, so other sinks get events
+// without it.
+type QueueSink interface {
+	DumpSink
+	DumpQueues() bool
+}
+
+type queueSink struct {
+	DumpSink
+}
+
+// IncludeQueues makes sink get a copy of all queues with every event.
+func IncludeQueues(sink DumpSink) DumpSink {
+	return queueSink{sink}
+}
+
+func (queueSink) DumpQueues() bool {
+	return true
+}
+
+func (q queueSink) Close() error {
+	if c, ok := q.DumpSink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func wantsQueues(sink DumpSink) bool {
+	q, ok := sink.(QueueSink)
+	return ok && q.DumpQueues()
+}
+
+// NopSink discards events. The scheduler does not even build them.
+type NopSink struct{}
+
+func (NopSink) Dump(trace.Event) {}
+
+// RingSink keeps the last events.
+type RingSink struct {
+	mu     sync.Mutex
+	events []trace.Event
+	next   int
+	full   bool
+}
+
+func NewRingSink(size int) *RingSink {
+	return &RingSink{events: make([]trace.Event, size)}
+}
+
+func (r *RingSink) Dump(e trace.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return
+	}
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Events returns the kept events, oldest first.
+func (r *RingSink) Events() []trace.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]trace.Event(nil), r.events[:r.next]...)
+	}
+	return append(append([]trace.Event(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
+
+// ChanSink passes events to a subscriber. Events the subscriber is not ready
+// for when its buffer is full are dropped, so a slow subscriber never stalls
+// the scheduler.
+type ChanSink struct {
+	ch chan trace.Event
+}
+
+func NewChanSink(buffer int) *ChanSink {
+	return &ChanSink{ch: make(chan trace.Event, buffer)}
+}
+
+// C returns the channel with the events. It is closed after the last event.
+func (c *ChanSink) C() <-chan trace.Event {
+	return c.ch
+}
+
+func (c *ChanSink) Dump(e trace.Event) {
+	select {
+	case c.ch <- e:
+	default:
+	}
+}
+
+func (c *ChanSink) Close() error {
+	close(c.ch)
+	return nil
+}
+
+// WriterSink writes events to w as JSON Lines.
+type WriterSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+func (w *WriterSink) Dump(e trace.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = w.enc.Encode(e)
+	}
+}
+
+// Err returns the first write error. Events after it are not written.
+func (w *WriterSink) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// BroadcastSink passes events to any number of subscribers. Like ChanSink, it
+// drops events for subscribers whose buffer is full.
+type BroadcastSink struct {
+	mu     sync.Mutex
+	subs   map[chan trace.Event]struct{}
+	closed bool
+}
+
+func NewBroadcastSink() *BroadcastSink {
+	return &BroadcastSink{subs: make(map[chan trace.Event]struct{})}
+}
+
+// Subscribe returns a channel with the events from now on and a function
+// that cancels the subscription. The channel is closed on cancel or once the
+// scheduler has stopped.
+func (b *BroadcastSink) Subscribe(buffer int) (<-chan trace.Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan trace.Event, buffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *BroadcastSink) Dump(e trace.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (b *BroadcastSink) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		close(ch)
+	}
+	clear(b.subs)
+	b.closed = true
+	return nil
+}
+
+type multiSink []DumpSink
+
+// MultiSink passes every event to all sinks in turn. If one of them wants
+// the queues, all of them get the queues.
+func MultiSink(sinks ...DumpSink) DumpSink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Dump(e trace.Event) {
+	for _, sink := range m {
+		sink.Dump(e)
+	}
+}
+
+func (m multiSink) DumpQueues() bool {
+	for _, sink := range m {
+		if wantsQueues(sink) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiSink) Close() error {
+	var errs []error
+	for _, sink := range m {
+		if c, ok := sink.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
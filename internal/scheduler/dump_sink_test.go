@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingSink(t *testing.T) {
+	r := NewRingSink(3)
+	assert.Empty(t, r.Events())
+	for i := 1; i <= 2; i++ {
+		r.Dump(trace.Event{Seq: uint64(i)})
+	}
+	assert.Equal(t, []trace.Event{{Seq: 1}, {Seq: 2}}, r.Events())
+	for i := 3; i <= 5; i++ {
+		r.Dump(trace.Event{Seq: uint64(i)})
+	}
+	assert.Equal(t, []trace.Event{{Seq: 3}, {Seq: 4}, {Seq: 5}}, r.Events())
+
+	empty := NewRingSink(0)
+	empty.Dump(trace.Event{Seq: 1})
+	assert.Empty(t, empty.Events())
+}
+
+func TestChanSink(t *testing.T) {
+	c := NewChanSink(1)
+	c.Dump(trace.Event{Seq: 1})
+	c.Dump(trace.Event{Seq: 2})
+	assert.NoError(t, c.Close())
+
+	events := make([]trace.Event, 0)
+	for e := range c.C() {
+		events = append(events, e)
+	}
+	assert.Equal(t, []trace.Event{{Seq: 1}}, events)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterSink(&buf)
+	w.Dump(trace.Event{Seq: 1, To: task.Suspended})
+	w.Dump(trace.Event{Seq: 2, To: task.Ready})
+	assert.NoError(t, w.Err())
+
+	dec := json.NewDecoder(&buf)
+	for _, seq := range []uint64{1, 2} {
+		var e trace.Event
+		assert.NoError(t, dec.Decode(&e))
+		assert.Equal(t, seq, e.Seq)
+	}
+
+	failing := NewWriterSink(failingWriter{})
+	failing.Dump(trace.Event{Seq: 1})
+	assert.EqualError(t, failing.Err(), "disk full")
+}
+
+func TestScheduler_ChanSink(t *testing.T) {
+	sink := NewChanSink(DefaultDumpBuffer)
+	s, err := New(WithDumpSink(sink), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	tsk := newPolicyTask(t, task.P1)
+	s.AddNewTask(tsk)
+	s.Run(context.Background())
+	<-s.StopChan
+
+	transitions := make([]string, 0)
+	var seq uint64
+	for e := range sink.C() {
+		assert.Greater(t, e.Seq, seq)
+		seq = e.Seq
+		transitions = append(transitions, e.String())
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("task -> suspended | ID=%d", tsk.ID),
+		fmt.Sprintf("task suspended -> ready | ID=%d", tsk.ID),
+		fmt.Sprintf("task ready -> running | ID=%d", tsk.ID),
+		fmt.Sprintf("task running -> suspended | ID=%d", tsk.ID),
+	}, transitions)
+	assert.Zero(t, s.DroppedDumps())
+}
+
+func TestScheduler_NopSink(t *testing.T) {
+	s, err := New(WithDumpSink(NopSink{}), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	s.AddNewTask(newPolicyTask(t, task.P1))
+	s.Run(context.Background())
+	<-s.StopChan
+
+	assert.Zero(t, s.seq)
+	assert.Zero(t, s.DroppedDumps())
+}
+
+// gateSink holds back events until gate is closed. It tells on got when it
+// takes an event.
+type gateSink struct {
+	*RingSink
+	gate chan struct{}
+	got  chan struct{}
+}
+
+func (g *gateSink) Dump(e trace.Event) {
+	g.got <- struct{}{}
+	<-g.gate
+	g.RingSink.Dump(e)
+}
+
+func TestScheduler_DropsDumpsWhenBufferIsFull(t *testing.T) {
+	sink := &gateSink{RingSink: NewRingSink(DefaultRingSize), gate: make(chan struct{}), got: make(chan struct{}, DefaultRingSize)}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	s, err := New(WithDumpSink(sink), WithDumpBuffer(1), WithDumpOverflow(DumpDrop), WithLogger(logger), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	// The sink holds the first event and the buffer the second, so the
+	// third is dropped.
+	s.AddNewTask(newPolicyTask(t, task.P1))
+	<-sink.got
+	s.AddNewTask(newPolicyTask(t, task.P1))
+	s.AddNewTask(newPolicyTask(t, task.P1))
+	assert.Equal(t, uint64(1), s.DroppedDumps())
+	assert.Contains(t, buf.String(), "msg=\"dump dropped\" seq=3")
+
+	close(sink.gate)
+	s.Run(context.Background())
+	<-s.StopChan
+	assert.Equal(t, []uint64{1, 2}, []uint64{sink.Events()[0].Seq, sink.Events()[1].Seq})
+
+	// Events after the scheduler stopped are dropped too.
+	dropped := s.DroppedDumps()
+	s.AddNewTask(newPolicyTask(t, task.P1))
+	assert.Equal(t, dropped+1, s.DroppedDumps())
+}
+
+func TestScheduler_BlocksDumpsWhenBufferIsFull(t *testing.T) {
+	sink := NewRingSink(DefaultRingSize)
+	s, err := New(WithDumpSink(sink), WithDumpBuffer(1), WithTickDuration(time.Millisecond))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		s.AddNewTask(newPolicyTask(t, task.P1))
+	}
+	s.Run(context.Background())
+	<-s.StopChan
+
+	events := sink.Events()
+	assert.Greater(t, len(events), 30)
+	for i, e := range events {
+		assert.Equal(t, uint64(i+1), e.Seq)
+	}
+	assert.Zero(t, s.DroppedDumps())
+}
+
+func TestScheduler_VirtualClockKeepsDumps(t *testing.T) {
+	sink := NewRingSink(DefaultRingSize)
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})), WithDumpSink(sink), WithDumpBuffer(1), WithDumpOverflow(DumpDrop))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		s.AddNewTask(newPolicyTask(t, task.P1))
+	}
+	s.Run(context.Background())
+	<-s.StopChan
+
+	for i, e := range sink.Events() {
+		assert.Equal(t, uint64(i+1), e.Seq)
+	}
+	assert.Zero(t, s.DroppedDumps())
+}
+
+func TestIncludeQueues(t *testing.T) {
+	ring := NewRingSink(DefaultRingSize)
+	s, err := New(WithDumpSink(MultiSink(IncludeQueues(ring), NopSink{})))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(newPolicyTask(t, task.P1)))
+	s, err = New()
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(newPolicyTask(t, task.P1)))
+
+	assert.Eventually(t, func() bool { return len(ring.Events()) == 1 && len(dumps(s)) == 1 }, time.Second, time.Millisecond)
+	if q := ring.Events()[0].Queues; assert.NotNil(t, q) && assert.Len(t, q.Suspended, 1) {
+		assert.Equal(t, ring.Events()[0].TaskID, q.Suspended[0].ID)
+	}
+	assert.Nil(t, dumps(s)[0].Queues)
+}
+
+func TestBroadcastSink(t *testing.T) {
+	b := NewBroadcastSink()
+	first, cancelFirst := b.Subscribe(1)
+	second, _ := b.Subscribe(2)
+
+	b.Dump(trace.Event{Seq: 1})
+	cancelFirst()
+	cancelFirst()
+	b.Dump(trace.Event{Seq: 2})
+	b.Dump(trace.Event{Seq: 3})
+	assert.NoError(t, b.Close())
+
+	collect := func(ch <-chan trace.Event) []uint64 {
+		res := make([]uint64, 0)
+		for e := range ch {
+			res = append(res, e.Seq)
+		}
+		return res
+	}
+	assert.Equal(t, []uint64{1}, collect(first))
+	assert.Equal(t, []uint64{1, 2}, collect(second))
+
+	late, _ := b.Subscribe(1)
+	assert.Empty(t, collect(late))
+}
+
+func TestMultiSink(t *testing.T) {
+	ring := NewRingSink(2)
+	ch := NewChanSink(2)
+	sink := MultiSink(ring, ch, NopSink{})
+	sink.Dump(trace.Event{Seq: 1})
+	assert.NoError(t, sink.(io.Closer).Close())
+
+	assert.Equal(t, []trace.Event{{Seq: 1}}, ring.Events())
+	assert.Equal(t, trace.Event{Seq: 1}, <-ch.C())
+	_, ok := <-ch.C()
+	assert.False(t, ok)
+}
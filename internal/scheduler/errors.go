@@ -5,4 +5,38 @@ import "errors"
 var (
 	ErrNoTasksLeft     = errors.New("no tasks")
 	ErrNoSpaceForReady = errors.New("no space for new ready task")
+	ErrTasksLeft       = errors.New("tasks left in queues")
+	ErrInvalidPriority = errors.New("task priority exceeds scheduler priority levels")
+	ErrUnknownTask     = errors.New("no task with such ID")
+	ErrTaskSuspended   = errors.New("task is suspended")
+	ErrTaskNotQueued   = errors.New("task is running or done")
+	ErrInvalidAffinity = errors.New("task affinity exceeds scheduler cores")
+
+	ErrInvalidCounter     = errors.New("counter tick and max value must be positive")
+	ErrCounterExists      = errors.New("counter already exists")
+	ErrUnknownCounter     = errors.New("no counter with such name")
+	ErrInvalidAlarmAction = errors.New("alarm action must be set")
+	ErrAlarmExists        = errors.New("alarm already exists")
+	ErrUnknownAlarm       = errors.New("no alarm with such name")
+	ErrAlarmInUse         = errors.New("alarm is already set")
+	ErrAlarmNotSet        = errors.New("alarm is not set")
+	ErrInvalidAlarmValue  = errors.New("alarm ticks exceed the counter range")
+
+	ErrResourceAccess  = errors.New("task did not declare the resource")
+	ErrResourceHeld    = errors.New("resource is already held")
+	ErrResourceNotHeld = errors.New("task does not hold the resource")
+	ErrReleaseOrder    = errors.New("resources must be released in the reverse order they were taken")
+
+	ErrInvalidMaxReadyTasks  = errors.New("max ready tasks must be positive")
+	ErrInvalidReadyLimit     = errors.New("ready limit must be positive and not exceed max ready tasks")
+	ErrInvalidPriorityLevels = errors.New("invalid number of priority levels")
+	ErrInvalidTickDuration   = errors.New("tick duration must be positive")
+	ErrInvalidIdleTicks      = errors.New("idle ticks must not be negative")
+	ErrInvalidPolicy         = errors.New("scheduling policy must be set")
+	ErrInvalidCores          = errors.New("number of cores must be positive")
+	ErrInvalidDumpSink       = errors.New("dump sink must be set")
+	ErrInvalidDumpBuffer     = errors.New("dump buffer must be positive")
+	ErrInvalidDumpOverflow   = errors.New("dump overflow must be DumpBlock or DumpDrop")
+	ErrInvalidClock          = errors.New("clock must be set")
+	ErrInvalidLogger         = errors.New("logger must be set")
 )
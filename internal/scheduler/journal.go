@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"scheduler/internal/task"
+	"sync"
+)
+
+// journalRecord is a task transition. It carries the whole task, so replaying
+// a record moves the task to its destination wherever it was before. Update
+// records change a task without a transition; activation records put a done
+// task back to the suspended queue.
+type journalRecord struct {
+	Seq       uint64         `json:"seq"`
+	Update    bool           `json:"update,omitempty"`
+	Activate  bool           `json:"activate,omitempty"`
+	From      task.TaskState `json:"from,omitempty"`
+	To        task.TaskState `json:"to"`
+	Front     bool           `json:"front,omitempty"`
+	Partition int            `json:"partition,omitempty"`
+	Task      task.Snapshot  `json:"task"`
+}
+
+type journal struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	seq    uint64
+	logger *slog.Logger
+}
+
+func newJournal(w io.Writer, logger *slog.Logger) *journal {
+	if w == nil {
+		return nil
+	}
+	return &journal{enc: json.NewEncoder(w), logger: logger}
+}
+
+func (j *journal) write(rec journalRecord) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	rec.Seq = j.seq
+	if err := j.enc.Encode(rec); err != nil {
+		j.logger.Error("journal write failed", "seq", rec.Seq, "error", err)
+	}
+}
+
+func (j *journal) last() uint64 {
+	if j == nil {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+func (j *journal) setLast(seq uint64) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq = seq
+}
+
+// record journals a transition of t. It is called right after the transition,
+// holding the lock of the destination queue, so records of one queue are in
+// queue order. The journal is not write-ahead: a crash between a transition
+// and its record loses the transition.
+func (s *Scheduler) record(from, to task.TaskState, t *task.Task, front bool) {
+	if s.journal == nil {
+		return
+	}
+	s.journal.write(journalRecord{From: from, To: to, Front: front, Partition: s.partitionOf(t), Task: t.Snapshot()})
+}
+
+func (s *Scheduler) partitionOf(t *task.Task) int {
+	s.tMu.Lock()
+	defer s.tMu.Unlock()
+	return s.partitions[t.ID]
+}
@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"scheduler/internal/task"
+	"time"
+)
+
+type QueuePosition int
+
+const (
+	Front QueuePosition = iota
+	Back
+)
+
+type PreemptReason int
+
+const (
+	// PreemptedByTask means another task became ready and won the processor.
+	PreemptedByTask PreemptReason = iota
+	// SliceExpired means the running task used up its time slice.
+	SliceExpired
+)
+
+// Policy decides which ready task runs and when the running task is preempted.
+type Policy interface {
+	// Next returns the ready task to run next or nil to stay idle.
+	Next(ready *PriorityQueues) *task.Task
+	// Preempts reports whether a task that became ready preempts the running one.
+	Preempts(running, ready *task.Task) bool
+	// Requeue returns where a preempted task goes back into its ready queue.
+	Requeue(t *task.Task, reason PreemptReason) QueuePosition
+	// TimeSlice returns how long a task runs before tasks of the same
+	// priority get their turn. Zero disables time slicing.
+	TimeSlice() time.Duration
+}
+
+// FixedPriority runs the highest-priority task first, FIFO within a level,
+// and preempts the running task as soon as a higher-priority one is ready.
+type FixedPriority struct{}
+
+func (FixedPriority) Next(ready *PriorityQueues) *task.Task {
+	return ready.Highest()
+}
+
+func (FixedPriority) Preempts(running, ready *task.Task) bool {
+	return running.GetPriority() < ready.GetPriority()
+}
+
+func (FixedPriority) Requeue(t *task.Task, reason PreemptReason) QueuePosition {
+	return Front
+}
+
+func (FixedPriority) TimeSlice() time.Duration {
+	return 0
+}
+
+// NonPreemptive selects tasks like FixedPriority but lets the running task
+// keep the processor until it finishes or waits.
+type NonPreemptive struct{}
+
+func (NonPreemptive) Next(ready *PriorityQueues) *task.Task {
+	return ready.Highest()
+}
+
+func (NonPreemptive) Preempts(running, ready *task.Task) bool {
+	return false
+}
+
+func (NonPreemptive) Requeue(t *task.Task, reason PreemptReason) QueuePosition {
+	return Front
+}
+
+func (NonPreemptive) TimeSlice() time.Duration {
+	return 0
+}
+
+// RoundRobin is FixedPriority with time slices: when a slice expires, the
+// running task goes to the back of its queue if another task of the same
+// priority is ready.
+type RoundRobin struct {
+	Slice time.Duration
+}
+
+func (RoundRobin) Next(ready *PriorityQueues) *task.Task {
+	return ready.Highest()
+}
+
+func (RoundRobin) Preempts(running, ready *task.Task) bool {
+	return running.GetPriority() < ready.GetPriority()
+}
+
+func (RoundRobin) Requeue(t *task.Task, reason PreemptReason) QueuePosition {
+	if reason == SliceExpired {
+		return Back
+	}
+	return Front
+}
+
+func (p RoundRobin) TimeSlice() time.Duration {
+	return p.Slice
+}
+
+// EDF runs the task with the earliest deadline. Tasks without a deadline run
+// only when no task with a deadline is ready; ties go to the higher priority.
+type EDF struct{}
+
+func (EDF) Next(ready *PriorityQueues) *task.Task {
+	var res *task.Task
+	ready.Each(func(t *task.Task) bool {
+		if res == nil || earlierDeadline(t, res) {
+			res = t
+		}
+		return true
+	})
+	return res
+}
+
+func (EDF) Preempts(running, ready *task.Task) bool {
+	return earlierDeadline(ready, running)
+}
+
+func (EDF) Requeue(t *task.Task, reason PreemptReason) QueuePosition {
+	return Front
+}
+
+func (EDF) TimeSlice() time.Duration {
+	return 0
+}
+
+func earlierDeadline(a, b *task.Task) bool {
+	da, db := a.GetDeadline(), b.GetDeadline()
+	if da.IsZero() {
+		return false
+	}
+	return db.IsZero() || da.Before(db)
+}
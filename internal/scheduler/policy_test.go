@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newReadyQueues(tasks ...*task.Task) *PriorityQueues {
+	q := NewPriorityQueues(DefaultPriorityLevels)
+	for _, v := range tasks {
+		q.PushBack(v)
+	}
+	return &q
+}
+
+func newPolicyTask(t *testing.T, priority task.TaskPriority, opts ...task.Option) *task.Task {
+	opts = append([]task.Option{task.WithSleepTime(10 * time.Millisecond)}, opts...)
+	tsk, err := task.New(task.Basic, priority, task.Suspended, opts...)
+	assert.NoError(t, err)
+	return tsk
+}
+
+func TestFixedPriority(t *testing.T) {
+	low := newPolicyTask(t, task.P0)
+	high := newPolicyTask(t, task.P2)
+	p := FixedPriority{}
+
+	assert.Equal(t, high, p.Next(newReadyQueues(low, high)))
+	assert.Nil(t, p.Next(newReadyQueues()))
+	assert.True(t, p.Preempts(low, high))
+	assert.False(t, p.Preempts(high, low))
+	assert.False(t, p.Preempts(low, low))
+	assert.Equal(t, Front, p.Requeue(low, PreemptedByTask))
+	assert.Zero(t, p.TimeSlice())
+}
+
+func TestNonPreemptive(t *testing.T) {
+	low := newPolicyTask(t, task.P0)
+	high := newPolicyTask(t, task.P2)
+	p := NonPreemptive{}
+
+	assert.Equal(t, high, p.Next(newReadyQueues(low, high)))
+	assert.False(t, p.Preempts(low, high))
+	assert.Zero(t, p.TimeSlice())
+}
+
+func TestRoundRobin(t *testing.T) {
+	first := newPolicyTask(t, task.P1)
+	second := newPolicyTask(t, task.P1)
+	high := newPolicyTask(t, task.P2)
+	p := RoundRobin{Slice: time.Second}
+
+	assert.Equal(t, first, p.Next(newReadyQueues(first, second)))
+	assert.True(t, p.Preempts(first, high))
+	assert.False(t, p.Preempts(first, second))
+	assert.Equal(t, Front, p.Requeue(first, PreemptedByTask))
+	assert.Equal(t, Back, p.Requeue(first, SliceExpired))
+	assert.Equal(t, time.Second, p.TimeSlice())
+}
+
+func TestEDF(t *testing.T) {
+	now := time.Now()
+	noDeadline := newPolicyTask(t, task.P3)
+	late := newPolicyTask(t, task.P2, task.WithDeadline(now.Add(time.Minute)))
+	early := newPolicyTask(t, task.P0, task.WithDeadline(now.Add(time.Second)))
+	earlyHigh := newPolicyTask(t, task.P1, task.WithDeadline(now.Add(time.Second)))
+	p := EDF{}
+
+	assert.Equal(t, early, p.Next(newReadyQueues(noDeadline, late, early)))
+	assert.Equal(t, earlyHigh, p.Next(newReadyQueues(early, earlyHigh)))
+	assert.Equal(t, noDeadline, p.Next(newReadyQueues(noDeadline)))
+	assert.True(t, p.Preempts(late, early))
+	assert.True(t, p.Preempts(noDeadline, late))
+	assert.False(t, p.Preempts(early, late))
+	assert.False(t, p.Preempts(early, noDeadline))
+	assert.False(t, p.Preempts(early, earlyHigh))
+	assert.Equal(t, Front, p.Requeue(late, PreemptedByTask))
+}
+
+func TestScheduler_NonPreemptivePolicy(t *testing.T) {
+	s, err := New(WithPolicy(NonPreemptive{}), WithReadyLimit(1), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	low := newPolicyTask(t, task.P0)
+	high := newPolicyTask(t, task.P3)
+	s.AddNewTask(low)
+	s.AddNewTask(high)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	assert.Equal(t, []int{low.ID, high.ID}, getTaskExecutionOrder(dumps(s)))
+}
+
+func TestScheduler_RoundRobinPolicy(t *testing.T) {
+	s, err := New(WithPolicy(RoundRobin{Slice: 30 * time.Millisecond}), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	first := newPolicyTask(t, task.P1, task.WithSleepTime(20*time.Millisecond))
+	second := newPolicyTask(t, task.P1, task.WithSleepTime(20*time.Millisecond))
+	s.AddNewTask(first)
+	s.AddNewTask(second)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	order := getTaskExecutionOrder(dumps(s))
+	assert.GreaterOrEqual(t, len(order), 3)
+	assert.Equal(t, []int{first.ID, second.ID, first.ID}, order[:3])
+	assert.Equal(t, task.Suspended, first.GetState())
+	assert.Equal(t, task.Suspended, second.GetState())
+}
+
+func TestScheduler_EDFPolicy(t *testing.T) {
+	s, err := New(WithPolicy(EDF{}), WithTickDuration(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	early := newPolicyTask(t, task.P0, task.WithDeadline(now.Add(time.Second)))
+	mid := newPolicyTask(t, task.P1, task.WithDeadline(now.Add(2*time.Second)))
+	late := newPolicyTask(t, task.P3, task.WithDeadline(now.Add(3*time.Second)))
+	s.AddNewTask(early)
+	s.AddNewTask(mid)
+	s.AddNewTask(late)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	assert.Equal(t, []int{early.ID, mid.ID, late.ID}, getTaskExecutionOrder(dumps(s)))
+}
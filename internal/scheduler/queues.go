@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"math/bits"
+	"scheduler/internal/task"
+)
+
+// priorityBitmap has a bit set for every priority level with queued tasks,
+// so the highest one is found by scanning words instead of queues.
+type priorityBitmap []uint64
+
+func newPriorityBitmap(levels int) priorityBitmap {
+	return make(priorityBitmap, (levels+63)/64)
+}
+
+func (b priorityBitmap) set(p int) {
+	b[p/64] |= 1 << (p % 64)
+}
+
+func (b priorityBitmap) clear(p int) {
+	b[p/64] &^= 1 << (p % 64)
+}
+
+func (b priorityBitmap) highest() int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] != 0 {
+			return i*64 + 63 - bits.LeadingZeros64(b[i])
+		}
+	}
+	return -1
+}
+
+// PriorityQueues keeps a FIFO queue per priority level. Policies get it
+// read-only; only the scheduler and the simulator modify it.
+type PriorityQueues struct {
+	queues [][]*task.Task
+	bitmap priorityBitmap
+	count  int
+}
+
+func NewPriorityQueues(levels int) PriorityQueues {
+	q := PriorityQueues{
+		queues: make([][]*task.Task, levels),
+		bitmap: newPriorityBitmap(levels),
+	}
+	for i := range q.queues {
+		q.queues[i] = make([]*task.Task, 0)
+	}
+	return q
+}
+
+func (q *PriorityQueues) Len() int {
+	return q.count
+}
+
+func (q *PriorityQueues) Levels() int {
+	return len(q.queues)
+}
+
+func (q *PriorityQueues) Queue(p task.TaskPriority) []*task.Task {
+	return q.queues[p]
+}
+
+// Highest returns the head of the highest non-empty priority level.
+func (q *PriorityQueues) Highest() *task.Task {
+	p := q.bitmap.highest()
+	if p < 0 {
+		return nil
+	}
+	return q.queues[p][0]
+}
+
+// Each calls fn for every queued task from the highest priority level down,
+// in queue order, until fn returns false.
+func (q *PriorityQueues) Each(fn func(t *task.Task) bool) {
+	for p := q.bitmap.highest(); p >= 0; p-- {
+		for _, t := range q.queues[p] {
+			if !fn(t) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns a copy holding only the tasks fn accepts.
+func (q *PriorityQueues) Filter(fn func(t *task.Task) bool) *PriorityQueues {
+	res := NewPriorityQueues(q.Levels())
+	q.Each(func(t *task.Task) bool {
+		if fn(t) {
+			res.PushBack(t)
+		}
+		return true
+	})
+	return &res
+}
+
+func (q *PriorityQueues) PushBack(t *task.Task) {
+	p := int(t.GetPriority())
+	q.queues[p] = append(q.queues[p], t)
+	q.bitmap.set(p)
+	q.count++
+}
+
+func (q *PriorityQueues) PushFront(t *task.Task) {
+	p := int(t.GetPriority())
+	q.queues[p] = append([]*task.Task{t}, q.queues[p]...)
+	q.bitmap.set(p)
+	q.count++
+}
+
+func (q *PriorityQueues) Push(t *task.Task, pos QueuePosition) {
+	if pos == Front {
+		q.PushFront(t)
+		return
+	}
+	q.PushBack(t)
+}
+
+func (q *PriorityQueues) popHighest() *task.Task {
+	t := q.Highest()
+	if t != nil {
+		q.Remove(t)
+	}
+	return t
+}
+
+// Remove reports whether t was queued.
+func (q *PriorityQueues) Remove(t *task.Task) bool {
+	p := int(t.GetPriority())
+	for i, v := range q.queues[p] {
+		if v == t {
+			q.queues[p] = append(q.queues[p][:i:i], q.queues[p][i+1:]...)
+			if len(q.queues[p]) == 0 {
+				q.bitmap.clear(p)
+			}
+			q.count--
+			return true
+		}
+	}
+	return false
+}
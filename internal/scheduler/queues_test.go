@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityBitmap(t *testing.T) {
+	b := newPriorityBitmap(task.MaxPriorityLevels)
+	assert.Len(t, b, 4)
+	assert.Equal(t, -1, b.highest())
+
+	b.set(3)
+	b.set(64)
+	b.set(200)
+	assert.Equal(t, 200, b.highest())
+
+	b.clear(200)
+	assert.Equal(t, 64, b.highest())
+
+	b.set(255)
+	assert.Equal(t, 255, b.highest())
+
+	b.clear(255)
+	b.clear(64)
+	assert.Equal(t, 3, b.highest())
+
+	b.clear(3)
+	assert.Equal(t, -1, b.highest())
+}
+
+func TestPriorityQueues(t *testing.T) {
+	q := NewPriorityQueues(task.MaxPriorityLevels)
+
+	low, err := task.New(task.Basic, 1, task.Ready)
+	assert.NoError(t, err)
+	high1, err := task.New(task.Basic, 130, task.Ready)
+	assert.NoError(t, err)
+	high2, err := task.New(task.Basic, 130, task.Ready)
+	assert.NoError(t, err)
+	preempted, err := task.New(task.Basic, 130, task.Ready)
+	assert.NoError(t, err)
+
+	q.PushBack(low)
+	q.PushBack(high1)
+	q.PushBack(high2)
+	q.PushFront(preempted)
+	assert.Equal(t, 4, q.Len())
+
+	assert.Equal(t, preempted, q.popHighest())
+	assert.Equal(t, high1, q.popHighest())
+	assert.Equal(t, high2, q.popHighest())
+	assert.Equal(t, low, q.popHighest())
+	assert.Nil(t, q.popHighest())
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestScheduler_ManyPriorityLevels(t *testing.T) {
+	s, err := New(WithPriorityLevels(task.MaxPriorityLevels), WithTickDuration(time.Millisecond))
+	assert.NoError(t, err)
+
+	priorities := []task.TaskPriority{255, 17, 0, 200}
+	tasks := make([]*task.Task, 0, len(priorities))
+	for _, p := range priorities {
+		tsk, err := task.New(task.Basic, p, task.Suspended, task.WithSleepTime(time.Millisecond))
+		assert.NoError(t, err)
+		assert.NoError(t, s.AddNewTask(tsk))
+		tasks = append(tasks, tsk)
+	}
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	expectedOrder := []int{tasks[0].ID, tasks[3].ID, tasks[1].ID, tasks[2].ID}
+	assert.Equal(t, expectedOrder, getTaskExecutionOrder(dumps(s)))
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"scheduler/internal/task"
+	"slices"
+	"time"
+)
+
+// NoHolder is the holder of a resource no task holds.
+const NoHolder = -1
+
+// Resource describes a resource shared by the tasks that declared it with
+// task.WithResources.
+type Resource struct {
+	Name    string            `json:"name"`
+	Ceiling task.TaskPriority `json:"ceiling"`
+	Users   []int             `json:"users"`
+	Holder  int               `json:"holder"`
+}
+
+// ResourceViolation is a misuse of resources the scheduler detected, such as
+// releasing resources out of the order they were taken in.
+type ResourceViolation struct {
+	Time     time.Time `json:"time"`
+	TaskID   int       `json:"task_id"`
+	Resource string    `json:"resource"`
+	Message  string    `json:"message"`
+}
+
+func (v ResourceViolation) String() string {
+	return fmt.Sprintf("%s task %d resource %s: %s", v.Time.Format(time.RFC3339Nano), v.TaskID, v.Resource, v.Message)
+}
+
+// resource is taken under the priority ceiling protocol: its holder runs at
+// the highest priority of the tasks using it, so none of them can preempt
+// the holder and block on the resource.
+type resource struct {
+	users  []*task.Task
+	holder *task.Task
+}
+
+// ceiling is computed when the resource is taken, so it follows priority
+// changes of the users.
+func (r *resource) ceiling() task.TaskPriority {
+	res := task.TaskPriority(0)
+	for _, t := range r.users {
+		res = max(res, t.GetBasePriority())
+	}
+	return res
+}
+
+// addResourceUser registers t with the resources it declared.
+func (s *Scheduler) addResourceUser(t *task.Task) {
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	for _, name := range t.GetResources() {
+		r, ok := s.resources[name]
+		if !ok {
+			r = &resource{}
+			s.resources[name] = r
+		}
+		r.users = slices.DeleteFunc(r.users, func(u *task.Task) bool { return u.ID == t.ID })
+		r.users = append(r.users, t)
+	}
+}
+
+// Resources returns all resources ordered by name.
+func (s *Scheduler) Resources() []Resource {
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	res := make([]Resource, 0, len(s.resources))
+	for _, name := range slices.Sorted(maps.Keys(s.resources)) {
+		r := s.resources[name]
+		v := Resource{Name: name, Ceiling: r.ceiling(), Users: make([]int, 0, len(r.users)), Holder: NoHolder}
+		for _, t := range r.users {
+			v.Users = append(v.Users, t.ID)
+		}
+		slices.Sort(v.Users)
+		if r.holder != nil {
+			v.Holder = r.holder.ID
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// ResourceViolations returns the resource violations detected so far.
+func (s *Scheduler) ResourceViolations() []ResourceViolation {
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	return slices.Clone(s.violations)
+}
+
+// handleResource answers a resource request of cur, the task running on c.
+// Releasing a resource may let a ready task preempt cur.
+func (s *Scheduler) handleResource(c *core, cur *task.Task, req task.ResourceRequest) error {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	r, ok := s.resources[req.Name]
+	if !ok || !cur.UsesResource(req.Name) {
+		return ErrResourceAccess
+	}
+	held := s.held[cur.ID]
+
+	if !req.Release {
+		if r.holder != nil {
+			return ErrResourceHeld
+		}
+		r.holder = cur
+		s.held[cur.ID] = append(held, req.Name)
+		cur.SetCeiling(max(cur.GetPriority(), r.ceiling()))
+		s.logTask(slog.LevelDebug, "resource taken", cur, "resource", req.Name, "ceiling", r.ceiling())
+		return nil
+	}
+
+	switch {
+	case r.holder != cur:
+		return ErrResourceNotHeld
+	case held[len(held)-1] != req.Name:
+		s.violateLocked(cur, req.Name, fmt.Sprintf("released before %s, which was taken later", held[len(held)-1]))
+		return ErrReleaseOrder
+	}
+	s.releaseLocked(cur, len(held)-1)
+	s.logTask(slog.LevelDebug, "resource released", cur, "resource", req.Name)
+	if next := s.cfg.Policy.Next(s.readyFor(c)); next != nil && s.cfg.Policy.Preempts(cur, next) {
+		s.interrupt(c)
+	}
+	return nil
+}
+
+// releaseAll releases the resources t still holds, reporting why as a
+// violation unless reason is empty. It is called with rMu held, since the
+// priority of t changes.
+func (s *Scheduler) releaseAll(t *task.Task, reason string) {
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	for held := s.held[t.ID]; len(held) > 0; held = s.held[t.ID] {
+		name := held[len(held)-1]
+		if reason != "" {
+			s.violateLocked(t, name, reason)
+		}
+		s.releaseLocked(t, len(held)-1)
+	}
+}
+
+// releaseLocked releases the i-th resource t holds and lowers its ceiling to
+// that of the resources left. It is called with resMu held.
+func (s *Scheduler) releaseLocked(t *task.Task, i int) {
+	held := s.held[t.ID]
+	s.resources[held[i]].holder = nil
+	held = slices.Delete(held, i, i+1)
+	ceiling := task.TaskPriority(-1)
+	for _, name := range held {
+		ceiling = max(ceiling, s.resources[name].ceiling())
+	}
+	t.SetCeiling(ceiling)
+	if len(held) == 0 {
+		delete(s.held, t.ID)
+		return
+	}
+	s.held[t.ID] = held
+}
+
+// violate records a resource violation of t on the resource it took last.
+func (s *Scheduler) violate(t *task.Task, msg string) {
+	s.resMu.Lock()
+	defer s.resMu.Unlock()
+	held := s.held[t.ID]
+	if len(held) == 0 {
+		return
+	}
+	s.violateLocked(t, held[len(held)-1], msg)
+}
+
+func (s *Scheduler) violateLocked(t *task.Task, name, msg string) {
+	v := ResourceViolation{Time: s.cfg.Clock.Now(), TaskID: t.ID, Resource: name, Message: msg}
+	s.violations = append(s.violations, v)
+	s.logTask(slog.LevelWarn, "resource violation", t, "resource", name, "violation", msg)
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"scheduler/internal/validate"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_PriorityCeiling(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, err := New(WithClock(clock.NewVirtual(start)), withQueueDumps())
+	assert.NoError(t, err)
+
+	high, err := task.NewWithFunc(task.Basic, task.P2, func(task.Context) error { return nil }, task.WithResources("r"))
+	assert.NoError(t, err)
+	mid := newPolicyTask(t, task.P1, task.WithProgressLimit(1))
+	var midWhileHeld, midAfterRelease task.TaskState
+	low, err := task.NewWithFunc(task.Basic, task.P0, func(ctx task.Context) error {
+		if err := ctx.GetResource("r"); err != nil {
+			return err
+		}
+		assert.ErrorIs(t, ctx.GetResource("r"), ErrResourceHeld)
+		assert.ErrorIs(t, ctx.GetResource("x"), ErrResourceAccess)
+		assert.Equal(t, task.TaskPriority(task.P2), ctx.Task().GetPriority())
+		// mid outranks low but not the ceiling of r.
+		assert.NoError(t, s.AddNewTask(mid))
+		ctx.Sleep(time.Second)
+		midWhileHeld = mid.GetState()
+		if err := ctx.ReleaseResource("r"); err != nil {
+			return err
+		}
+		assert.Equal(t, task.TaskPriority(task.P0), ctx.Task().GetPriority())
+		ctx.Yield()
+		midAfterRelease = mid.GetState()
+		return nil
+	}, task.WithResources("r"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(high))
+	assert.NoError(t, s.AddNewTask(low))
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	assert.Equal(t, task.Ready, midWhileHeld)
+	assert.Equal(t, task.Suspended, midAfterRelease)
+	assert.Equal(t, []int{high.ID, low.ID, mid.ID, low.ID}, getTaskExecutionOrder(dumps(s)))
+	assert.Equal(t, []Resource{{Name: "r", Ceiling: task.P2, Users: []int{high.ID, low.ID}, Holder: NoHolder}}, s.Resources())
+	assert.Empty(t, s.ResourceViolations())
+	assert.Empty(t, validate.Trace(dumps(s), validate.Default(DefaultMaxReadyTasks)...))
+}
+
+func TestScheduler_ResourceViolations(t *testing.T) {
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})))
+	assert.NoError(t, err)
+
+	nested, err := task.NewWithFunc(task.Basic, task.P1, func(ctx task.Context) error {
+		assert.NoError(t, ctx.GetResource("a"))
+		assert.NoError(t, ctx.GetResource("b"))
+		assert.ErrorIs(t, ctx.ReleaseResource("a"), ErrReleaseOrder)
+		assert.NoError(t, ctx.ReleaseResource("b"))
+		assert.NoError(t, ctx.ReleaseResource("a"))
+		assert.ErrorIs(t, ctx.ReleaseResource("a"), ErrResourceNotHeld)
+		// Ending the task releases b.
+		return ctx.GetResource("b")
+	}, task.WithResources("a", "b"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(nested))
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	violations := s.ResourceViolations()
+	if assert.Len(t, violations, 2) {
+		assert.Equal(t, "a", violations[0].Resource)
+		assert.Contains(t, violations[0].Message, "released before b")
+		assert.Equal(t, "b", violations[1].Resource)
+		assert.Equal(t, nested.ID, violations[1].TaskID)
+	}
+	for _, r := range s.Resources() {
+		assert.Equal(t, NoHolder, r.Holder)
+	}
+	assert.Equal(t, task.TaskPriority(task.P1), nested.GetPriority())
+}
@@ -1,189 +1,687 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"log/slog"
+	"maps"
+	"scheduler/internal/clock"
 	"scheduler/internal/task"
+	"scheduler/internal/trace"
 	"scheduler/internal/utils"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const (
-	WaitTaskTime  time.Duration = 100 * time.Millisecond
-	MaxReadyTasks int           = 5
-	ReadyLimit    int           = MaxReadyTasks - 2
-)
-
 type Scheduler struct {
-	currentTask    *task.Task
-	readyQueues    [][]*task.Task
+	cfg            Config
+	cores          []*core
+	readyQueues    []PriorityQueues
 	suspendedQueue []*task.Task
-	waitingQueues  [][]*task.Task
+	waitingQueues  PriorityQueues
+	tasks          map[int]*task.Task
+	partitions     map[int]int
+	nextPartition  int
+	journal        *journal
+	stats          *stats
+	tMu            sync.Mutex
 	sMu            sync.Mutex
 	rMu            sync.Mutex
 	wMu            sync.Mutex
-	ctMu           sync.Mutex
+	counters       map[string]*counter
+	alarms         map[string]*alarm
+	alarmWake      chan struct{}
+	aMu            sync.Mutex
+	resources      map[string]*resource
+	held           map[int][]string
+	violations     []ResourceViolation
+	resMu          sync.Mutex
 	StopChan       chan struct{}
-	interruptChan  chan struct{}
-	Dumps          []scheduler_dump
-	noTasksCount   int
+	seq            uint64
+	events         chan trace.Event
+	eventsMu       sync.Mutex
+	eventsClosed   bool
+	dumpQueues     bool
+	dumpBlocks     bool
+	dropped        atomic.Uint64
+	holds          atomic.Int64
+	sinkDone       chan struct{}
+	cancel         context.CancelFunc
+	done           <-chan struct{}
+	wg             sync.WaitGroup
 }
 
-func New() *Scheduler {
-	s := Scheduler{}
+func New(opts ...Option) (*Scheduler, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := Scheduler{cfg: cfg}
 	s.StopChan = make(chan struct{})
-	s.interruptChan = make(chan struct{})
-	s.readyQueues = make([][]*task.Task, 4)
+	s.cores = make([]*core, cfg.Cores)
+	for i := range s.cores {
+		s.cores[i] = newCore(i)
+	}
+	partitions := 1
+	if cfg.Partitioned {
+		partitions = cfg.Cores
+	}
+	s.readyQueues = make([]PriorityQueues, partitions)
+	for i := range s.readyQueues {
+		s.readyQueues[i] = NewPriorityQueues(cfg.PriorityLevels)
+	}
 	s.suspendedQueue = make([]*task.Task, 0)
-	s.waitingQueues = make([][]*task.Task, 4)
-	for i := task.P0; i < task.P3; i++ {
-		s.readyQueues[i] = make([]*task.Task, 0)
-		s.waitingQueues[i] = make([]*task.Task, 0)
+	s.waitingQueues = NewPriorityQueues(cfg.PriorityLevels)
+	s.tasks = make(map[int]*task.Task)
+	s.partitions = make(map[int]int)
+	s.journal = newJournal(cfg.Journal, cfg.Logger)
+	s.stats = newStats()
+	s.counters = map[string]*counter{SystemCounter: {tick: cfg.TickDuration, max: DefaultCounterMaxValue, start: cfg.Clock.Now()}}
+	s.alarms = make(map[string]*alarm)
+	s.alarmWake = make(chan struct{}, 1)
+	s.resources = make(map[string]*resource)
+	s.held = make(map[int][]string)
+	s.events = make(chan trace.Event, cfg.DumpBuffer)
+	s.dumpQueues = wantsQueues(cfg.DumpSink)
+	_, virtual := cfg.Clock.(*clock.Virtual)
+	s.dumpBlocks = cfg.DumpOverflow == DumpBlock || virtual
+	s.sinkDone = make(chan struct{})
+	// Events of tasks added before Run are delivered at once, so they do
+	// not fill the buffer.
+	go s.deliverDumps()
+	return &s, nil
+}
+
+func (s *Scheduler) Config() Config {
+	return s.cfg
+}
+
+// Run processes tasks on every core until ctx is cancelled or the scheduler
+// stays idle. StopChan is closed once all goroutines have exited and the dump
+// sink got all events.
+func (s *Scheduler) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.sMu.Lock()
+	s.cancel = cancel
+	s.done = ctx.Done()
+	s.sMu.Unlock()
+	s.admit()
+	s.wg.Add(len(s.cores) + 1)
+	for _, c := range s.cores {
+		s.cfg.Clock.Busy()
+		go s.processTasks(ctx, c)
 	}
-	s.Dumps = make([]scheduler_dump, 0)
-	return &s
+	s.cfg.Clock.Busy()
+	go s.runAlarms(ctx)
+	go func() {
+		s.wg.Wait()
+		s.closeDumps()
+		<-s.sinkDone
+		close(s.StopChan)
+	}()
 }
 
-func (s *Scheduler) Run() {
-	go s.processTasks()
-	go s.manageQueues()
+// Shutdown interrupts the current task and waits for the scheduler to stop.
+// Tasks left in the queues are reported with ErrTasksLeft.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.sMu.Lock()
+	cancel := s.cancel
+	s.sMu.Unlock()
+	if cancel != nil {
+		cancel()
+		select {
+		case <-s.StopChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.leftTasks()
 }
 
-func (s *Scheduler) processTasks() {
+// processTasks runs the tasks of c. Whenever it blocks, it tells the clock
+// it is idle; whoever wakes it up has marked it busy again.
+func (s *Scheduler) processTasks(ctx context.Context, c *core) {
+	defer s.wg.Done()
+	clk := s.cfg.Clock
+	defer clk.Idle()
+	var slice clock.Timer
+	defer func() { stopTimer(slice) }()
 	for {
-		if s.currentTask == nil {
-			t := s.popNextFromReady()
-			if t == nil {
-				time.Sleep(WaitTaskTime)
-				s.noTasksCount++
-				if s.noTasksCount >= 10 {
-					s.sortDumpsByTimestamp()
-					log.Println("PROCESS EXITING...")
-					close(s.StopChan)
+		cur := c.running()
+		if cur == nil {
+			stopTimer(slice)
+			slice = nil
+			if ctx.Err() != nil {
+				s.cfg.Logger.Info("core stopped", "core", c.id)
+				return
+			}
+			cur = s.dispatchNext(c)
+			if cur == nil {
+				tick := clk.NewTimer(s.cfg.TickDuration)
+				clk.Idle()
+				select {
+				case <-ctx.Done():
+					clk.Busy()
+					tick.Stop()
+					continue
+				case <-tick.C():
+				}
+				if s.isIdle(c.tick()) {
+					s.cfg.Logger.Info("core stopped", "core", c.id, "idle_ticks", s.cfg.IdleTicks)
+					s.cancel()
 					return
 				}
 				continue
 			}
-			s.noTasksCount = 0
-			s.currentTask = t
-			s.currentTask.SetState(task.Running)
-			s.dump(fmt.Sprintf("task ready -> running | ID=%d\n", t.ID))
-			go s.currentTask.Do()
+			s.dump(task.Ready, task.Running, cur, c.id)
+			s.admit()
+			// A task admitted meanwhile preempts cur before it starts.
+			select {
+			case <-c.interruptChan:
+				clk.Idle()
+				s.stats.preempt(cur)
+				s.interruptCurrentTask(c, s.cfg.Policy.Requeue(cur, PreemptedByTask))
+				continue
+			default:
+			}
+			if d := s.cfg.Policy.TimeSlice(); d > 0 {
+				slice = clk.NewTimer(d)
+			}
+			cur.Do()
 		}
+		clk.Idle()
 		select {
-		case <-s.interruptChan:
-			slog.Debug("PROCESS TASK INTERRUPTING")
-			s.currentTask.Interrupt()
-			slog.Debug("PROCESS TASK INTERRUPTED")
-			s.currentTask.SetState(task.Ready)
-			s.prependToReady(s.currentTask)
-			id := s.currentTask.ID
-			s.nilCurrentTask()
-			s.dump(fmt.Sprintf("task running -> ready | ID=%d\n", id))
-		case <-s.currentTask.DoneChan:
-			slog.Debug("PROCESS TASK DONE")
-			s.currentTask.SetState(task.Suspended)
-			id := s.currentTask.ID
-			s.nilCurrentTask()
-			s.dump(fmt.Sprintf("task running -> suspended | ID=%d\n", id))
-		case _, ok := <-s.currentTask.WaitChan:
-			if !ok {
+		case <-ctx.Done():
+			clk.Busy()
+			s.interruptCurrentTask(c, Front)
+		case <-c.interruptChan:
+			s.stats.preempt(cur)
+			s.interruptCurrentTask(c, s.cfg.Policy.Requeue(cur, PreemptedByTask))
+		case <-timerC(slice):
+			if !s.hasReadyPeer(c, cur) {
+				slice = clk.NewTimer(s.cfg.Policy.TimeSlice())
 				continue
 			}
-			slog.Debug("PROCESS TASK WAIT")
-			s.currentTask.SetState(task.Waiting)
-			s.appendToWaiting(s.currentTask)
-			close(s.currentTask.WaitChan)
-			id := s.currentTask.ID
-			s.nilCurrentTask()
-			s.dump(fmt.Sprintf("task running -> waiting | ID=%d\n", id))
+			s.interruptCurrentTask(c, s.cfg.Policy.Requeue(cur, SliceExpired))
+		case <-cur.DoneChan:
+			c.setCurrent(nil)
+			s.complete(cur, c.id)
+		case <-cur.WaitChan:
+			s.violate(cur, "waits for events holding the resource")
+			if !s.waitCurrentTask(c) {
+				// The event was set before the task got to the waiting queue.
+				cur.Do()
+				continue
+			}
+		case <-cur.YieldChan:
+			s.interruptCurrentTask(c, Back)
+		case target := <-cur.EventChan:
+			s.releaseWaiting(target)
+			continue
+		case req := <-cur.ResourceChan:
+			req.Reply(s.handleResource(c, cur, req))
+			continue
 		}
-		slog.Debug("PROCESS", slog.Any("SUS", s.suspendedQueue))
-		slog.Debug("PROCESS", slog.Any("REA", s.readyQueues))
-		slog.Debug("PROCESS", slog.Any("WAI", s.waitingQueues))
 	}
 }
 
-func (s *Scheduler) manageQueues() {
-	for {
-		select {
-		case <-s.StopChan:
-			log.Println("MANAGE EXITING...")
-			return
-		default:
+// isIdle reports whether every core has been idle for IdleTicks ticks, no
+// task is queued to run and nothing holds the scheduler or sets an alarm.
+func (s *Scheduler) isIdle(ticks int) bool {
+	if s.cfg.IdleTicks == 0 || ticks < s.cfg.IdleTicks || s.holds.Load() > 0 || s.alarmsSet() {
+		return false
+	}
+	for _, c := range s.cores {
+		if c.idleTicks() < s.cfg.IdleTicks {
+			return false
 		}
+	}
+	// A task added during the last tick has not reached a core yet.
+	s.sMu.Lock()
+	suspended := len(s.suspendedQueue)
+	s.sMu.Unlock()
+	return suspended == 0 && s.readyLen() == 0
+}
 
-		if utils.LMDA(s.readyQueues) < ReadyLimit && len(s.suspendedQueue) > 0 {
-			slog.Debug("MANAGE", slog.Any("SUS", s.suspendedQueue))
-			slog.Debug("MANAGE", slog.Any("REA", s.readyQueues))
-			t := s.popNextFromSuspended()
-			s.appendToReady(t)
-			slog.Debug("MANAGE", slog.Any("SUS", s.suspendedQueue))
-			slog.Debug("MANAGE", slog.Any("REA", s.readyQueues))
-			s.dump(fmt.Sprintf("task suspended -> ready | ID=%d\n", t.ID))
-		}
-		s.ctMu.Lock()
-		if s.currentTask != nil {
-			select {
-			case _, ok := <-s.currentTask.EventChan:
-				if !ok {
-					s.ctMu.Unlock()
-					continue
-				}
-				t := s.popNextFromWaiting()
-				if t == nil {
-					s.ctMu.Unlock()
-					continue
-				}
-				close(s.currentTask.EventChan)
-				s.ctMu.Unlock()
-				s.prependToReady(t)
-				s.dump(fmt.Sprintf("task waiting -> ready | ID=%d\n", t.ID))
-			default:
-				s.ctMu.Unlock()
-				continue
-			}
-		} else {
-			s.ctMu.Unlock()
+// Hold keeps the scheduler from stopping when idle until release is called,
+// so tasks that arrive later still get run.
+func (s *Scheduler) Hold() (release func()) {
+	s.holds.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.holds.Add(-1) })
+	}
+}
+
+// interruptCurrentTask clears the current task of the core before requeueing
+// it, so checkInterruption no longer takes it for running. The transition is
+// dumped holding the queue locks, so no other core dispatches the task first.
+func (s *Scheduler) interruptCurrentTask(c *core, pos QueuePosition) {
+	t := c.running()
+	t.Interrupt()
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+	c.setCurrent(nil)
+	s.requeueToReady(t, pos)
+	s.dumpLocked(task.Running, task.Ready, t, c.id)
+}
+
+// admit moves released waiting tasks to ready while the ready queues are
+// below MaxReadyTasks, then suspended tasks while they are below ReadyLimit.
+// It is called whenever the ready queues may have room.
+func (s *Scheduler) admit() {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+	if s.done == nil || utils.IsChannelClosed(s.done) {
+		return
+	}
+	for s.readyLenLocked() < s.cfg.MaxReadyTasks {
+		t := s.nextReleased()
+		if t == nil {
+			break
 		}
+		s.waitingQueues.Remove(t)
+		s.prependToReady(t)
+	}
+	for s.readyLenLocked() < s.cfg.ReadyLimit && len(s.suspendedQueue) > 0 {
+		s.appendToReady(s.popNextFromSuspended())
+	}
+}
+
+// releaseWaiting moves target to ready if one of the events it waits for is
+// set. The built-in task body signals a nil target, which sets DefaultEvent on
+// the highest-priority task waiting for it. While the ready queues are full,
+// a released task stays waiting until admit finds room for it.
+func (s *Scheduler) releaseWaiting(target *task.Task) {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+	if s.readyLenLocked() >= s.cfg.MaxReadyTasks {
+		s.eventTarget(target)
+		return
+	}
+	if t := s.popReleasedFromWaiting(target); t != nil {
+		s.prependToReady(t)
 	}
 }
 
-func (s *Scheduler) AddNewTask(t *task.Task) {
+func (s *Scheduler) AddNewTask(t *task.Task) error {
+	if err := s.cfg.CheckTask(t.GetPriority(), t.GetAffinity()); err != nil {
+		return err
+	}
+	s.tMu.Lock()
+	s.tasks[t.ID] = t
+	if s.cfg.Partitioned {
+		p := t.GetAffinity()
+		if p == task.AnyCore {
+			p = s.nextPartition
+			s.nextPartition = (s.nextPartition + 1) % s.cfg.Cores
+		}
+		s.partitions[t.ID] = p
+	}
+	s.tMu.Unlock()
+	s.addResourceUser(t)
+	t.SetClock(s.cfg.Clock)
+	t.InheritLogger(s.cfg.Logger)
+	s.logTask(slog.LevelInfo, "task added", t)
+	s.rMu.Lock()
 	s.sMu.Lock()
+	s.wMu.Lock()
 	t.SetState(task.Suspended)
 	s.suspendedQueue = append(s.suspendedQueue, t)
+	s.record("", task.Suspended, t, false)
+	s.dumpLocked("", task.Suspended, t, trace.NoCore)
+	s.wMu.Unlock()
 	s.sMu.Unlock()
-	s.dump(fmt.Sprintf("task -> suspended | ID=%d\n", t.ID))
+	s.rMu.Unlock()
+	s.admit()
+	return nil
 }
 
-func (s *Scheduler) appendToReady(t *task.Task) {
+// ActivateTask activates a task as OSEK ActivateTask does. A done or
+// cancelled task starts over from the suspended queue. Activations of a task
+// still queued or running are queued, up to its max activations, and start it
+// over once it is done.
+func (s *Scheduler) ActivateTask(id int) error {
+	return s.activateTask(id, true)
+}
+
+// activateTask waits for the body of a cancelled task to stop unless wait is
+// false; then a goroutine activates the task once it has.
+func (s *Scheduler) activateTask(id int, wait bool) error {
+	t, err := s.Task(id)
+	if err != nil {
+		return err
+	}
+	var queued bool
+	for {
+		s.rMu.Lock()
+		s.sMu.Lock()
+		s.wMu.Lock()
+		queued = t.GetState() != task.Suspended || slices.Contains(s.suspendedQueue, t)
+		if queued || utils.IsChannelClosed(t.Stopped()) {
+			break
+		}
+		s.wMu.Unlock()
+		s.sMu.Unlock()
+		s.rMu.Unlock()
+		if !wait {
+			s.activateLater(t)
+			return nil
+		}
+		// The body of a cancelled task may still be on its way out.
+		<-t.Stopped()
+	}
+	if queued {
+		if err = t.QueueActivation(); err == nil {
+			s.journal.write(journalRecord{Update: true, To: t.GetState(), Partition: s.partitionOf(t), Task: t.Snapshot()})
+		}
+	} else {
+		s.activate(t)
+	}
+	s.wMu.Unlock()
+	s.sMu.Unlock()
+	s.rMu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.logTask(slog.LevelInfo, "task activated", t)
+	s.admit()
+	return nil
+}
+
+// activateLater activates t once its body has stopped. The clock stays busy
+// until then, as it does for ActivateTask.
+func (s *Scheduler) activateLater(t *task.Task) {
+	s.cfg.Clock.Busy()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.cfg.Clock.Idle()
+		<-t.Stopped()
+		if err := s.ActivateTask(t.ID); err != nil {
+			s.logTask(slog.LevelWarn, "task activation failed", t, "error", err)
+		}
+	}()
+}
+
+// complete suspends a task that is done and starts it over if it has queued
+// activations. Its transitions are dumped holding the queue locks, so an
+// ActivateTask in between sees it either running or done.
+func (s *Scheduler) complete(t *task.Task, core int) {
+	// The body has returned, so this does not take long.
+	<-t.Stopped()
+	s.rMu.Lock()
+	s.sMu.Lock()
+	s.wMu.Lock()
+	s.releaseAll(t, "done holding the resource")
+	t.SetState(task.Suspended)
+	s.record(task.Running, task.Suspended, t, false)
+	s.dumpLocked(task.Running, task.Suspended, t, core)
+	again := t.NextActivation()
+	if again {
+		s.activate(t)
+	}
+	s.wMu.Unlock()
+	s.sMu.Unlock()
+	s.rMu.Unlock()
+	if again {
+		s.admit()
+	}
+}
+
+// activate puts a done task back to the suspended queue with its progress
+// reset. It is called with the queue locks held, once the body has stopped.
+func (s *Scheduler) activate(t *task.Task) {
+	t.Reset()
+	s.suspendedQueue = append(s.suspendedQueue, t)
+	s.journal.write(journalRecord{Activate: true, From: task.Suspended, To: task.Suspended, Partition: s.partitionOf(t), Task: t.Snapshot()})
+	s.dumpLocked(task.Suspended, task.Suspended, t, trace.NoCore)
+}
+
+// SetEvent sets events of an extended task and releases it if it waits for
+// one of them.
+func (s *Scheduler) SetEvent(id int, mask task.EventMask) error {
+	t, err := s.eventTask(id)
+	if err != nil {
+		return err
+	}
+	t.SetEvent(mask)
+	s.releaseWaiting(t)
+	return nil
+}
+
+// GetEvent returns the events currently set for an extended task.
+func (s *Scheduler) GetEvent(id int) (task.EventMask, error) {
+	t, err := s.eventTask(id)
+	if err != nil {
+		return 0, err
+	}
+	return t.GetEvent(), nil
+}
+
+// Task returns the task added with the given ID.
+func (s *Scheduler) Task(id int) (*task.Task, error) {
+	s.tMu.Lock()
+	defer s.tMu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrUnknownTask
+	}
+	return t, nil
+}
+
+// Tasks returns all tasks ever added, ordered by ID.
+func (s *Scheduler) Tasks() []*task.Task {
+	s.tMu.Lock()
+	defer s.tMu.Unlock()
+	res := make([]*task.Task, 0, len(s.tasks))
+	for _, id := range slices.Sorted(maps.Keys(s.tasks)) {
+		res = append(res, s.tasks[id])
+	}
+	return res
+}
+
+// SetTaskPriority changes the priority of a task. A queued task moves to the
+// back of its new priority level; the change may preempt running tasks.
+func (s *Scheduler) SetTaskPriority(id int, p task.TaskPriority) error {
+	t, err := s.Task(id)
+	if err != nil {
+		return err
+	}
+	if p < 0 || int(p) >= s.cfg.PriorityLevels {
+		return ErrInvalidPriority
+	}
 	s.rMu.Lock()
 	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+
+	ready := s.readyQueueOf(t)
+	inReady := ready.Remove(t)
+	inWaiting := s.waitingQueues.Remove(t)
+	if err := t.SetPriority(p); err != nil {
+		return err
+	}
+	if inWaiting {
+		s.waitingQueues.PushBack(t)
+	}
+	s.journal.write(journalRecord{Update: true, To: t.GetState(), Partition: s.partitionOf(t), Task: t.Snapshot()})
+	if inReady {
+		ready.PushBack(t)
+		s.checkInterruption(t)
+	}
+	for _, c := range s.cores {
+		if c.running() != t {
+			continue
+		}
+		if next := s.cfg.Policy.Next(s.readyFor(c)); next != nil && s.cfg.Policy.Preempts(t, next) {
+			s.interrupt(c)
+		}
+	}
+	return nil
+}
+
+// CancelTask terminates a task waiting in one of the queues. It leaves its
+// queue and becomes suspended without running again. Running and done tasks
+// are not cancelled.
+func (s *Scheduler) CancelTask(id int) error {
+	t, err := s.Task(id)
+	if err != nil {
+		return err
+	}
+	s.rMu.Lock()
+	s.sMu.Lock()
+	s.wMu.Lock()
+	from := t.GetState()
+	removed := false
+	if i := slices.Index(s.suspendedQueue, t); i >= 0 {
+		s.suspendedQueue = slices.Delete(s.suspendedQueue, i, i+1)
+		removed = true
+	}
+	removed = removed || s.readyQueueOf(t).Remove(t) || s.waitingQueues.Remove(t)
+	if removed {
+		s.releaseAll(t, "")
+		t.Cancel()
+		t.SetState(task.Suspended)
+		s.record(from, task.Suspended, t, false)
+		// An ActivateTask right after must not be dumped first.
+		s.dumpLocked(from, task.Suspended, t, trace.NoCore)
+	}
+	s.wMu.Unlock()
+	s.sMu.Unlock()
+	s.rMu.Unlock()
+	if !removed {
+		return ErrTaskNotQueued
+	}
+	s.admit()
+	return nil
+}
+
+func (s *Scheduler) eventTask(id int) (*task.Task, error) {
+	s.tMu.Lock()
+	t, ok := s.tasks[id]
+	s.tMu.Unlock()
+	if !ok {
+		return nil, ErrUnknownTask
+	}
+	if t.GetType() != task.Extended {
+		return nil, task.ErrNotExtended
+	}
+	if t.GetState() == task.Suspended {
+		return nil, ErrTaskSuspended
+	}
+	return t, nil
+}
+
+func (s *Scheduler) leftTasks() error {
+	ids := func(queues ...[]*task.Task) []int {
+		res := make([]int, 0)
+		for _, q := range queues {
+			for _, t := range q {
+				res = append(res, t.ID)
+			}
+		}
+		return res
+	}
+
+	s.rMu.Lock()
+	ready := make([]int, 0)
+	for _, q := range s.readyQueues {
+		ready = append(ready, ids(q.queues...)...)
+	}
+	s.rMu.Unlock()
+	s.sMu.Lock()
+	suspended := ids(s.suspendedQueue)
+	s.sMu.Unlock()
+	s.wMu.Lock()
+	waiting := ids(s.waitingQueues.queues...)
+	s.wMu.Unlock()
+
+	if len(ready)+len(suspended)+len(waiting) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w | ready=%v | suspended=%v | waiting=%v", ErrTasksLeft, ready, suspended, waiting)
+}
+
+// appendToReady and prependToReady are called with the queue locks held, so
+// the event of t is dumped before t may preempt a core.
+func (s *Scheduler) appendToReady(t *task.Task) {
 	t.SetState(task.Ready)
-	s.readyQueues[t.GetPriority()] = append(s.readyQueues[t.GetPriority()], t)
+	s.readyQueueOf(t).PushBack(t)
+	s.record(task.Suspended, task.Ready, t, false)
+	s.dumpLocked(task.Suspended, task.Ready, t, trace.NoCore)
 	s.checkInterruption(t)
 }
 
 func (s *Scheduler) prependToReady(t *task.Task) {
-	s.rMu.Lock()
-	defer s.rMu.Unlock()
 	t.SetState(task.Ready)
-	s.readyQueues[t.GetPriority()] = append([]*task.Task{t}, s.readyQueues[t.GetPriority()]...)
+	s.readyQueueOf(t).PushFront(t)
+	s.record(task.Waiting, task.Ready, t, true)
+	s.dumpLocked(task.Waiting, task.Ready, t, trace.NoCore)
 	s.checkInterruption(t)
 }
 
-func (s *Scheduler) appendToWaiting(t *task.Task) {
+// requeueToReady is called with rMu held.
+func (s *Scheduler) requeueToReady(t *task.Task, pos QueuePosition) {
+	t.SetState(task.Ready)
+	s.readyQueueOf(t).Push(t, pos)
+	s.record(task.Running, task.Ready, t, pos == Front)
+}
+
+// waitCurrentTask moves the current task of c to the waiting queue. It
+// clears c and dumps the transition holding the queue locks, so a SetEvent
+// cannot release the task before it is seen waiting.
+func (s *Scheduler) waitCurrentTask(c *core) bool {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
 	s.wMu.Lock()
 	defer s.wMu.Unlock()
-	s.waitingQueues[t.GetPriority()] = append(s.waitingQueues[t.GetPriority()], t)
+	t := c.running()
+	if !s.appendToWaiting(t) {
+		return false
+	}
+	c.setCurrent(nil)
+	s.dumpLocked(task.Running, task.Waiting, t, c.id)
+	return true
+}
+
+// appendToWaiting returns false if an awaited event is already set, so an
+// event is not lost between the task asking to wait and joining the queue.
+// It is called with wMu held.
+func (s *Scheduler) appendToWaiting(t *task.Task) bool {
+	if t.IsReleased() {
+		return false
+	}
+	t.SetState(task.Waiting)
+	s.waitingQueues.PushBack(t)
+	s.record(task.Running, task.Waiting, t, false)
+	return true
 }
 
+// popNextFromSuspended is called with sMu held.
 func (s *Scheduler) popNextFromSuspended() *task.Task {
-	s.sMu.Lock()
-	defer s.sMu.Unlock()
 	if len(s.suspendedQueue) == 0 {
 		return nil
 	}
@@ -192,45 +690,170 @@ func (s *Scheduler) popNextFromSuspended() *task.Task {
 	return res
 }
 
-func (s *Scheduler) popNextFromReady() *task.Task {
+// dispatchNext makes the next ready task current on c. It holds the ready
+// lock meanwhile, so checkInterruption never sees c idle with t taken.
+func (s *Scheduler) dispatchNext(c *core) *task.Task {
 	s.rMu.Lock()
 	defer s.rMu.Unlock()
-	for i := task.P3; i >= task.P0; i-- {
-		if len(s.readyQueues[i]) > 0 {
-			res := s.readyQueues[i][0]
-			s.readyQueues[i] = s.readyQueues[i][1:]
-			return res
+	// An interrupt still pending was meant for the task that left c.
+	select {
+	case <-c.interruptChan:
+		s.cfg.Clock.Idle()
+	default:
+	}
+	t := s.cfg.Policy.Next(s.readyFor(c))
+	if t == nil {
+		return nil
+	}
+	s.readyQueueOf(t).Remove(t)
+	t.SetState(task.Running)
+	c.setCurrent(t)
+	s.record(task.Ready, task.Running, t, false)
+	return t
+}
+
+// readyQueueOf returns the ready queues t belongs to. It is called with rMu held.
+func (s *Scheduler) readyQueueOf(t *task.Task) *PriorityQueues {
+	if !s.cfg.Partitioned {
+		return &s.readyQueues[0]
+	}
+	return &s.readyQueues[s.partitionOf(t)]
+}
+
+// readyFor returns the ready tasks c may run. It is called with rMu held.
+func (s *Scheduler) readyFor(c *core) *PriorityQueues {
+	if s.cfg.Partitioned {
+		return &s.readyQueues[c.id]
+	}
+	if len(s.cores) == 1 {
+		return &s.readyQueues[0]
+	}
+	return s.readyQueues[0].Filter(c.canRun)
+}
+
+// coresFor returns the cores t may run on.
+func (s *Scheduler) coresFor(t *task.Task) []*core {
+	if s.cfg.Partitioned {
+		p := s.partitionOf(t)
+		return s.cores[p : p+1]
+	}
+	res := make([]*core, 0, len(s.cores))
+	for _, c := range s.cores {
+		if c.canRun(t) {
+			res = append(res, c)
 		}
 	}
-	return nil
+	return res
 }
 
-func (s *Scheduler) popNextFromWaiting() *task.Task {
-	s.wMu.Lock()
-	defer s.wMu.Unlock()
-	for i := task.P3; i >= task.P0; i-- {
-		if len(s.waitingQueues[i]) > 0 {
-			res := s.waitingQueues[i][0]
-			s.waitingQueues[i] = s.waitingQueues[i][1:]
-			return res
+// popReleasedFromWaiting is called with wMu held.
+func (s *Scheduler) popReleasedFromWaiting(target *task.Task) *task.Task {
+	target = s.eventTarget(target)
+	if target == nil || !target.IsReleased() || !s.waitingQueues.Remove(target) {
+		return nil
+	}
+	return target
+}
+
+// eventTarget resolves the nil target of the built-in body to the
+// highest-priority task still waiting for DefaultEvent and sets the event on
+// it. It is called with wMu held.
+func (s *Scheduler) eventTarget(target *task.Task) *task.Task {
+	if target != nil {
+		return target
+	}
+	s.waitingQueues.Each(func(t *task.Task) bool {
+		if t.GetWaitMask()&task.DefaultEvent != 0 && !t.IsReleased() {
+			target = t
+			return false
 		}
+		return true
+	})
+	if target != nil {
+		target.SetEvent(task.DefaultEvent)
 	}
-	return nil
+	return target
+}
+
+// nextReleased returns the first waiting task whose events are set, which
+// releaseWaiting left waiting because the ready queues were full. It is
+// called with wMu held.
+func (s *Scheduler) nextReleased() *task.Task {
+	var res *task.Task
+	s.waitingQueues.Each(func(t *task.Task) bool {
+		if t.IsReleased() {
+			res = t
+			return false
+		}
+		return true
+	})
+	return res
 }
 
-func (s *Scheduler) nilCurrentTask() {
-	s.ctMu.Lock()
-	defer s.ctMu.Unlock()
-	s.currentTask = nil
+func (s *Scheduler) hasReadyPeer(c *core, t *task.Task) bool {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	return len(s.readyFor(c).Queue(t.GetPriority())) > 0
+}
+
+func (s *Scheduler) readyLen() int {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	return s.readyLenLocked()
+}
+
+func (s *Scheduler) readyLenLocked() int {
+	res := 0
+	for _, q := range s.readyQueues {
+		res += q.Len()
+	}
+	return res
 }
 
+// checkInterruption preempts the weakest core t may run on, unless one of
+// them is idle and picks t up anyway. It is called with rMu held.
 func (s *Scheduler) checkInterruption(t *task.Task) {
-	if s.currentTask != nil && s.currentTask.GetPriority() < t.GetPriority() {
-		select {
-		case s.interruptChan <- struct{}{}:
-			log.Println("Interrupt signal sent")
-		default:
-			slog.Debug("Interrupt channel is busy")
+	var victim *core
+	var victimTask *task.Task
+	for _, c := range s.coresFor(t) {
+		cur := c.running()
+		if cur == nil {
+			return
+		}
+		if !s.cfg.Policy.Preempts(cur, t) {
+			continue
+		}
+		if victim == nil || s.cfg.Policy.Preempts(cur, victimTask) {
+			victim, victimTask = c, cur
 		}
 	}
+	if victim == nil {
+		return
+	}
+	s.interrupt(victim)
+}
+
+// interrupt marks c busy before it can take the interrupt.
+func (s *Scheduler) interrupt(c *core) {
+	s.cfg.Clock.Busy()
+	select {
+	case c.interruptChan <- struct{}{}:
+		s.cfg.Logger.Debug("core interrupted", "core", c.id)
+	default:
+		s.cfg.Clock.Idle()
+		s.cfg.Logger.Debug("core interrupted already", "core", c.id)
+	}
+}
+
+func timerC(t clock.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C()
+}
+
+func stopTimer(t clock.Timer) {
+	if t != nil {
+		t.Stop()
+	}
 }
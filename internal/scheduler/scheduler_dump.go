@@ -1,64 +1,169 @@
 package scheduler
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"scheduler/internal/task"
-	"sort"
+	"scheduler/internal/trace"
 	"time"
 )
 
-type scheduler_dump struct {
-	Name           string
-	CurrentTask    *task.Task
-	ReadyQueues    [][]task.Task
-	SuspendedQueue []task.Task
-	WaitingQueues  [][]task.Task
-	Timestamp      time.Time
+// dump passes a transition of t to the dump sink and counts it in the stats.
+// Sinks that want the queues get a copy taken under the queue locks, so no
+// other transition gets in between.
+func (s *Scheduler) dump(from, to task.TaskState, t *task.Task, core int) {
+	now := s.cfg.Clock.Now()
+	s.stats.observe(from, to, t, now)
+	s.logTask(slog.LevelDebug, "task transition", t, "from", from, "core", core)
+	if _, ok := s.cfg.DumpSink.(NopSink); ok {
+		return
+	}
+	if !s.dumpQueues {
+		s.emit(s.event(from, to, t, core, now, nil))
+		return
+	}
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+	q := s.queues()
+	s.emit(s.event(from, to, t, core, now, &q))
 }
 
-type byTimestamp []scheduler_dump
+// dumpLocked is dump called holding the queue locks.
+func (s *Scheduler) dumpLocked(from, to task.TaskState, t *task.Task, core int) {
+	now := s.cfg.Clock.Now()
+	s.stats.observe(from, to, t, now)
+	s.logTask(slog.LevelDebug, "task transition", t, "from", from, "core", core)
+	if _, ok := s.cfg.DumpSink.(NopSink); ok {
+		return
+	}
+	var q *trace.Queues
+	if s.dumpQueues {
+		queues := s.queues()
+		q = &queues
+	}
+	s.emit(s.event(from, to, t, core, now, q))
+}
+
+// logTask logs msg with the attributes of t, if the logger takes level.
+func (s *Scheduler) logTask(level slog.Level, msg string, t *task.Task, args ...any) {
+	if !s.cfg.Logger.Enabled(context.Background(), level) {
+		return
+	}
+	s.cfg.Logger.Log(context.Background(), level, msg, append(t.LogAttrs(), args...)...)
+}
 
-func (a byTimestamp) Len() int           { return len(a) }
-func (a byTimestamp) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byTimestamp) Less(i, j int) bool { return a[i].Timestamp.Before(a[j].Timestamp) }
+func (s *Scheduler) event(from, to task.TaskState, t *task.Task, core int, now time.Time, q *trace.Queues) trace.Event {
+	return trace.Event{
+		Time:     now,
+		TaskID:   t.ID,
+		Priority: t.GetPriority(),
+		From:     from,
+		To:       to,
+		Core:     core,
+		Queues:   q,
+	}
+}
 
-func (s *Scheduler) dump(name string) {
+// Queues returns a copy of all queues and the running tasks.
+func (s *Scheduler) Queues() trace.Queues {
 	s.rMu.Lock()
 	defer s.rMu.Unlock()
 	s.sMu.Lock()
 	defer s.sMu.Unlock()
 	s.wMu.Lock()
 	defer s.wMu.Unlock()
+	return s.queues()
+}
 
-	dump := scheduler_dump{
-		Name:           name,
-		ReadyQueues:    make([][]task.Task, len(s.readyQueues)),
-		SuspendedQueue: make([]task.Task, len(s.suspendedQueue)),
-		WaitingQueues:  make([][]task.Task, len(s.waitingQueues)),
-		Timestamp:      time.Now(),
+// queues is called holding the queue locks.
+func (s *Scheduler) queues() trace.Queues {
+	res := trace.Queues{
+		Running:   make([]*task.Snapshot, len(s.cores)),
+		Ready:     make([][]task.Snapshot, s.cfg.PriorityLevels),
+		Suspended: make([]task.Snapshot, len(s.suspendedQueue)),
+		Waiting:   make([][]task.Snapshot, len(s.waitingQueues.queues)),
 	}
-	if s.currentTask != nil {
-		dump.CurrentTask = s.currentTask.Copy()
+	for i, c := range s.cores {
+		if t := c.running(); t != nil {
+			snap := t.Snapshot()
+			res.Running[i] = &snap
+		}
 	}
 
-	for i := range s.readyQueues {
-		dump.ReadyQueues[i] = make([]task.Task, len(s.readyQueues[i]))
-		for j, t := range s.readyQueues[i] {
-			dump.ReadyQueues[i][j] = *t.Copy()
+	for _, q := range s.readyQueues {
+		for i, queue := range q.queues {
+			for _, t := range queue {
+				res.Ready[i] = append(res.Ready[i], t.Snapshot())
+			}
 		}
 	}
 	for i, t := range s.suspendedQueue {
-		dump.SuspendedQueue[i] = *t.Copy()
+		res.Suspended[i] = t.Snapshot()
 	}
-	for i := range s.waitingQueues {
-		dump.WaitingQueues[i] = make([]task.Task, len(s.waitingQueues[i]))
-		for j, t := range s.waitingQueues[i] {
-			dump.WaitingQueues[i][j] = *t.Copy()
+	for i, queue := range s.waitingQueues.queues {
+		res.Waiting[i] = make([]task.Snapshot, len(queue))
+		for j, t := range queue {
+			res.Waiting[i][j] = t.Snapshot()
 		}
 	}
+	return res
+}
+
+// emit numbers e and queues it for the sink, so events are queued in Seq
+// order. When the buffer is full it waits or drops e, as DumpOverflow says.
+// The sink is not driven by the clock, so waiting does not let a virtual
+// clock move on.
+func (s *Scheduler) emit(e trace.Event) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.seq++
+	e.Seq = s.seq
+	if s.eventsClosed {
+		s.dropDump(e, "scheduler stopped")
+		return
+	}
+	if s.dumpBlocks {
+		s.events <- e
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		s.dropDump(e, "dump buffer full")
+	}
+}
 
-	s.Dumps = append(s.Dumps, dump)
+func (s *Scheduler) dropDump(e trace.Event, reason string) {
+	n := s.dropped.Add(1)
+	s.cfg.Logger.Warn("dump dropped", "seq", e.Seq, "task_id", e.TaskID, "reason", reason, "dropped", n)
+}
+
+func (s *Scheduler) closeDumps() {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.eventsClosed = true
+	close(s.events)
+}
+
+func (s *Scheduler) deliverDumps() {
+	defer close(s.sinkDone)
+	for e := range s.events {
+		s.cfg.DumpSink.Dump(e)
+	}
+	if c, ok := s.cfg.DumpSink.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			s.cfg.Logger.Error("dump sink close failed", "error", err)
+		}
+	}
 }
 
-func (s *Scheduler) sortDumpsByTimestamp() {
-	sort.Sort(byTimestamp(s.Dumps))
+// DroppedDumps returns the number of events the sink did not get because the
+// dump buffer was full under DumpDrop or the scheduler had stopped.
+func (s *Scheduler) DroppedDumps() uint64 {
+	return s.dropped.Load()
 }
@@ -1,33 +1,53 @@
 package scheduler
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"scheduler/internal/clock"
 	"scheduler/internal/generator"
 	"scheduler/internal/task"
+	"scheduler/internal/trace"
 	"scheduler/internal/utils"
+	"scheduler/internal/validate"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestScheduler_Requirements(t *testing.T) {
+	seed, err := generator.TestSeed()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("generated with %s=%d", generator.SeedEnv, seed)
+		}
+	})
+
 	testsAmount := 100
 	var wg sync.WaitGroup
 	wg.Add(testsAmount)
 	for i := 0; i < testsAmount; i++ {
+		g, err := generator.New(seed + int64(i))
+		assert.NoError(t, err)
 		go func() {
 			defer wg.Done()
 			tasksAmount := 5
 			for i := 0; i < tasksAmount; i++ {
-				s := New()
+				s, err := New(WithClock(clock.NewVirtual(time.Time{})), withQueueDumps())
+				assert.NoError(t, err)
 				tasks := make([]*task.Task, 0, tasksAmount)
 
 				for i := 0; i < tasksAmount; i++ {
-					task, err := generator.GenerateTask()
+					a, err := g.Next()
 					assert.NoError(t, err)
-					s.AddNewTask(task)
-					tasks = append(tasks, task)
+					s.AddNewTask(a.Task)
+					tasks = append(tasks, a.Task)
 				}
 
 				tasksMsg := ""
@@ -35,24 +55,13 @@ func TestScheduler_Requirements(t *testing.T) {
 					tasksMsg += fmt.Sprintf("task | ID=%d | type=%s | priority=%d\n", v.ID, v.GetType(), v.GetPriority())
 				}
 
-				s.Run()
+				s.Run(context.Background())
 				<-s.StopChan
 
-				for _, v := range tasks {
-					err := checkFirstInstanceIsSuspended(v, s.Dumps)
-					assert.NoError(t, err, tasksMsg)
-					err = checkStatesStoryCorrectness(v, s.Dumps)
-					assert.NoError(t, err, tasksMsg)
-				}
-
-				err := checkReadyQueuesLimit(s.Dumps)
-				assert.NoError(t, err)
-
-				err = checkRunningTasksLimit(s.Dumps)
-				assert.NoError(t, err)
+				invariants := append(validate.Default(DefaultMaxReadyTasks), validate.ReadyPosition)
+				assert.Empty(t, validate.Trace(dumps(s), invariants...), tasksMsg)
 
-				err = checkTaskPositionInReadyQueue(s.Dumps)
-				assert.NoError(t, err)
+				assert.Zero(t, s.DroppedDumps())
 			}
 		}()
 	}
@@ -60,7 +69,8 @@ func TestScheduler_Requirements(t *testing.T) {
 }
 
 func TestScheduler_TaskExecutionOrder(t *testing.T) {
-	s := New()
+	s, err := New()
+	assert.NoError(t, err)
 
 	// Create tasks with specified priorities
 	taskP3, err := task.New(task.Basic, task.P3, task.Suspended)
@@ -82,18 +92,19 @@ func TestScheduler_TaskExecutionOrder(t *testing.T) {
 	s.AddNewTask(taskP0)
 
 	// Run the scheduler
-	s.Run()
+	s.Run(context.Background())
 	<-s.StopChan
 
 	// Check the order of task execution
 	expectedOrder := []int{taskP3.ID, taskP2.ID, taskP1_1.ID, taskP1_2.ID, taskP0.ID}
-	actualOrder := getTaskExecutionOrder(s.Dumps)
+	actualOrder := getTaskExecutionOrder(dumps(s))
 
 	assert.Equal(t, expectedOrder, actualOrder, "Tasks were not executed in the correct order")
 }
 
 func TestScheduler_TaskExecutionOrderWithWaiting(t *testing.T) {
-	s := New()
+	s, err := New()
+	assert.NoError(t, err)
 
 	// Create tasks with specified priorities and types
 	task1, err := task.New(task.Basic, task.P3, task.Suspended)
@@ -109,213 +120,542 @@ func TestScheduler_TaskExecutionOrderWithWaiting(t *testing.T) {
 	s.AddNewTask(task3)
 
 	// Run the scheduler
-	s.Run()
+	s.Run(context.Background())
 	<-s.StopChan
 
 	// Check the order of task execution
 	expectedOrder := []int{task1.ID, task2.ID, task3.ID, task2.ID, task3.ID}
-	actualOrder := getTaskExecutionOrder(s.Dumps)
+	actualOrder := getTaskExecutionOrder(dumps(s))
 
 	assert.Equal(t, expectedOrder, actualOrder, "Tasks were not executed in the correct order")
 }
 
-func checkFirstInstanceIsSuspended(t *task.Task, dumps []scheduler_dump) error {
-	for _, dump := range dumps {
-		for _, v := range dump.SuspendedQueue {
-			if v.ID == t.ID {
-				if v.GetState() != task.Suspended {
-					return fmt.Errorf("task first met in suspended but with wrong state | ID=%d | state=%s\n", v.ID, v.GetState())
-				}
-				return nil
-			}
-		}
-		for _, queue := range dump.ReadyQueues {
-			for _, v := range queue {
-				if v.ID == t.ID {
-					return fmt.Errorf("task first met in ready not suspended | ID=%d | state=%s\n", v.ID, v.GetState())
-				}
-			}
-		}
-		for _, queue := range dump.WaitingQueues {
-			for _, v := range queue {
-				if v.ID == t.ID {
-					return fmt.Errorf("task first met in waiting not suspended | ID=%d | state=%s\n", v.ID, v.GetState())
-				}
-			}
+func TestScheduler_TaskBodies(t *testing.T) {
+	s, err := New(WithTickDuration(10 * time.Millisecond))
+	assert.NoError(t, err)
+
+	var got task.EventMask
+	waiter, err := task.NewWithFunc(task.Extended, task.P2, func(ctx task.Context) error {
+		ev, err := ctx.WaitEvent(0b10)
+		got = ev
+		return err
+	})
+	assert.NoError(t, err)
+	setter, err := task.NewWithFunc(task.Basic, task.P1, func(ctx task.Context) error {
+		if err := ctx.SetEvent(waiter, 0b10); err != nil {
+			return err
 		}
+		<-ctx.Done()
+		ctx.Yield()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	s.AddNewTask(waiter)
+	s.AddNewTask(setter)
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	expectedOrder := []int{waiter.ID, setter.ID, waiter.ID, setter.ID}
+	assert.Equal(t, expectedOrder, getTaskExecutionOrder(dumps(s)))
+	assert.Equal(t, task.EventMask(0b10), got)
+	assert.NoError(t, waiter.Err())
+	assert.NoError(t, setter.Err())
+	assert.Equal(t, task.Suspended, waiter.GetState())
+	assert.Equal(t, task.Suspended, setter.GetState())
+}
+
+func TestScheduler_EventMasks(t *testing.T) {
+	s, err := New(WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	var got task.EventMask
+	waiter, err := task.NewWithFunc(task.Extended, task.P2, func(ctx task.Context) error {
+		ev, err := ctx.WaitEvent(0b100)
+		got = ev
+		return err
+	})
+	assert.NoError(t, err)
+	// The built-in body sets DefaultEvent, which the waiter does not wait for.
+	basic, err := task.New(task.Basic, task.P1, task.Suspended, task.WithSleepTime(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	s.AddNewTask(waiter)
+	s.AddNewTask(basic)
+	_, err = s.GetEvent(waiter.ID)
+	assert.ErrorIs(t, err, ErrTaskSuspended)
+
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		return queuedState(s, basic.ID) == "" && queuedState(s, waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, s.SetEvent(waiter.ID, 0b001))
+	ev, err := s.GetEvent(waiter.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, task.EventMask(0b001), ev)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, task.Waiting, queuedState(s, waiter.ID))
+
+	assert.NoError(t, s.SetEvent(waiter.ID, 0b100))
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, task.EventMask(0b100), got)
+
+	assert.ErrorIs(t, s.SetEvent(waiter.ID, 0b100), ErrTaskSuspended)
+	assert.ErrorIs(t, s.SetEvent(basic.ID, 0b100), task.ErrNotExtended)
+	assert.ErrorIs(t, s.SetEvent(-1, 0b100), ErrUnknownTask)
+	_, err = s.GetEvent(-1)
+	assert.ErrorIs(t, err, ErrUnknownTask)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
+func TestScheduler_ShutdownWhileStarting(t *testing.T) {
+	s, err := New(WithTickDuration(time.Millisecond))
+	assert.NoError(t, err)
+	go s.Run(context.Background())
+	s.Shutdown(context.Background())
+	<-s.StopChan
+}
+
+func TestScheduler_Shutdown(t *testing.T) {
+	s, err := New(withQueueDumps())
+	assert.NoError(t, err)
+
+	taskP1, err := task.New(task.Basic, task.P1, task.Suspended)
+	assert.NoError(t, err)
+	taskP0, err := task.New(task.Basic, task.P0, task.Suspended)
+	assert.NoError(t, err)
+
+	s.AddNewTask(taskP1)
+	s.AddNewTask(taskP0)
+
+	s.Run(context.Background())
+	time.Sleep(task.DefaultTaskSleepTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	assert.ErrorIs(t, err, ErrTasksLeft)
+	assert.True(t, utils.IsChannelClosed(s.StopChan))
+
+	// The interrupted task must be back at the head of its ready queue.
+	events := dumps(s)
+	last := events[len(events)-1]
+	assert.Equal(t, taskP1.ID, last.TaskID)
+	assert.Equal(t, task.Running, last.From)
+	assert.Equal(t, task.Ready, last.To)
+	assert.Nil(t, last.Queues.Running[0])
+	assert.Equal(t, taskP1.ID, last.Queues.Ready[task.P1][0].ID)
+	assert.Equal(t, task.Ready, taskP1.GetState())
+}
+
+func TestScheduler_RunContextCancel(t *testing.T) {
+	s, err := New()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Run(ctx)
+	cancel()
+
+	select {
+	case <-s.StopChan:
+	case <-time.After(DefaultTickDuration * 5):
+		t.Fatal("scheduler did not stop after context cancellation")
 	}
-	return nil
+	assert.NoError(t, s.Shutdown(context.Background()))
 }
 
-func checkStatesStoryCorrectness(t *task.Task, dumps []scheduler_dump) error {
-	var prevState task.TaskState
+func TestScheduler_Hold(t *testing.T) {
+	s, err := New(WithTickDuration(time.Millisecond), WithIdleExit(2))
+	assert.NoError(t, err)
 
-	for _, dump := range dumps {
-		dumpTask := findTaskInDump(t, dump)
-		if dumpTask.ID == -1 {
-			continue // Task not found in this dump
-		}
+	release := s.Hold()
+	s.Run(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, utils.IsChannelClosed(s.StopChan), "scheduler stopped while held")
 
-		currentState := dumpTask.GetState()
-		if currentState == prevState {
-			continue
+	late, err := task.New(task.Basic, task.P1, task.Suspended, task.WithProgressLimit(2), task.WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(late))
+	release()
+	release()
+
+	select {
+	case <-s.StopChan:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after release")
+	}
+	assert.Equal(t, task.Suspended, late.GetState())
+	assert.Equal(t, 2, late.GetProgress())
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
+
+func TestScheduler_VirtualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func() []string {
+		s, err := New(WithClock(clock.NewVirtual(start)))
+		assert.NoError(t, err)
+		ext, err := task.New(task.Extended, task.P1, task.Suspended)
+		assert.NoError(t, err)
+		for _, p := range []task.TaskPriority{task.P0, task.P2, task.P3} {
+			bsc, err := task.New(task.Basic, p, task.Suspended)
+			assert.NoError(t, err)
+			assert.NoError(t, s.AddNewTask(bsc))
 		}
+		assert.NoError(t, s.AddNewTask(ext))
 
-		if prevState != "" {
-			switch prevState {
-			case task.Suspended:
-				if currentState != task.Ready {
-					return fmt.Errorf("invalid state transition | ID=%d | from=%s to=%s\n", dumpTask.ID, prevState, currentState)
-				}
-			case task.Ready:
-				if currentState != task.Running {
-					return fmt.Errorf("invalid state transition | ID=%d | from=%s to=%s\n", dumpTask.ID, prevState, currentState)
-				}
-			case task.Running:
-				if currentState != task.Waiting && currentState != task.Ready && currentState != task.Suspended {
-					return fmt.Errorf("invalid state transition | ID=%d | from=%s to=%s\n", dumpTask.ID, prevState, currentState)
-				}
-			case task.Waiting:
-				if currentState != task.Ready {
-					return fmt.Errorf("invalid state transition | ID=%d | from=%s to=%s\n", dumpTask.ID, prevState, currentState)
-				}
-			}
+		wall := time.Now()
+		s.Run(context.Background())
+		<-s.StopChan
+		assert.Less(t, time.Since(wall), time.Second)
+
+		res := make([]string, 0)
+		for _, e := range dumps(s) {
+			res = append(res, fmt.Sprintf("%d %v %d %s->%s %d", e.Seq, e.Time.Sub(start), e.TaskID-ext.ID, e.From, e.To, e.Core))
 		}
+		return res
+	}
+
+	first := run()
+	assert.Equal(t, first, run())
+	// The extended task waits halfway, two work units after it started at 5s.
+	assert.Contains(t, first, "14 6s 0 running->waiting 0")
+}
+
+func TestScheduler_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})), WithLogger(logger))
+	assert.NoError(t, err)
+	tk, err := task.New(task.Basic, task.P1, task.Suspended, task.WithProgressLimit(2))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(tk))
+	s.Run(context.Background())
+	<-s.StopChan
+
+	messages := make(map[string][]map[string]any)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var msg map[string]any
+		assert.NoError(t, dec.Decode(&msg))
+		messages[msg["msg"].(string)] = append(messages[msg["msg"].(string)], msg)
+	}
+	id := float64(tk.ID)
+	assert.Equal(t, id, messages["task added"][0]["task_id"])
+	assert.Len(t, messages["task transition"], 4)
+	for _, msg := range messages["task transition"] {
+		assert.Equal(t, id, msg["task_id"])
+	}
+	assert.Equal(t, "ready", messages["task transition"][2]["from"])
+	assert.Equal(t, "running", messages["task transition"][2]["state"])
+	assert.Equal(t, float64(0), messages["task transition"][2]["core"])
+	assert.Len(t, messages["task setting event"], 1)
+	assert.Equal(t, float64(task.DefaultEvent), messages["task setting event"][0]["event"])
+	assert.Len(t, messages["task progress"], 2)
+	assert.Equal(t, float64(2), messages["task progress"][1]["progress"])
+	assert.Equal(t, float64(2), messages["task progress"][1]["progress_limit"])
+	assert.Len(t, messages["core stopped"], 1)
+}
 
-		prevState = currentState
+// dumps returns the events kept by the default ring sink.
+func dumps(s *Scheduler) []trace.Event {
+	sink := s.cfg.DumpSink
+	if q, ok := sink.(queueSink); ok {
+		sink = q.DumpSink
 	}
+	return sink.(*RingSink).Events()
+}
 
-	return nil
+// withQueueDumps keeps the events with a copy of the queues, for tests that
+// check the queues.
+func withQueueDumps() Option {
+	return WithDumpSink(IncludeQueues(NewRingSink(DefaultRingSize)))
 }
 
-func checkReadyQueuesLimit(dumps []scheduler_dump) error {
-	for _, dump := range dumps {
-		totalReadyTasks := utils.LMDA(dump.ReadyQueues)
-		if totalReadyTasks > MaxReadyTasks {
-			return fmt.Errorf("number of tasks in readyQueues exceeds limit | Timestamp=%s | Count=%d\n", dump.Timestamp, totalReadyTasks)
+// queuedState returns the state of a task as the queues of s show it, taking
+// the queue locks, so tests can poll it while the scheduler runs. A task in no
+// queue is done and gets the empty state.
+func queuedState(s *Scheduler, id int) task.TaskState {
+	q := s.Queues()
+	for _, r := range q.Running {
+		if r != nil && r.ID == id {
+			return task.Running
 		}
 	}
-	return nil
+	for _, v := range q.Suspended {
+		if v.ID == id {
+			return task.Suspended
+		}
+	}
+	for _, queues := range []struct {
+		state  task.TaskState
+		levels [][]task.Snapshot
+	}{{task.Ready, q.Ready}, {task.Waiting, q.Waiting}} {
+		for _, level := range queues.levels {
+			for _, v := range level {
+				if v.ID == id {
+					return queues.state
+				}
+			}
+		}
+	}
+	return ""
 }
 
-func getTaskExecutionOrder(dumps []scheduler_dump) []int {
+func getTaskExecutionOrder(dumps []trace.Event) []int {
 	executionOrder := []int{}
 	prevTaskID := -1
 
 	for _, dump := range dumps {
-		if dump.CurrentTask != nil && dump.CurrentTask.ID != prevTaskID {
-			executionOrder = append(executionOrder, dump.CurrentTask.ID)
-			prevTaskID = dump.CurrentTask.ID
+		if dump.To == task.Running && dump.TaskID != prevTaskID {
+			executionOrder = append(executionOrder, dump.TaskID)
+			prevTaskID = dump.TaskID
 		}
 	}
 
 	return executionOrder
 }
 
-func findTaskInDump(t *task.Task, dump scheduler_dump) task.Task {
-	if dump.CurrentTask != nil && dump.CurrentTask.ID == t.ID {
-		return *dump.CurrentTask
+func TestScheduler_MaxReadyTasks(t *testing.T) {
+	s, err := New(WithMaxReadyTasks(1), WithReadyLimit(1), WithTickDuration(10*time.Millisecond), WithRunForever(), withQueueDumps())
+	assert.NoError(t, err)
+	waiters := make([]*task.Task, 3)
+	for i := range waiters {
+		waiters[i], err = task.New(task.Extended, task.P1, task.Suspended, task.WithProgressLimit(2), task.WithSleepTime(time.Millisecond))
+		assert.NoError(t, err)
+		assert.NoError(t, s.AddNewTask(waiters[i]))
 	}
-	for _, v := range dump.SuspendedQueue {
-		if v.ID == t.ID {
-			return v
+
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		for _, w := range waiters {
+			if queuedState(s, w.ID) != task.Waiting {
+				return false
+			}
 		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	// The ready queues take one of the released tasks; the others wait for
+	// room.
+	for _, w := range waiters {
+		assert.NoError(t, s.SetEvent(w.ID, task.DefaultEvent))
 	}
-	for _, v := range dump.ReadyQueues[t.GetPriority()] {
-		if v.ID == t.ID {
-			return v
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Eventually(t, func() bool {
+		return s.leftTasks() == nil && len(slices.DeleteFunc(s.Queues().Running, func(r *task.Snapshot) bool { return r == nil })) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(ctx))
+
+	released := 0
+	for _, e := range dumps(s) {
+		if e.From == task.Waiting && e.To == task.Ready {
+			released++
 		}
 	}
-	for _, v := range dump.WaitingQueues[t.GetPriority()] {
-		if v.ID == t.ID {
-			return v
-		}
+	assert.Equal(t, 3, released)
+	assert.Empty(t, validate.Trace(dumps(s), validate.ReadyLimit(1)))
+}
+
+func TestScheduler_SetTaskPriority(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithJournal(&journal))
+	assert.NoError(t, err)
+
+	first := newPolicyTask(t, task.P1)
+	second := newPolicyTask(t, task.P1)
+	suspended := newPolicyTask(t, task.P1)
+	for _, v := range []*task.Task{first, second, suspended} {
+		assert.NoError(t, s.AddNewTask(v))
 	}
-	return task.Task{ID: -1}
+	s.appendToReady(s.popNextFromSuspended())
+	s.appendToReady(s.popNextFromSuspended())
+
+	assert.NoError(t, s.SetTaskPriority(first.ID, task.P2))
+	assert.NoError(t, s.SetTaskPriority(suspended.ID, task.P3))
+	assert.ErrorIs(t, s.SetTaskPriority(first.ID, task.TaskPriority(DefaultPriorityLevels)), ErrInvalidPriority)
+	assert.ErrorIs(t, s.SetTaskPriority(-1, task.P0), ErrUnknownTask)
+
+	q := s.Queues()
+	assert.Equal(t, first.ID, q.Ready[task.P2][0].ID)
+	assert.Equal(t, task.TaskPriority(task.P2), q.Ready[task.P2][0].Priority)
+	assert.Equal(t, second.ID, q.Ready[task.P1][0].ID)
+	assert.Equal(t, task.TaskPriority(task.P3), q.Suspended[0].Priority)
+	assert.Equal(t, []*task.Task{first, second, suspended}, s.Tasks())
+
+	replayed, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, s.leftTasks(), replayed.leftTasks())
+	assert.Equal(t, q, replayed.Queues())
 }
 
-func checkRunningTasksLimit(dumps []scheduler_dump) error {
-	for _, dump := range dumps {
-		runningCount := 0
+func TestScheduler_SetTaskPriorityPreempts(t *testing.T) {
+	s, err := New(WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
 
-		if dump.CurrentTask != nil && dump.CurrentTask.GetState() == task.Running {
-			runningCount++
-		}
+	stop := make(chan struct{})
+	spin := newSpinTask(t, task.P2, stop)
+	other := newPolicyTask(t, task.P1)
+	s.AddNewTask(spin)
+	s.AddNewTask(other)
+
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		return queuedState(s, spin.ID) == task.Running && queuedState(s, other.ID) == task.Ready
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, s.SetTaskPriority(spin.ID, task.P0))
+	assert.Eventually(t, func() bool {
+		return queuedState(s, other.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	close(stop)
+
+	assert.Eventually(t, func() bool {
+		return queuedState(s, spin.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(context.Background()))
+}
 
-		for _, queue := range dump.ReadyQueues {
-			for _, t := range queue {
-				if t.GetState() == task.Running {
-					runningCount++
-				}
-			}
-		}
+func TestScheduler_CancelTask(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithJournal(&journal))
+	assert.NoError(t, err)
 
-		for _, t := range dump.SuspendedQueue {
-			if t.GetState() == task.Running {
-				runningCount++
-			}
-		}
+	ready := newPolicyTask(t, task.P1)
+	running := newPolicyTask(t, task.P2)
+	suspended := newPolicyTask(t, task.P1)
+	for _, v := range []*task.Task{ready, running, suspended} {
+		assert.NoError(t, s.AddNewTask(v))
+	}
+	s.appendToReady(s.popNextFromSuspended())
+	s.appendToReady(s.popNextFromSuspended())
+	assert.Equal(t, running, s.dispatchNext(s.cores[0]))
+
+	assert.NoError(t, s.CancelTask(ready.ID))
+	assert.NoError(t, s.CancelTask(suspended.ID))
+	assert.ErrorIs(t, s.CancelTask(ready.ID), ErrTaskNotQueued)
+	assert.ErrorIs(t, s.CancelTask(running.ID), ErrTaskNotQueued)
+	assert.ErrorIs(t, s.CancelTask(-1), ErrUnknownTask)
+	assert.Equal(t, task.Suspended, ready.GetState())
+	assert.NoError(t, s.leftTasks())
+
+	replayed, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%v | ready=[%d] | suspended=[] | waiting=[]", ErrTasksLeft, running.ID), replayed.leftTasks().Error())
+}
 
-		for _, queue := range dump.WaitingQueues {
-			for _, t := range queue {
-				if t.GetState() == task.Running {
-					runningCount++
-				}
-			}
-		}
+func TestScheduler_CancelWaitingTask(t *testing.T) {
+	s, err := New(WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
 
-		if runningCount > 1 {
-			return fmt.Errorf("more than one task with state Running in dump | Timestamp=%s | RunningCount=%d\n", dump.Timestamp, runningCount)
-		}
+	exited := make(chan struct{})
+	waiter, err := task.NewWithFunc(task.Extended, task.P1, func(ctx task.Context) error {
+		defer close(exited)
+		_, err := ctx.WaitEvent(0b10)
+		return err
+	})
+	assert.NoError(t, err)
+	s.AddNewTask(waiter)
+
+	s.Run(context.Background())
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, s.CancelTask(waiter.ID))
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("cancelled task body did not exit")
 	}
-	return nil
+	assert.ErrorIs(t, s.SetEvent(waiter.ID, 0b10), ErrTaskSuspended)
+	assert.NoError(t, s.Shutdown(context.Background()))
+
+	last := dumps(s)[len(dumps(s))-1]
+	assert.Equal(t, waiter.ID, last.TaskID)
+	assert.Equal(t, task.Waiting, last.From)
+	assert.Equal(t, task.Suspended, last.To)
 }
 
-func checkTaskPositionInReadyQueue(dumps []scheduler_dump) error {
-	for _, dump := range dumps {
-		var id int
-		var priority task.TaskPriority
-		var queue []task.Task
-
-		if _, err := fmt.Sscanf(dump.Name, "task waiting -> ready | ID=%d", &id); err == nil {
-			priority = findTaskPriorityByID(dump, id)
-			if priority == -1 {
-				return fmt.Errorf("failed to find task by its priority | ID=%d: %s", id, dump.Name)
-			}
-			queue = dump.ReadyQueues[priority]
-			if len(queue) == 0 || queue[0].ID != id {
-				return fmt.Errorf("task with ID=%d is not at the beginning of its priority readyQueue in dump: %s", id, dump.Name)
-			}
-		} else if _, err := fmt.Sscanf(dump.Name, "task running -> ready | ID=%d", &id); err == nil {
-			priority = findTaskPriorityByID(dump, id)
-			if priority == -1 {
-				return fmt.Errorf("failed to find task by its priority | ID=%d: %s", id, dump.Name)
-			}
-			queue = dump.ReadyQueues[priority]
-			if len(queue) == 0 || queue[0].ID != id {
-				return fmt.Errorf("task with ID=%d is not at the beginning of its priority readyQueue in dump: %s", id, dump.Name)
-			}
-		} else if _, err := fmt.Sscanf(dump.Name, "task suspended -> ready | ID=%d", &id); err == nil {
-			priority = findTaskPriorityByID(dump, id)
-			if priority == -1 {
-				return nil
-			}
-			queue = dump.ReadyQueues[priority]
-			if len(queue) == 0 || queue[len(queue)-1].ID != id {
-				return fmt.Errorf("task with ID=%d is not at the end of its priority readyQueue in dump: %s", id, dump.Name)
-			}
+func TestScheduler_ActivateTask(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})), WithJournal(&journal), withQueueDumps())
+	assert.NoError(t, err)
+
+	tsk := newPolicyTask(t, task.P1, task.WithProgressLimit(2), task.WithMaxActivations(2))
+	assert.NoError(t, s.AddNewTask(tsk))
+	assert.ErrorIs(t, s.ActivateTask(-1), ErrUnknownTask)
+	assert.NoError(t, s.ActivateTask(tsk.ID))
+	assert.ErrorIs(t, s.ActivateTask(tsk.ID), task.ErrActivationLimit)
+	assert.Equal(t, 1, tsk.GetActivations())
+
+	s.Run(context.Background())
+	<-s.StopChan
+
+	// The queued activation started the task over once it was done.
+	runs := 0
+	for _, e := range dumps(s) {
+		if e.From == task.Running && e.To == task.Suspended {
+			runs++
 		}
 	}
-	return nil
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, 2, tsk.GetProgress())
+	assert.Zero(t, tsk.GetActivations())
+	assert.Empty(t, validate.Trace(dumps(s), validate.Default(DefaultMaxReadyTasks)...))
+
+	// A done task starts over from the suspended queue.
+	assert.NoError(t, s.ActivateTask(tsk.ID))
+	assert.Zero(t, tsk.GetProgress())
+	assert.Equal(t, []task.Snapshot{tsk.Snapshot()}, s.Queues().Suspended)
+
+	replayed, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%v | ready=[] | suspended=[%d] | waiting=[]", ErrTasksLeft, tsk.ID), replayed.leftTasks().Error())
 }
 
-func findTaskPriorityByID(dump scheduler_dump, id int) task.TaskPriority {
-	for priority, queue := range dump.ReadyQueues {
-		for _, t := range queue {
-			if t.ID == id {
-				return task.TaskPriority(priority)
-			}
-		}
+func TestScheduler_ActivateCancelledTask(t *testing.T) {
+	s, err := New(WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	runs := make(chan struct{}, 2)
+	waiter, err := task.NewWithFunc(task.Extended, task.P1, func(ctx task.Context) error {
+		runs <- struct{}{}
+		_, err := ctx.WaitEvent(0b10)
+		return err
+	})
+	assert.NoError(t, err)
+	s.AddNewTask(waiter)
+
+	s.Run(context.Background())
+	<-runs
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.SetEvent(waiter.ID, 0b1))
+	assert.NoError(t, s.CancelTask(waiter.ID))
+
+	// The body starts over with the events cleared.
+	assert.NoError(t, s.ActivateTask(waiter.ID))
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("activated task did not run again")
 	}
-	return -1
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == task.Waiting
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, waiter.GetEvent())
+	assert.NoError(t, s.SetEvent(waiter.ID, 0b10))
+	assert.Eventually(t, func() bool {
+		return queuedState(s, waiter.ID) == ""
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Shutdown(context.Background()))
 }
@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"scheduler/internal/task"
+	"slices"
+)
+
+type snapshotTask struct {
+	Partition int           `json:"partition,omitempty"`
+	Task      task.Snapshot `json:"task"`
+}
+
+// snapshot holds the queues in queue order. Done holds the tasks in no queue
+// by ID. Seq is the last journal record the snapshot includes.
+type snapshot struct {
+	Seq        uint64         `json:"seq"`
+	NextTaskID int            `json:"next_task_id"`
+	Running    []snapshotTask `json:"running"`
+	Ready      []snapshotTask `json:"ready"`
+	Suspended  []snapshotTask `json:"suspended"`
+	Waiting    []snapshotTask `json:"waiting"`
+	Done       []snapshotTask `json:"done"`
+}
+
+// Snapshot writes all tasks added to the scheduler to w.
+func (s *Scheduler) Snapshot(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s.snapshot())
+}
+
+func (s *Scheduler) snapshot() snapshot {
+	s.rMu.Lock()
+	defer s.rMu.Unlock()
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+	s.wMu.Lock()
+	defer s.wMu.Unlock()
+
+	snap := snapshot{Seq: s.journal.last(), NextTaskID: task.NextID()}
+	queued := make(map[int]bool)
+	each := func(dst *[]snapshotTask) func(t *task.Task) bool {
+		return func(t *task.Task) bool {
+			queued[t.ID] = true
+			*dst = append(*dst, snapshotTask{Partition: s.partitionOf(t), Task: t.Snapshot()})
+			return true
+		}
+	}
+	for _, c := range s.cores {
+		if t := c.running(); t != nil {
+			each(&snap.Running)(t)
+		}
+	}
+	for _, q := range s.readyQueues {
+		q.Each(each(&snap.Ready))
+	}
+	for _, t := range s.suspendedQueue {
+		each(&snap.Suspended)(t)
+	}
+	s.waitingQueues.Each(each(&snap.Waiting))
+	for _, t := range s.Tasks() {
+		if !queued[t.ID] {
+			each(&snap.Done)(t)
+		}
+	}
+	return snap
+}
+
+// Restore creates a scheduler with the tasks and queues of a snapshot.
+// Running tasks go back to the head of their ready queues. Restored tasks run
+// the built-in body, so tasks with custom bodies or event points are left out
+// when done, and fail with task.ErrNotRestorable when still queued or running.
+func Restore(r io.Reader, opts ...Option) (*Scheduler, error) {
+	return Recover(r, nil, opts...)
+}
+
+// Recover restores a snapshot and replays the journal records written after
+// it. Either reader may be nil. A torn last record is ignored.
+func Recover(snap, journal io.Reader, opts ...Option) (*Scheduler, error) {
+	s, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[int]*task.Task)
+	// lost tells whether a task that cannot be restored is queued.
+	lost := make(map[int]bool)
+	var last uint64
+
+	if snap != nil {
+		var sn snapshot
+		if err := json.NewDecoder(snap).Decode(&sn); err != nil {
+			return nil, err
+		}
+		task.ReserveIDs(sn.NextTaskID)
+		last = sn.Seq
+		queues := []struct {
+			from, to task.TaskState
+			tasks    []snapshotTask
+		}{
+			{task.Ready, task.Running, sn.Running},
+			{task.Suspended, task.Ready, sn.Ready},
+			{"", task.Suspended, sn.Suspended},
+			{task.Running, task.Waiting, sn.Waiting},
+			{task.Running, task.Suspended, sn.Done},
+		}
+		for _, q := range queues {
+			for _, v := range q.tasks {
+				rec := journalRecord{From: q.from, To: q.to, Partition: v.Partition, Task: v.Task}
+				if err := s.place(running, lost, rec); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if journal != nil {
+		dec := json.NewDecoder(journal)
+		for {
+			var rec journalRecord
+			err := dec.Decode(&rec)
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if rec.Seq <= last {
+				continue
+			}
+			last = rec.Seq
+			if err := s.place(running, lost, rec); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, queued := range lost {
+		if queued {
+			return nil, task.ErrNotRestorable
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(running)) {
+		t := running[id]
+		t.SetState(task.Ready)
+		s.readyQueueOf(t).PushFront(t)
+	}
+	s.journal.setLast(last)
+	return s, nil
+}
+
+// place moves the task of rec to the destination of the transition. Tasks
+// that cannot be restored are only tracked in lost. The scheduler is not
+// running yet, so no locks are taken.
+func (s *Scheduler) place(running map[int]*task.Task, lost map[int]bool, rec journalRecord) error {
+	t, err := task.Restore(rec.Task)
+	if errors.Is(err, task.ErrNotRestorable) {
+		if !rec.Update {
+			lost[rec.Task.ID] = queuedAfter(rec)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.CheckTask(t.GetPriority(), t.GetAffinity()); err != nil {
+		return err
+	}
+	if rec.Partition < 0 || rec.Partition >= s.cfg.Cores {
+		return ErrInvalidAffinity
+	}
+	if s.cfg.Partitioned {
+		s.partitions[t.ID] = rec.Partition
+	}
+	if rec.Update {
+		s.update(running, t)
+		return nil
+	}
+	if old, ok := s.tasks[t.ID]; ok {
+		s.unplace(old)
+		delete(running, old.ID)
+	}
+	s.tasks[t.ID] = t
+	s.addResourceUser(t)
+
+	switch rec.To {
+	case task.Suspended:
+		if queuedAfter(rec) {
+			s.suspendedQueue = append(s.suspendedQueue, t)
+		}
+	case task.Ready:
+		pos := Back
+		if rec.Front {
+			pos = Front
+		}
+		s.readyQueueOf(t).Push(t, pos)
+	case task.Running:
+		running[t.ID] = t
+	case task.Waiting:
+		s.waitingQueues.PushBack(t)
+	}
+	return nil
+}
+
+// queuedAfter tells whether the task of rec is queued or running after the
+// transition. A running task becomes suspended when it is done.
+func queuedAfter(rec journalRecord) bool {
+	return rec.To != task.Suspended || rec.From == "" || rec.Activate
+}
+
+// update replaces a task where it is, as SetTaskPriority does.
+func (s *Scheduler) update(running map[int]*task.Task, t *task.Task) {
+	old, ok := s.tasks[t.ID]
+	if !ok {
+		return
+	}
+	s.tasks[t.ID] = t
+	if i := slices.Index(s.suspendedQueue, old); i >= 0 {
+		s.suspendedQueue[i] = t
+	}
+	for i := range s.readyQueues {
+		if s.readyQueues[i].Remove(old) {
+			s.readyQueueOf(t).PushBack(t)
+		}
+	}
+	if s.waitingQueues.Remove(old) {
+		s.waitingQueues.PushBack(t)
+	}
+	if _, ok := running[t.ID]; ok {
+		running[t.ID] = t
+	}
+}
+
+func (s *Scheduler) unplace(t *task.Task) {
+	s.suspendedQueue = slices.DeleteFunc(s.suspendedQueue, func(v *task.Task) bool { return v == t })
+	for i := range s.readyQueues {
+		s.readyQueues[i].Remove(t)
+	}
+	s.waitingQueues.Remove(t)
+}
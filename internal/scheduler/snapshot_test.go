@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_SnapshotRecover(t *testing.T) {
+	var journal, snap bytes.Buffer
+	s, err := New(WithJournal(&journal))
+	assert.NoError(t, err)
+
+	ext, err := task.New(task.Extended, task.P2, task.Suspended)
+	assert.NoError(t, err)
+	first := newPolicyTask(t, task.P1)
+	second := newPolicyTask(t, task.P1)
+	low := newPolicyTask(t, task.P0)
+	for _, v := range []*task.Task{ext, first, second, low} {
+		assert.NoError(t, s.AddNewTask(v))
+	}
+	for i := 0; i < 3; i++ {
+		s.appendToReady(s.popNextFromSuspended())
+	}
+	c := s.cores[0]
+	assert.Equal(t, ext, s.dispatchNext(c))
+	assert.NoError(t, s.Snapshot(&snap))
+
+	assert.True(t, s.appendToWaiting(ext))
+	c.setCurrent(nil)
+	assert.Equal(t, first, s.dispatchNext(c))
+	s.interruptCurrentTask(c, Back)
+	assert.Equal(t, second, s.dispatchNext(c))
+	s.appendToReady(s.popNextFromSuspended())
+
+	// The running task is restored to the head of its ready queue.
+	left := func(ready, suspended, waiting []int) string {
+		return fmt.Sprintf("%v | ready=%v | suspended=%v | waiting=%v", ErrTasksLeft, ready, suspended, waiting)
+	}
+	expected := left([]int{low.ID, second.ID, first.ID}, []int{}, []int{ext.ID})
+
+	restored, err := Recover(bytes.NewReader(snap.Bytes()), bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, restored.leftTasks().Error())
+
+	replayed, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, replayed.leftTasks().Error())
+	assert.Equal(t, task.Ready, replayed.tasks[second.ID].GetState())
+	assert.Equal(t, task.Waiting, replayed.tasks[ext.ID].GetState())
+
+	torn := journal.Bytes()[:journal.Len()-10]
+	replayed, err = Recover(nil, bytes.NewReader(torn))
+	assert.NoError(t, err)
+	assert.Equal(t, left([]int{second.ID, first.ID}, []int{low.ID}, []int{ext.ID}), replayed.leftTasks().Error())
+
+	fromSnapshot, err := Restore(bytes.NewReader(snap.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, left([]int{first.ID, second.ID, ext.ID}, []int{low.ID}, []int{}), fromSnapshot.leftTasks().Error())
+	assert.Greater(t, task.NextID(), low.ID)
+
+	// A recovered scheduler continues the journal.
+	var next bytes.Buffer
+	restored, err = Recover(nil, bytes.NewReader(journal.Bytes()), WithJournal(&next))
+	assert.NoError(t, err)
+	assert.NoError(t, restored.AddNewTask(newPolicyTask(t, task.P0)))
+	var rec journalRecord
+	assert.NoError(t, json.Unmarshal(next.Bytes(), &rec))
+	assert.Equal(t, s.journal.last()+1, rec.Seq)
+}
+
+func TestScheduler_RecoverAfterShutdown(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithJournal(&journal), WithTickDuration(10*time.Millisecond), WithRunForever())
+	assert.NoError(t, err)
+
+	// Tasks with custom bodies cannot be recovered, so these run the
+	// built-in body for longer than the test.
+	for _, p := range []task.TaskPriority{task.P1, task.P3, task.P0, task.P2, task.P1} {
+		assert.NoError(t, s.AddNewTask(newPolicyTask(t, p, task.WithProgressLimit(1000))))
+	}
+
+	s.Run(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	left := s.Shutdown(ctx)
+	assert.ErrorIs(t, left, ErrTasksLeft)
+
+	recovered, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, left.Error(), recovered.leftTasks().Error())
+}
+
+func TestScheduler_RecoverCustomTask(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithJournal(&journal))
+	assert.NoError(t, err)
+	custom, err := task.NewWithFunc(task.Basic, task.P1, func(task.Context) error { return nil })
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(custom))
+
+	_, err = Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.ErrorIs(t, err, task.ErrNotRestorable)
+}
+
+func TestScheduler_RecoverDoneCustomTask(t *testing.T) {
+	var journal bytes.Buffer
+	s, err := New(WithJournal(&journal), WithClock(clock.NewVirtual(time.Time{})))
+	assert.NoError(t, err)
+	custom, err := task.NewWithFunc(task.Basic, task.P1, func(task.Context) error { return nil })
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddNewTask(custom))
+	basic := newPolicyTask(t, task.P1, task.WithProgressLimit(2))
+	assert.NoError(t, s.AddNewTask(basic))
+	s.Run(context.Background())
+	<-s.StopChan
+
+	// Done tasks that cannot be restored are left out.
+	recovered, err := Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.NoError(t, err)
+	_, err = recovered.Task(custom.ID)
+	assert.ErrorIs(t, err, ErrUnknownTask)
+	_, err = recovered.Task(basic.ID)
+	assert.NoError(t, err)
+
+	var snap bytes.Buffer
+	assert.NoError(t, s.Snapshot(&snap))
+	_, err = Restore(&snap)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.ActivateTask(custom.ID))
+	_, err = Recover(nil, bytes.NewReader(journal.Bytes()))
+	assert.ErrorIs(t, err, task.ErrNotRestorable)
+}
+
+func TestScheduler_RestoreDoneTask(t *testing.T) {
+	s, err := New(WithClock(clock.NewVirtual(time.Time{})))
+	assert.NoError(t, err)
+	done := newPolicyTask(t, task.P1, task.WithProgressLimit(2))
+	assert.NoError(t, s.AddNewTask(done))
+	s.Run(context.Background())
+	<-s.StopChan
+
+	var snap bytes.Buffer
+	assert.NoError(t, s.Snapshot(&snap))
+	restored, err := Restore(&snap)
+	assert.NoError(t, err)
+	got, err := restored.Task(done.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.GetProgress())
+	assert.NoError(t, restored.ActivateTask(done.ID))
+	assert.Equal(t, task.Suspended, queuedState(restored, done.ID))
+}
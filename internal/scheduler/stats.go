@@ -0,0 +1,288 @@
+package scheduler
+
+import (
+	"maps"
+	"scheduler/internal/task"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TaskStats are the metrics of one task. Times the task has not reached yet
+// are zero.
+type TaskStats struct {
+	ID          int
+	Priority    task.TaskPriority
+	Added       time.Time
+	FirstRun    time.Time
+	Completed   time.Time
+	Ready       time.Duration
+	Waiting     time.Duration
+	Running     time.Duration
+	Preemptions int
+}
+
+// ResponseTime is the time from being added to running for the first time.
+func (t TaskStats) ResponseTime() time.Duration {
+	if t.FirstRun.IsZero() {
+		return 0
+	}
+	return t.FirstRun.Sub(t.Added)
+}
+
+// CompletionTime is the time from being added to being done.
+func (t TaskStats) CompletionTime() time.Duration {
+	if t.Completed.IsZero() {
+		return 0
+	}
+	return t.Completed.Sub(t.Added)
+}
+
+// Summary sums up durations of tasks.
+type Summary struct {
+	Count int
+	Sum   time.Duration
+}
+
+func (s *Summary) add(d time.Duration) {
+	s.Count++
+	s.Sum += d
+}
+
+func (s Summary) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// PriorityStats are the metrics of the tasks of one priority level.
+type PriorityStats struct {
+	Completed int
+	// Throughput is the number of completed tasks per second.
+	Throughput float64
+}
+
+// Stats are the metrics of all tasks added so far, taken at Time. Elapsed
+// counts from the first task added. The response and completion times only
+// cover tasks that got there; the ready and waiting times cover all tasks.
+type Stats struct {
+	Time           time.Time
+	Elapsed        time.Duration
+	Tasks          []TaskStats
+	Added          int
+	Completed      int
+	Preemptions    int
+	ResponseTime   Summary
+	ReadyTime      Summary
+	WaitingTime    Summary
+	CompletionTime Summary
+	// Utilization is the share of core time spent running tasks.
+	Utilization float64
+	Priorities  []PriorityStats
+}
+
+// RunBuckets are the upper bounds of the run duration histogram.
+var RunBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// Transition is a change of the state of a task. From is empty for new tasks.
+type Transition struct {
+	From, To task.TaskState
+}
+
+// Histogram counts durations by the upper bounds of its buckets. Counts has
+// one more bucket for durations above all bounds.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Sum    time.Duration
+	Count  uint64
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.Bounds, d)
+	h.Counts[i]++
+	h.Sum += d
+	h.Count++
+}
+
+// Counts are the counters of the scheduler since it was created.
+type Counts struct {
+	Transitions map[Transition]uint64
+	// RunDurations are the times tasks ran on a core before leaving it.
+	RunDurations Histogram
+	// IdleTicks are the ticks each core found no task to run.
+	IdleTicks []uint64
+}
+
+// stats collects the metrics from the transitions. Tasks restored from a
+// snapshot count from their first transition on.
+type stats struct {
+	mu          sync.Mutex
+	start       time.Time
+	tasks       map[int]*taskStats
+	preemptions int
+	transitions map[Transition]uint64
+	runs        Histogram
+}
+
+type taskStats struct {
+	TaskStats
+	state task.TaskState
+	since time.Time
+}
+
+func newStats() *stats {
+	return &stats{tasks: make(map[int]*taskStats), transitions: make(map[Transition]uint64), runs: newHistogram(RunBuckets)}
+}
+
+func (st *stats) observe(from, to task.TaskState, t *task.Task, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ts := st.tasks[t.ID]
+	if ts == nil {
+		if st.start.IsZero() {
+			st.start = now
+		}
+		ts = &taskStats{TaskStats: TaskStats{ID: t.ID, Added: now}}
+		st.tasks[t.ID] = ts
+	}
+	if ts.state == task.Running {
+		st.runs.observe(now.Sub(ts.since))
+	}
+	st.transitions[Transition{From: from, To: to}]++
+	ts.accrue(now)
+	ts.Priority = t.GetBasePriority()
+	ts.state = to
+	if to == task.Running && ts.FirstRun.IsZero() {
+		ts.FirstRun = now
+	}
+	if from == task.Running && to == task.Suspended {
+		ts.Completed = now
+	}
+}
+
+// preempt counts a preemption of t through the interrupt channel of its core.
+func (st *stats) preempt(t *task.Task) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.preemptions++
+	if ts := st.tasks[t.ID]; ts != nil {
+		ts.Preemptions++
+	}
+}
+
+// accrue adds the time since the last transition to the current state.
+func (ts *taskStats) accrue(now time.Time) {
+	d := now.Sub(ts.since)
+	switch ts.state {
+	case task.Ready:
+		ts.Ready += d
+	case task.Waiting:
+		ts.Waiting += d
+	case task.Running:
+		ts.Running += d
+	}
+	ts.since = now
+}
+
+func (st *stats) snapshot(now time.Time, cores, levels int) Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	res := Stats{
+		Time:        now,
+		Tasks:       make([]TaskStats, 0, len(st.tasks)),
+		Added:       len(st.tasks),
+		Preemptions: st.preemptions,
+		Priorities:  make([]PriorityStats, levels),
+	}
+	if !st.start.IsZero() {
+		res.Elapsed = now.Sub(st.start)
+	}
+	var running time.Duration
+	for _, id := range slices.Sorted(maps.Keys(st.tasks)) {
+		ts := *st.tasks[id]
+		ts.accrue(now)
+		res.Tasks = append(res.Tasks, ts.TaskStats)
+		running += ts.Running
+		res.ReadyTime.add(ts.Ready)
+		res.WaitingTime.add(ts.Waiting)
+		if !ts.FirstRun.IsZero() {
+			res.ResponseTime.add(ts.ResponseTime())
+		}
+		if !ts.Completed.IsZero() {
+			res.Completed++
+			res.CompletionTime.add(ts.CompletionTime())
+			if int(ts.Priority) < levels {
+				res.Priorities[ts.Priority].Completed++
+			}
+		}
+	}
+	if res.Elapsed > 0 {
+		res.Utilization = float64(running) / float64(time.Duration(cores)*res.Elapsed)
+		for i := range res.Priorities {
+			res.Priorities[i].Throughput = float64(res.Priorities[i].Completed) / res.Elapsed.Seconds()
+		}
+	}
+	return res
+}
+
+func (st *stats) counts() Counts {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	runs := st.runs
+	runs.Counts = slices.Clone(runs.Counts)
+	return Counts{Transitions: maps.Clone(st.transitions), RunDurations: runs}
+}
+
+// Stats returns the metrics of all tasks added so far.
+func (s *Scheduler) Stats() Stats {
+	return s.stats.snapshot(s.cfg.Clock.Now(), s.cfg.Cores, s.cfg.PriorityLevels)
+}
+
+// Counts returns the counters of the scheduler. Unlike Stats, it does not go
+// through the tasks, so it is cheap to call often.
+func (s *Scheduler) Counts() Counts {
+	res := s.stats.counts()
+	res.IdleTicks = make([]uint64, len(s.cores))
+	for i, c := range s.cores {
+		res.IdleTicks[i] = c.totalIdleTicks()
+	}
+	return res
+}
+
+// QueueLengths are the numbers of tasks in the queues. Ready and Waiting are
+// indexed by priority; Ready sums up the partitions.
+type QueueLengths struct {
+	Ready     []int
+	Waiting   []int
+	Suspended int
+}
+
+func (s *Scheduler) QueueLengths() QueueLengths {
+	res := QueueLengths{Ready: make([]int, s.cfg.PriorityLevels), Waiting: make([]int, s.cfg.PriorityLevels)}
+	s.rMu.Lock()
+	for _, q := range s.readyQueues {
+		for p, queue := range q.queues {
+			res.Ready[p] += len(queue)
+		}
+	}
+	s.rMu.Unlock()
+	s.sMu.Lock()
+	res.Suspended = len(s.suspendedQueue)
+	s.sMu.Unlock()
+	s.wMu.Lock()
+	for p, queue := range s.waitingQueues.queues {
+		res.Waiting[p] = len(queue)
+	}
+	s.wMu.Unlock()
+	return res
+}
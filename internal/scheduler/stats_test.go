@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"context"
+	"scheduler/internal/clock"
+	"scheduler/internal/task"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Stats(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, err := New(WithClock(clock.NewVirtual(start)), WithDumpSink(NopSink{}))
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Time: start, Tasks: []TaskStats{}, Priorities: make([]PriorityStats, DefaultPriorityLevels)}, s.Stats())
+
+	tasks := make([]*task.Task, 0)
+	for _, p := range []task.TaskPriority{task.P0, task.P2, task.P3} {
+		bsc, err := task.New(task.Basic, p, task.Suspended)
+		assert.NoError(t, err)
+		tasks = append(tasks, bsc)
+	}
+	ext, err := task.New(task.Extended, task.P1, task.Suspended)
+	assert.NoError(t, err)
+	tasks = append(tasks, ext)
+	for _, tk := range tasks {
+		assert.NoError(t, s.AddNewTask(tk))
+	}
+	s.Run(context.Background())
+	<-s.StopChan
+
+	// P3 runs first, then P2 and the extended task, which waits halfway for
+	// P0 and preempts it when released.
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	stats := s.Stats()
+	assert.Equal(t, []TaskStats{
+		{ID: tasks[0].ID, Priority: task.P0, Added: start, FirstRun: at(6 * time.Second), Completed: at(9500 * time.Millisecond),
+			Ready: 7500 * time.Millisecond, Running: 2 * time.Second, Preemptions: 1},
+		{ID: tasks[1].ID, Priority: task.P2, Added: start, FirstRun: at(2500 * time.Millisecond), Completed: at(5 * time.Second),
+			Ready: 2500 * time.Millisecond, Running: 2500 * time.Millisecond},
+		{ID: tasks[2].ID, Priority: task.P3, Added: start, FirstRun: start, Completed: at(2500 * time.Millisecond),
+			Running: 2500 * time.Millisecond},
+		{ID: ext.ID, Priority: task.P1, Added: start, FirstRun: at(5 * time.Second), Completed: at(8500 * time.Millisecond),
+			Ready: 5 * time.Second, Waiting: time.Second, Running: 2500 * time.Millisecond},
+	}, stats.Tasks)
+	assert.Equal(t, 4, stats.Added)
+	assert.Equal(t, 4, stats.Completed)
+	assert.Equal(t, 1, stats.Preemptions)
+	assert.Equal(t, Summary{Count: 4, Sum: 13500 * time.Millisecond}, stats.ResponseTime)
+	assert.Equal(t, 3375*time.Millisecond, stats.ResponseTime.Mean())
+	assert.Equal(t, Summary{Count: 4, Sum: 15 * time.Second}, stats.ReadyTime)
+	assert.Equal(t, Summary{Count: 4, Sum: time.Second}, stats.WaitingTime)
+	assert.Equal(t, Summary{Count: 4, Sum: 25500 * time.Millisecond}, stats.CompletionTime)
+
+	// The scheduler idles a few ticks before it stops.
+	assert.Equal(t, stats.Time.Sub(start), stats.Elapsed)
+	assert.InDelta(t, 9.5/stats.Elapsed.Seconds(), stats.Utilization, 1e-9)
+	for _, p := range stats.Priorities {
+		assert.Equal(t, 1, p.Completed)
+		assert.InDelta(t, 1/stats.Elapsed.Seconds(), p.Throughput, 1e-9)
+	}
+
+	counts := s.Counts()
+	assert.Equal(t, map[Transition]uint64{
+		{To: task.Suspended}:                     4,
+		{From: task.Suspended, To: task.Ready}:   4,
+		{From: task.Ready, To: task.Running}:     6,
+		{From: task.Running, To: task.Ready}:     1,
+		{From: task.Running, To: task.Waiting}:   1,
+		{From: task.Waiting, To: task.Ready}:     1,
+		{From: task.Running, To: task.Suspended}: 4,
+	}, counts.Transitions)
+	runs := counts.RunDurations
+	assert.Equal(t, uint64(6), runs.Count)
+	assert.Equal(t, 9500*time.Millisecond, runs.Sum)
+	assert.Equal(t, uint64(3), runs.Counts[slices.Index(RunBuckets, time.Second)])
+	assert.Equal(t, uint64(3), runs.Counts[slices.Index(RunBuckets, 2500*time.Millisecond)])
+	assert.GreaterOrEqual(t, counts.IdleTicks[0], uint64(DefaultIdleTicks))
+}
+
+func TestScheduler_QueueLengths(t *testing.T) {
+	s, err := New(WithCores(2), WithPartitionedQueues(), WithReadyLimit(2))
+	assert.NoError(t, err)
+	for range 3 {
+		tk, err := task.New(task.Basic, task.P1, task.Suspended)
+		assert.NoError(t, err)
+		assert.NoError(t, s.AddNewTask(tk))
+	}
+	assert.Equal(t, QueueLengths{Ready: []int{0, 0, 0, 0}, Waiting: []int{0, 0, 0, 0}, Suspended: 3}, s.QueueLengths())
+
+	// Admit as if running, without cores taking the tasks.
+	s.done = make(chan struct{})
+	s.admit()
+	assert.Equal(t, QueueLengths{Ready: []int{0, 2, 0, 0}, Waiting: []int{0, 0, 0, 0}, Suspended: 1}, s.QueueLengths())
+}
+
+func TestTaskStats_NotYet(t *testing.T) {
+	ts := TaskStats{Added: time.Now()}
+	assert.Zero(t, ts.ResponseTime())
+	assert.Zero(t, ts.CompletionTime())
+	assert.Zero(t, Summary{}.Mean())
+}
@@ -0,0 +1,10 @@
+package sim
+
+import "errors"
+
+var (
+	ErrInvalidArrival = errors.New("arrival must not be before the simulated time")
+	ErrDuplicateTask  = errors.New("task was added to the simulation already")
+	ErrResources      = errors.New("tasks holding resources cannot be simulated")
+	ErrActivations    = errors.New("tasks with pending activations cannot be simulated")
+)
@@ -0,0 +1,60 @@
+package sim
+
+import (
+	"scheduler/internal/task"
+	"time"
+)
+
+type eventKind int
+
+const (
+	// arrival adds the task to the suspended queue.
+	arrival eventKind = iota
+	// unitDone ends the work unit the task started in dispatch gen.
+	unitDone
+	// sliceEnd ends the time slice gen of the core.
+	sliceEnd
+	// tick wakes up an idle core.
+	tick
+	// resume starts the work unit the task goes on with after an event, once
+	// the cores have handled the event.
+	resume
+)
+
+type event struct {
+	at   time.Time
+	seq  uint64
+	kind eventKind
+	task *task.Task
+	core *core
+	gen  int
+}
+
+// eventHeap orders events by time, then by the order they were scheduled.
+type eventHeap []event
+
+func (h eventHeap) Len() int {
+	return len(h)
+}
+
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h eventHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *eventHeap) Push(x any) {
+	*h = append(*h, x.(event))
+}
+
+func (h *eventHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
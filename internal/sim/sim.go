@@ -0,0 +1,541 @@
+// Package sim runs the scheduling rules of the scheduler as a single-threaded
+// discrete-event simulation. Tasks take the steps of their built-in body
+// instead of running goroutines, so a simulation scales to millions of tasks
+// and produces the same events as the scheduler on a virtual clock.
+package sim
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"time"
+)
+
+type Simulator struct {
+	cfg           scheduler.Config
+	start         time.Time
+	now           time.Time
+	seq           uint64
+	nextEvent     uint64
+	events        eventHeap
+	pending       int
+	cores         []*core
+	readyQueues   []scheduler.PriorityQueues
+	suspended     []*task.Task
+	waitingQueues scheduler.PriorityQueues
+	jobs          map[*task.Task]*job
+	partitions    map[int]int
+	nextPartition int
+	running       bool
+	dumpQueues    bool
+}
+
+// core runs one task at a time. interrupt is a preemption the core has not
+// handled yet; slice counts the time slices handed out. An idle core sleeps
+// until its next tick.
+type core struct {
+	id        int
+	current   *task.Task
+	interrupt bool
+	slice     int
+	sleeping  bool
+}
+
+// job is the body of a task. until is the end of its current work unit, gen
+// counts its dispatches and resume is set until a resume event is handled.
+// next is a step taken by a resume event that is not a work unit.
+type job struct {
+	steps  *task.Stepper
+	core   *core
+	until  time.Time
+	gen    int
+	resume bool
+	next   *task.Step
+}
+
+// New creates a simulator starting at start. It takes the options of the
+// scheduler; idle cores pick up tasks at their next tick as well. The idle
+// ticks, the journal, the dump buffer and overflow and the clock only matter
+// to live runs and are ignored. Like the scheduler, it copies the queues only
+// for sinks that ask for them with scheduler.IncludeQueues.
+func New(start time.Time, opts ...scheduler.Option) (*Simulator, error) {
+	cfg := scheduler.DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := Simulator{cfg: cfg, start: start, now: start}
+	s.cores = make([]*core, cfg.Cores)
+	for i := range s.cores {
+		s.cores[i] = &core{id: i}
+	}
+	partitions := 1
+	if cfg.Partitioned {
+		partitions = cfg.Cores
+	}
+	s.readyQueues = make([]scheduler.PriorityQueues, partitions)
+	for i := range s.readyQueues {
+		s.readyQueues[i] = scheduler.NewPriorityQueues(cfg.PriorityLevels)
+	}
+	s.suspended = make([]*task.Task, 0)
+	s.waitingQueues = scheduler.NewPriorityQueues(cfg.PriorityLevels)
+	s.jobs = make(map[*task.Task]*job)
+	s.partitions = make(map[int]int)
+	if q, ok := cfg.DumpSink.(scheduler.QueueSink); ok {
+		s.dumpQueues = q.DumpQueues()
+	}
+	return &s, nil
+}
+
+func (s *Simulator) Config() scheduler.Config {
+	return s.cfg
+}
+
+// Now returns the simulated time.
+func (s *Simulator) Now() time.Time {
+	return s.now
+}
+
+// AddTask makes t arrive at after the start of the simulation. Tasks arriving
+// at the start are added right away and admitted once Run starts, as by the
+// scheduler. Only tasks with the built-in body can be simulated; resources,
+// with their priority ceiling, and pending activations are not modelled.
+func (s *Simulator) AddTask(t *task.Task, at time.Duration) error {
+	if err := s.cfg.CheckTask(t.GetPriority(), t.GetAffinity()); err != nil {
+		return err
+	}
+	if at < 0 || s.start.Add(at).Before(s.now) {
+		return ErrInvalidArrival
+	}
+	if _, ok := s.jobs[t]; ok {
+		return ErrDuplicateTask
+	}
+	if len(t.GetResources()) > 0 {
+		return ErrResources
+	}
+	if t.GetMaxActivations() > 1 {
+		return ErrActivations
+	}
+	steps, err := t.Stepper()
+	if err != nil {
+		return err
+	}
+	s.jobs[t] = &job{steps: steps}
+	if at == 0 && !s.running {
+		s.add(t)
+		return nil
+	}
+	s.schedule(event{at: s.start.Add(at), kind: arrival, task: t})
+	return nil
+}
+
+// Run handles events until only the ticks of idle cores are left and closes
+// the dump sink if it is an io.Closer. Tasks left in the queues are reported
+// with scheduler.ErrTasksLeft.
+func (s *Simulator) Run() error {
+	s.running = true
+	s.admit()
+	s.settle()
+	for s.pending > 0 || s.readyLen() > 0 {
+		e := heap.Pop(&s.events).(event)
+		if e.kind != tick {
+			s.pending--
+		}
+		s.now = e.at
+		switch e.kind {
+		case arrival:
+			s.add(e.task)
+		case unitDone:
+			if j := s.jobs[e.task]; j != nil && j.core != nil && j.gen == e.gen {
+				s.step(j.core, j)
+			}
+		case sliceEnd:
+			s.endSlice(e.core, e.gen)
+		case tick:
+			e.core.sleeping = false
+		case resume:
+			j := s.jobs[e.task]
+			j.resume = false
+			if step := j.steps.Step(); step.Kind == task.StepWork {
+				j.until = s.now.Add(step.Duration)
+			} else {
+				j.next = &step
+			}
+			if j.core != nil {
+				s.step(j.core, j)
+			}
+		}
+		s.settle()
+	}
+	if c, ok := s.cfg.DumpSink.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			s.cfg.Logger.Error("dump sink close failed", "error", err)
+		}
+	}
+	return s.leftTasks()
+}
+
+func (s *Simulator) schedule(e event) {
+	if e.kind != tick {
+		s.pending++
+	}
+	s.nextEvent++
+	e.seq = s.nextEvent
+	heap.Push(&s.events, e)
+}
+
+func (s *Simulator) add(t *task.Task) {
+	if s.cfg.Partitioned {
+		p := t.GetAffinity()
+		if p == task.AnyCore {
+			p = s.nextPartition
+			s.nextPartition = (s.nextPartition + 1) % s.cfg.Cores
+		}
+		s.partitions[t.ID] = p
+	}
+	t.SetState(task.Suspended)
+	s.suspended = append(s.suspended, t)
+	s.dump("", task.Suspended, t, trace.NoCore)
+	s.admit()
+}
+
+// settle lets the cores handle their interrupts and pick up ready tasks, as
+// the cores of the scheduler do before the clock advances.
+func (s *Simulator) settle() {
+	for changed := true; changed; {
+		changed = false
+		for _, c := range s.cores {
+			if c.current != nil && c.interrupt {
+				c.interrupt = false
+				s.preempt(c, s.cfg.Policy.Requeue(c.current, scheduler.PreemptedByTask))
+				changed = true
+			}
+			if c.current == nil && !c.sleeping && s.dispatch(c) {
+				changed = true
+			}
+		}
+	}
+}
+
+func (s *Simulator) dispatch(c *core) bool {
+	// An interrupt still pending was meant for the task that left c.
+	c.interrupt = false
+	t := s.cfg.Policy.Next(s.readyFor(c))
+	if t == nil {
+		c.sleeping = true
+		s.schedule(event{at: s.now.Add(s.cfg.TickDuration), kind: tick, core: c})
+		return false
+	}
+	s.readyQueueOf(t).Remove(t)
+	t.SetState(task.Running)
+	c.current = t
+	s.dump(task.Ready, task.Running, t, c.id)
+	s.admit()
+	// A task admitted meanwhile preempts t before it starts.
+	if c.interrupt {
+		c.interrupt = false
+		s.preempt(c, s.cfg.Policy.Requeue(t, scheduler.PreemptedByTask))
+		return true
+	}
+	c.slice++
+	if d := s.cfg.Policy.TimeSlice(); d > 0 {
+		s.schedule(event{at: s.now.Add(d), kind: sliceEnd, core: c, gen: c.slice})
+	}
+	j := s.jobs[t]
+	j.core = c
+	j.gen++
+	s.step(c, j)
+	return true
+}
+
+// step takes the steps of the task running on c until it leaves c or its
+// work unit takes time.
+func (s *Simulator) step(c *core, j *job) {
+	t := c.current
+	for c.current == t && !j.resume {
+		if s.now.Before(j.until) {
+			s.schedule(event{at: j.until, kind: unitDone, task: t, gen: j.gen})
+			return
+		}
+		var step task.Step
+		if j.next != nil {
+			step, j.next = *j.next, nil
+		} else {
+			step = j.steps.Step()
+		}
+		switch step.Kind {
+		case task.StepWork:
+			j.until = s.now.Add(step.Duration)
+		case task.StepWait:
+			s.leave(c)
+			t.SetState(task.Waiting)
+			s.waitingQueues.PushBack(t)
+			s.dump(task.Running, task.Waiting, t, c.id)
+		case task.StepEvent:
+			s.releaseWaiting(step.Target)
+			if c.interrupt {
+				c.interrupt = false
+				s.preempt(c, s.cfg.Policy.Requeue(t, scheduler.PreemptedByTask))
+			}
+			// The work unit goes on even off the core and is waited out at
+			// the next dispatch.
+			if step.Duration > 0 {
+				j.resume = true
+				s.schedule(event{at: s.now, kind: resume, task: t})
+			}
+		case task.StepDone:
+			if step.Err != nil {
+				s.cfg.Logger.Error("task failed", append(t.LogAttrs(), "error", step.Err)...)
+			}
+			s.leave(c)
+			delete(s.jobs, t)
+			t.SetState(task.Suspended)
+			s.dump(task.Running, task.Suspended, t, c.id)
+		}
+	}
+}
+
+func (s *Simulator) endSlice(c *core, gen int) {
+	t := c.current
+	if t == nil || c.slice != gen {
+		return
+	}
+	if len(s.readyFor(c).Queue(t.GetPriority())) == 0 {
+		s.schedule(event{at: s.now.Add(s.cfg.Policy.TimeSlice()), kind: sliceEnd, core: c, gen: gen})
+		return
+	}
+	s.preempt(c, s.cfg.Policy.Requeue(t, scheduler.SliceExpired))
+}
+
+// leave clears the current task of c.
+func (s *Simulator) leave(c *core) {
+	if j := s.jobs[c.current]; j != nil {
+		j.core = nil
+	}
+	c.current = nil
+}
+
+func (s *Simulator) preempt(c *core, pos scheduler.QueuePosition) {
+	t := c.current
+	s.leave(c)
+	t.SetState(task.Ready)
+	s.readyQueueOf(t).Push(t, pos)
+	s.dump(task.Running, task.Ready, t, c.id)
+}
+
+// admit moves released waiting tasks to ready while the ready queues are
+// below MaxReadyTasks, then suspended tasks while they are below ReadyLimit.
+func (s *Simulator) admit() {
+	if !s.running {
+		return
+	}
+	for s.readyLen() < s.cfg.MaxReadyTasks {
+		var t *task.Task
+		s.waitingQueues.Each(func(w *task.Task) bool {
+			if w.IsReleased() {
+				t = w
+				return false
+			}
+			return true
+		})
+		if t == nil {
+			break
+		}
+		s.waitingQueues.Remove(t)
+		s.prepend(t)
+	}
+	for s.readyLen() < s.cfg.ReadyLimit && len(s.suspended) > 0 {
+		t := s.suspended[0]
+		s.suspended[0] = nil
+		s.suspended = s.suspended[1:]
+		t.SetState(task.Ready)
+		s.readyQueueOf(t).PushBack(t)
+		s.dump(task.Suspended, task.Ready, t, trace.NoCore)
+		s.checkInterruption(t)
+	}
+}
+
+// releaseWaiting moves target to ready if one of the events it waits for is
+// set. A nil target sets DefaultEvent on the highest-priority task still
+// waiting for it. While the ready queues are full, a released task stays
+// waiting until admit finds room for it.
+func (s *Simulator) releaseWaiting(target *task.Task) {
+	if target == nil {
+		s.waitingQueues.Each(func(t *task.Task) bool {
+			if t.GetWaitMask()&task.DefaultEvent != 0 && !t.IsReleased() {
+				target = t
+				return false
+			}
+			return true
+		})
+		if target == nil {
+			return
+		}
+		target.SetEvent(task.DefaultEvent)
+	}
+	if s.readyLen() >= s.cfg.MaxReadyTasks || !target.IsReleased() || !s.waitingQueues.Remove(target) {
+		return
+	}
+	s.prepend(target)
+}
+
+func (s *Simulator) prepend(t *task.Task) {
+	t.SetState(task.Ready)
+	s.readyQueueOf(t).PushFront(t)
+	s.dump(task.Waiting, task.Ready, t, trace.NoCore)
+	s.checkInterruption(t)
+}
+
+// checkInterruption marks the weakest core t may run on for preemption,
+// unless one of them is idle and picks t up anyway.
+func (s *Simulator) checkInterruption(t *task.Task) {
+	var victim *core
+	for _, c := range s.coresFor(t) {
+		if c.current == nil {
+			return
+		}
+		if !s.cfg.Policy.Preempts(c.current, t) {
+			continue
+		}
+		if victim == nil || s.cfg.Policy.Preempts(c.current, victim.current) {
+			victim = c
+		}
+	}
+	if victim != nil {
+		victim.interrupt = true
+	}
+}
+
+func (s *Simulator) readyQueueOf(t *task.Task) *scheduler.PriorityQueues {
+	if !s.cfg.Partitioned {
+		return &s.readyQueues[0]
+	}
+	return &s.readyQueues[s.partitions[t.ID]]
+}
+
+func (s *Simulator) readyFor(c *core) *scheduler.PriorityQueues {
+	if s.cfg.Partitioned {
+		return &s.readyQueues[c.id]
+	}
+	if len(s.cores) == 1 {
+		return &s.readyQueues[0]
+	}
+	return s.readyQueues[0].Filter(func(t *task.Task) bool {
+		return canRun(c, t)
+	})
+}
+
+func (s *Simulator) coresFor(t *task.Task) []*core {
+	if s.cfg.Partitioned {
+		p := s.partitions[t.ID]
+		return s.cores[p : p+1]
+	}
+	res := make([]*core, 0, len(s.cores))
+	for _, c := range s.cores {
+		if canRun(c, t) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func canRun(c *core, t *task.Task) bool {
+	a := t.GetAffinity()
+	return a == task.AnyCore || a == c.id
+}
+
+func (s *Simulator) readyLen() int {
+	res := 0
+	for _, q := range s.readyQueues {
+		res += q.Len()
+	}
+	return res
+}
+
+// dump passes a transition of t to the dump sink, with a copy of all queues
+// if the sink wants them.
+func (s *Simulator) dump(from, to task.TaskState, t *task.Task, core int) {
+	if _, ok := s.cfg.DumpSink.(scheduler.NopSink); ok {
+		return
+	}
+	s.seq++
+	e := trace.Event{
+		Seq:      s.seq,
+		Time:     s.now,
+		TaskID:   t.ID,
+		Priority: t.GetPriority(),
+		From:     from,
+		To:       to,
+		Core:     core,
+	}
+	if s.dumpQueues {
+		q := s.Queues()
+		e.Queues = &q
+	}
+	s.cfg.DumpSink.Dump(e)
+}
+
+// Queues returns a copy of all queues and the running tasks.
+func (s *Simulator) Queues() trace.Queues {
+	res := trace.Queues{
+		Running:   make([]*task.Snapshot, len(s.cores)),
+		Ready:     make([][]task.Snapshot, s.cfg.PriorityLevels),
+		Suspended: make([]task.Snapshot, len(s.suspended)),
+		Waiting:   make([][]task.Snapshot, s.cfg.PriorityLevels),
+	}
+	for i, c := range s.cores {
+		if c.current != nil {
+			snap := c.current.Snapshot()
+			res.Running[i] = &snap
+		}
+	}
+	for _, q := range s.readyQueues {
+		for p := range res.Ready {
+			for _, t := range q.Queue(task.TaskPriority(p)) {
+				res.Ready[p] = append(res.Ready[p], t.Snapshot())
+			}
+		}
+	}
+	for i, t := range s.suspended {
+		res.Suspended[i] = t.Snapshot()
+	}
+	for p := range res.Waiting {
+		queue := s.waitingQueues.Queue(task.TaskPriority(p))
+		res.Waiting[p] = make([]task.Snapshot, len(queue))
+		for i, t := range queue {
+			res.Waiting[p][i] = t.Snapshot()
+		}
+	}
+	return res
+}
+
+func (s *Simulator) leftTasks() error {
+	ids := func(tasks []*task.Task) []int {
+		res := make([]int, 0)
+		for _, t := range tasks {
+			res = append(res, t.ID)
+		}
+		return res
+	}
+	queued := func(queues ...scheduler.PriorityQueues) []int {
+		res := make([]int, 0)
+		for _, q := range queues {
+			for p := range s.cfg.PriorityLevels {
+				res = append(res, ids(q.Queue(task.TaskPriority(p)))...)
+			}
+		}
+		return res
+	}
+
+	ready, suspended, waiting := queued(s.readyQueues...), ids(s.suspended), queued(s.waitingQueues)
+	if len(ready)+len(suspended)+len(waiting) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w | ready=%v | suspended=%v | waiting=%v", scheduler.ErrTasksLeft, ready, suspended, waiting)
+}
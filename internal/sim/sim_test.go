@@ -0,0 +1,200 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"scheduler/internal/clock"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// planned is a task of a scenario arriving at.
+type planned struct {
+	at   time.Duration
+	task *task.Task
+}
+
+type scenario func(t *testing.T) []planned
+
+func newTask(t *testing.T, tType task.TaskType, p task.TaskPriority, opts ...task.Option) *task.Task {
+	res, err := task.New(tType, p, task.Suspended, opts...)
+	assert.NoError(t, err)
+	return res
+}
+
+// waiting has an extended task wait halfway for the basic tasks.
+func waiting(t *testing.T) []planned {
+	return []planned{
+		{task: newTask(t, task.Basic, task.P0)},
+		{task: newTask(t, task.Basic, task.P2)},
+		{task: newTask(t, task.Basic, task.P3)},
+		{task: newTask(t, task.Extended, task.P1)},
+	}
+}
+
+// arriving has tasks preempt each other as they arrive.
+func arriving(t *testing.T) []planned {
+	unit := task.WithSleepTime(10 * time.Millisecond)
+	return []planned{
+		{task: newTask(t, task.Extended, task.P1, unit)},
+		{at: 15 * time.Millisecond, task: newTask(t, task.Basic, task.P2, unit)},
+		{at: 15 * time.Millisecond, task: newTask(t, task.Basic, task.P3, unit)},
+		{at: 42 * time.Millisecond, task: newTask(t, task.Basic, task.P0, unit, task.WithProgressLimit(2))},
+		{at: 200 * time.Millisecond, task: newTask(t, task.Basic, task.P1, unit)},
+	}
+}
+
+// runLive runs the scenario on the scheduler with a virtual clock.
+func runLive(t *testing.T, arrivals []planned, opts ...scheduler.Option) []trace.Event {
+	sink := scheduler.NewRingSink(scheduler.DefaultRingSize)
+	clk := clock.NewVirtual(start)
+	s, err := scheduler.New(append([]scheduler.Option{scheduler.WithClock(clk), scheduler.WithDumpSink(scheduler.IncludeQueues(sink))}, opts...)...)
+	assert.NoError(t, err)
+
+	release := s.Hold()
+	later := make([]planned, 0)
+	for _, a := range arrivals {
+		if a.at == 0 {
+			assert.NoError(t, s.AddNewTask(a.task))
+			continue
+		}
+		later = append(later, a)
+	}
+	clk.Busy()
+	go func() {
+		defer release()
+		defer clk.Idle()
+		for _, a := range later {
+			clk.Sleep(start.Add(a.at).Sub(clk.Now()))
+			assert.NoError(t, s.AddNewTask(a.task))
+		}
+	}()
+	s.Run(context.Background())
+	<-s.StopChan
+	return sink.Events()
+}
+
+func runSim(t *testing.T, arrivals []planned, opts ...scheduler.Option) []trace.Event {
+	sink := scheduler.NewRingSink(scheduler.DefaultRingSize)
+	s, err := New(start, append([]scheduler.Option{scheduler.WithDumpSink(scheduler.IncludeQueues(sink))}, opts...)...)
+	assert.NoError(t, err)
+	for _, a := range arrivals {
+		assert.NoError(t, s.AddTask(a.task, a.at))
+	}
+	assert.NoError(t, s.Run())
+	return sink.Events()
+}
+
+// normalized formats events with task IDs relative to the first task, so
+// traces of different tasks compare.
+func normalized(events []trace.Event, base int) []string {
+	snaps := func(snaps []task.Snapshot) string {
+		res := make([]string, 0, len(snaps))
+		for _, s := range snaps {
+			res = append(res, fmt.Sprintf("%d:%d", s.ID-base, s.Progress))
+		}
+		return strings.Join(res, ",")
+	}
+	levels := func(queues [][]task.Snapshot) string {
+		res := make([]string, 0, len(queues))
+		for _, q := range queues {
+			res = append(res, snaps(q))
+		}
+		return strings.Join(res, "|")
+	}
+	res := make([]string, 0, len(events))
+	for _, e := range events {
+		running := make([]task.Snapshot, 0)
+		for _, r := range e.Queues.Running {
+			if r != nil {
+				running = append(running, *r)
+			}
+		}
+		res = append(res, fmt.Sprintf("%d %v %d %s->%s %d running=%s ready=%s suspended=%s waiting=%s",
+			e.Seq, e.Time.Sub(start), e.TaskID-base, e.From, e.To, e.Core,
+			snaps(running), levels(e.Queues.Ready), snaps(e.Queues.Suspended), levels(e.Queues.Waiting)))
+	}
+	return res
+}
+
+func TestSimulator_MatchesScheduler(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario scenario
+		opts     []scheduler.Option
+	}{
+		{name: "Waiting", scenario: waiting},
+		{name: "Arriving", scenario: arriving},
+		{name: "Ready limit", scenario: arriving, opts: []scheduler.Option{scheduler.WithReadyLimit(1)}},
+		{name: "Round robin", scenario: arriving, opts: []scheduler.Option{scheduler.WithPolicy(scheduler.RoundRobin{Slice: 15 * time.Millisecond})}},
+		{name: "Non-preemptive", scenario: arriving, opts: []scheduler.Option{scheduler.WithPolicy(scheduler.NonPreemptive{})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := tt.scenario(t)
+			simulated := tt.scenario(t)
+			expected := normalized(runLive(t, live, tt.opts...), live[0].task.ID)
+			actual := normalized(runSim(t, simulated, tt.opts...), simulated[0].task.ID)
+			assert.Equal(t, expected, actual)
+			for i := range live {
+				assert.Equal(t, live[i].task.GetProgress(), simulated[i].task.GetProgress())
+			}
+		})
+	}
+}
+
+func TestSimulator_AddTask(t *testing.T) {
+	s, err := New(start, scheduler.WithPriorityLevels(2))
+	assert.NoError(t, err)
+	added := newTask(t, task.Basic, task.P0)
+	custom, err := task.NewWithFunc(task.Basic, task.P0, func(task.Context) error { return nil })
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.AddTask(added, time.Second))
+	assert.ErrorIs(t, s.AddTask(added, 0), ErrDuplicateTask)
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P0), -time.Second), ErrInvalidArrival)
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P3), 0), scheduler.ErrInvalidPriority)
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P0, task.WithAffinity(1)), 0), scheduler.ErrInvalidAffinity)
+	assert.ErrorIs(t, s.AddTask(custom, 0), task.ErrNotSteppable)
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P0, task.WithResources("disk")), 0), ErrResources)
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P0, task.WithMaxActivations(2)), 0), ErrActivations)
+
+	assert.NoError(t, s.Run())
+	assert.Equal(t, start.Add(3500*time.Millisecond), s.Now())
+	assert.ErrorIs(t, s.AddTask(newTask(t, task.Basic, task.P0), time.Second), ErrInvalidArrival)
+}
+
+func TestSimulator_TasksLeft(t *testing.T) {
+	s, err := New(start)
+	assert.NoError(t, err)
+	waiter := newTask(t, task.Extended, task.P1)
+	assert.NoError(t, s.AddTask(waiter, 0))
+
+	assert.ErrorIs(t, s.Run(), scheduler.ErrTasksLeft)
+	assert.Equal(t, task.Waiting, waiter.GetState())
+}
+
+func TestSimulator_Scale(t *testing.T) {
+	const tasks = 100000
+	s, err := New(start, scheduler.WithDumpSink(scheduler.NopSink{}), scheduler.WithCores(4), scheduler.WithPolicy(scheduler.RoundRobin{Slice: 5 * time.Millisecond}))
+	assert.NoError(t, err)
+	all := make([]*task.Task, tasks)
+	for i := range all {
+		all[i] = newTask(t, task.Basic, task.TaskPriority(i%4), task.WithSleepTime(time.Millisecond))
+		assert.NoError(t, s.AddTask(all[i], time.Duration(i)*time.Millisecond))
+	}
+
+	assert.NoError(t, s.Run())
+	for _, tk := range all {
+		assert.Equal(t, task.Suspended, tk.GetState())
+		assert.Equal(t, tk.GetProgressLimit(), tk.GetProgress())
+	}
+}
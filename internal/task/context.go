@@ -0,0 +1,178 @@
+package task
+
+import (
+	"log/slog"
+	"time"
+)
+
+type EventMask uint64
+
+// DefaultEvent is the event the built-in task body of extended tasks waits for.
+const DefaultEvent EventMask = 1
+
+// EventPoint makes the built-in body wait for and set events before the work
+// unit At. The task first waits for one of the Wait events and clears them,
+// then sets the Set events on the task Target returns. A nil target skips
+// setting the events.
+type EventPoint struct {
+	At     int
+	Wait   EventMask
+	Set    EventMask
+	Target func() *Task
+}
+
+// Body is the workload of a task. It runs in its own goroutine and has to
+// cooperate with the scheduler through ctx.
+type Body func(ctx Context) error
+
+type Context interface {
+	Task() *Task
+	// Done is closed when the task has been preempted. The body should call
+	// Yield soon after, which returns once the task runs again.
+	Done() <-chan struct{}
+	// Yield gives up the processor and returns when the task runs again.
+	Yield()
+	// WaitEvent blocks until one of the events in mask is set for the task
+	// and returns the events of mask that are set.
+	WaitEvent(mask EventMask) (EventMask, error)
+	// SetEvent sets events of an extended task and releases it if it waits
+	// for one of them.
+	SetEvent(t *Task, mask EventMask) error
+	// ClearEvent clears events of the task itself.
+	ClearEvent(mask EventMask) error
+	// Sleep does d of work on the clock of the task.
+	Sleep(d time.Duration)
+	// GetResource takes a resource the task declared. The task runs at the
+	// ceiling priority of the resource until it releases it.
+	GetResource(name string) error
+	// ReleaseResource releases the resource the task took last.
+	ReleaseResource(name string) error
+}
+
+type taskContext struct {
+	task *Task
+}
+
+func (c *taskContext) Task() *Task {
+	return c.task
+}
+
+func (c *taskContext) Done() <-chan struct{} {
+	return c.task.run.done()
+}
+
+func (c *taskContext) Yield() {
+	t := c.task
+	clk := t.run.getClock()
+	select {
+	case <-t.run.done():
+	default:
+		clk.Busy()
+		select {
+		case t.YieldChan <- struct{}{}:
+		case <-t.run.done():
+			clk.Idle()
+		}
+	}
+	t.run.waitDispatch()
+}
+
+func (c *taskContext) WaitEvent(mask EventMask) (EventMask, error) {
+	t := c.task
+	if t.tType != Extended {
+		return 0, ErrNotExtended
+	}
+	t.run.mu.Lock()
+	if ev := t.run.events & mask; ev != 0 {
+		t.run.mu.Unlock()
+		return ev, nil
+	}
+	t.run.waitMask = mask
+	t.run.mu.Unlock()
+
+	t.signal(t.WaitChan)
+	t.run.waitDispatch()
+
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.run.waitMask = 0
+	return t.run.events & mask, nil
+}
+
+func (c *taskContext) SetEvent(t *Task, mask EventMask) error {
+	if t.tType != Extended {
+		return ErrNotExtended
+	}
+	t.SetEvent(mask)
+	c.task.signalEvent(t)
+	return nil
+}
+
+func (c *taskContext) Sleep(d time.Duration) {
+	c.task.run.getClock().Sleep(d)
+}
+
+func (c *taskContext) GetResource(name string) error {
+	return c.task.requestResource(name, false)
+}
+
+func (c *taskContext) ReleaseResource(name string) error {
+	return c.task.requestResource(name, true)
+}
+
+func (c *taskContext) ClearEvent(mask EventMask) error {
+	if c.task.tType != Extended {
+		return ErrNotExtended
+	}
+	c.task.ClearEvent(mask)
+	return nil
+}
+
+// defaultBody makes progress up to the progress limit. Halfway through, an
+// extended task waits for DefaultEvent and a basic task sets DefaultEvent on
+// the highest-priority task waiting for it. Tasks with event points reach
+// those instead. It takes the steps of a Stepper, so simulations run the same
+// body.
+func defaultBody(ctx Context) error {
+	t := ctx.Task()
+	steps, err := t.Stepper()
+	if err != nil {
+		return err
+	}
+	// A work unit right after an event starts even if the event preempts
+	// the task.
+	work := false
+	for {
+		if !work {
+			select {
+			case <-ctx.Done():
+				t.logAt(slog.LevelInfo, "task interrupted")
+				ctx.Yield()
+				continue
+			default:
+			}
+		}
+		step := steps.Step()
+		work = false
+		switch step.Kind {
+		case StepWork:
+			t.logAt(slog.LevelInfo, "task progress", "progress_limit", t.progressLimit)
+			ctx.Sleep(step.Duration)
+		case StepWait:
+			t.logAt(slog.LevelInfo, "task waiting", "event", step.Mask)
+			if _, err := ctx.WaitEvent(step.Mask); err != nil {
+				return err
+			}
+		case StepEvent:
+			if step.Target == nil {
+				t.logAt(slog.LevelInfo, "task setting event", "event", DefaultEvent)
+			} else {
+				t.logAt(slog.LevelInfo, "task setting event", "event", step.Mask, "target_id", step.Target.ID)
+			}
+			t.signalEvent(step.Target)
+			work = step.Duration != 0
+		case StepDone:
+			return step.Err
+		}
+	}
+}
@@ -6,4 +6,17 @@ var (
 	ErrInvalidState    = errors.New("failed to set invalid state")
 	ErrInvalidType     = errors.New("failed to set invalid type")
 	ErrInvalidPriority = errors.New("failed to set invalid priority")
+
+	ErrInvalidProgressLimit  = errors.New("progress limit must be positive")
+	ErrInvalidSleepTime      = errors.New("sleep time must be positive")
+	ErrInvalidBody           = errors.New("task body must not be nil")
+	ErrNotExtended           = errors.New("only extended tasks can wait for events")
+	ErrInvalidAffinity       = errors.New("affinity must be a core index or AnyCore")
+	ErrInvalidProgress       = errors.New("progress must be between zero and the progress limit")
+	ErrInvalidEventPoint     = errors.New("event point must be within the progress limit and setting events needs a target")
+	ErrNotSteppable          = errors.New("only the built-in task body can be stepped")
+	ErrInvalidMaxActivations = errors.New("max activations must be positive")
+	ErrActivationLimit       = errors.New("task has reached its max activations")
+	ErrInvalidResource       = errors.New("resource names must be set and unique")
+	ErrNotRestorable         = errors.New("tasks with a custom body or event points cannot be restored")
 )
@@ -0,0 +1,37 @@
+package task
+
+import "scheduler/internal/clock"
+
+// ResourceRequest asks the scheduler to take or release a resource for the
+// running task. The scheduler answers with Reply.
+type ResourceRequest struct {
+	Name    string
+	Release bool
+	reply   chan error
+	clock   clock.Clock
+}
+
+// Reply answers the request. The body is marked busy before it can take the
+// answer.
+func (r ResourceRequest) Reply(err error) {
+	r.clock.Busy()
+	r.reply <- err
+}
+
+// requestResource hands the request to the scheduler while the task is
+// running and waits for the answer.
+func (t *Task) requestResource(name string, release bool) error {
+	clk := t.run.getClock()
+	req := ResourceRequest{Name: name, Release: release, reply: make(chan error, 1), clock: clk}
+	for {
+		preempt := t.run.running()
+		clk.Busy()
+		select {
+		case t.ResourceChan <- req:
+			clk.Idle()
+			return <-req.reply
+		case <-preempt:
+			clk.Idle()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package task
+
+import "time"
+
+// Snapshot is the serializable state of a task.
+type Snapshot struct {
+	ID             int           `json:"id"`
+	Type           TaskType      `json:"type"`
+	Priority       TaskPriority  `json:"priority"`
+	State          TaskState     `json:"state"`
+	Progress       int           `json:"progress"`
+	ProgressLimit  int           `json:"progress_limit"`
+	SleepTime      time.Duration `json:"sleep_time"`
+	Deadline       time.Time     `json:"deadline,omitzero"`
+	Affinity       int           `json:"affinity"`
+	Events         EventMask     `json:"events,omitempty"`
+	WaitMask       EventMask     `json:"wait_mask,omitempty"`
+	MaxActivations int           `json:"max_activations,omitempty"`
+	Activations    int           `json:"activations,omitempty"`
+	Resources      []string      `json:"resources,omitempty"`
+	// CustomBody and EventPoints mark tasks that Restore cannot bring back
+	// as they were: bodies and event point targets are code.
+	CustomBody  bool `json:"custom_body,omitempty"`
+	EventPoints int  `json:"event_points,omitempty"`
+}
+
+func (t *Task) Snapshot() Snapshot {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return Snapshot{
+		ID:             t.ID,
+		Type:           t.tType,
+		Priority:       t.priority,
+		State:          t.state,
+		Progress:       t.progress,
+		ProgressLimit:  t.progressLimit,
+		SleepTime:      t.sleepTime,
+		Deadline:       t.deadline,
+		Affinity:       t.affinity,
+		Events:         t.run.events,
+		WaitMask:       t.run.waitMask,
+		MaxActivations: t.maxActivation,
+		Activations:    t.run.pending,
+		Resources:      t.resources,
+		CustomBody:     !t.builtin,
+		EventPoints:    len(t.points),
+	}
+}
+
+// Restore recreates a task from its snapshot. The task keeps its ID and runs
+// the built-in body from the saved progress on. New never hands out the
+// restored ID again. Tasks with a custom body or event points cannot be
+// restored and fail with ErrNotRestorable.
+func Restore(s Snapshot) (*Task, error) {
+	if s.CustomBody || s.EventPoints > 0 {
+		return nil, ErrNotRestorable
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	maxActivations := s.MaxActivations
+	if maxActivations == 0 {
+		maxActivations = DefaultMaxActivations
+	}
+	t, err := build(s.ID, s.Type, s.Priority, s.State, nil,
+		WithProgressLimit(s.ProgressLimit), WithSleepTime(s.SleepTime), WithDeadline(s.Deadline), WithAffinity(s.Affinity),
+		WithMaxActivations(maxActivations), WithResources(s.Resources...))
+	if err != nil {
+		return nil, err
+	}
+	if s.Progress < 0 || s.Progress > t.progressLimit {
+		return nil, ErrInvalidProgress
+	}
+	t.progress = s.Progress
+	t.run.events = s.Events
+	t.run.waitMask = s.WaitMask
+	if s.Activations < 0 || s.Activations >= t.maxActivation {
+		return nil, ErrActivationLimit
+	}
+	t.run.pending = s.Activations
+	nextTaskID = max(nextTaskID, s.ID+1)
+	return t, nil
+}
+
+// NextID returns the ID the next new task gets.
+func NextID() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return nextTaskID
+}
+
+// ReserveIDs makes new tasks get IDs from next on, unless they already do.
+func ReserveIDs(next int) {
+	mu.Lock()
+	defer mu.Unlock()
+	nextTaskID = max(nextTaskID, next)
+}
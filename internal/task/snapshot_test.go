@@ -0,0 +1,87 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).Round(0)
+	task, err := New(Extended, P2, Waiting, WithProgressLimit(8), WithSleepTime(time.Millisecond), WithDeadline(deadline), WithAffinity(1), WithMaxActivations(2), WithResources("a"))
+	assert.NoError(t, err)
+	task.progress = 4
+	assert.NoError(t, task.QueueActivation())
+	task.SetEvent(0b01)
+	task.run.waitMask = 0b10
+
+	snap := task.Snapshot()
+	restored, err := Restore(snap)
+	assert.NoError(t, err)
+	assert.Equal(t, snap, restored.Snapshot())
+	assert.Equal(t, task.ID, restored.ID)
+	assert.Equal(t, 4, restored.GetProgress())
+	assert.False(t, restored.IsReleased())
+
+	// Restored IDs are never handed out again.
+	snap.ID = NextID() + 10
+	_, err = Restore(snap)
+	assert.NoError(t, err)
+	next, err := New(Basic, P0, Suspended)
+	assert.NoError(t, err)
+	assert.Equal(t, snap.ID+1, next.ID)
+
+	ReserveIDs(next.ID + 5)
+	assert.Equal(t, next.ID+5, NextID())
+	ReserveIDs(0)
+	assert.Equal(t, next.ID+5, NextID())
+
+	snap.Progress = 9
+	_, err = Restore(snap)
+	assert.ErrorIs(t, err, ErrInvalidProgress)
+
+	snap.Progress = 0
+	snap.Activations = 2
+	_, err = Restore(snap)
+	assert.ErrorIs(t, err, ErrActivationLimit)
+
+	// Snapshots without max activations get the default.
+	snap.Activations, snap.MaxActivations = 0, 0
+	restored, err = Restore(snap)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxActivations, restored.GetMaxActivations())
+
+	snap.Priority = MaxPriorityLevels
+	_, err = Restore(snap)
+	assert.ErrorIs(t, err, ErrInvalidPriority)
+}
+
+func TestRestoreCustomTask(t *testing.T) {
+	custom, err := NewWithFunc(Basic, P1, func(Context) error { return nil })
+	assert.NoError(t, err)
+	assert.True(t, custom.Snapshot().CustomBody)
+	_, err = Restore(custom.Snapshot())
+	assert.ErrorIs(t, err, ErrNotRestorable)
+
+	target, err := New(Extended, P1, Suspended)
+	assert.NoError(t, err)
+	points, err := New(Basic, P1, Suspended, WithEventPoints(EventPoint{At: 1, Set: 0b1, Target: func() *Task { return target }}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, points.Snapshot().EventPoints)
+	_, err = Restore(points.Snapshot())
+	assert.ErrorIs(t, err, ErrNotRestorable)
+}
+
+func TestRestoredTaskRuns(t *testing.T) {
+	task, err := New(Basic, P1, Suspended, WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	snap := task.Snapshot()
+	snap.Progress = snap.ProgressLimit - 1
+
+	restored, err := Restore(snap)
+	assert.NoError(t, err)
+	restored.Do()
+	<-restored.DoneChan
+	assert.Equal(t, snap.ProgressLimit, restored.GetProgress())
+}
@@ -0,0 +1,131 @@
+package task
+
+import "time"
+
+// StepKind is what the built-in body does next.
+type StepKind int
+
+const (
+	// StepWork is a work unit taking Duration.
+	StepWork StepKind = iota
+	// StepWait waits for one of the events of Mask.
+	StepWait
+	// StepEvent hands an event to the scheduler: the events were set on
+	// Target, or for a nil Target DefaultEvent goes to the highest-priority
+	// task waiting for it. A non-zero Duration means the next step is a work
+	// unit the body starts right away, even if the event preempts the task.
+	StepEvent
+	// StepDone ends the body with Err.
+	StepDone
+)
+
+type Step struct {
+	Kind     StepKind
+	Duration time.Duration
+	Mask     EventMask
+	Target   *Task
+	Err      error
+}
+
+// Stepper takes the steps of the built-in body one at a time in the caller's
+// goroutine, so a simulation can run tasks without their body goroutines. The
+// built-in body takes the same steps.
+type Stepper struct {
+	t       *Task
+	next    int
+	halfway bool
+	waiting EventMask
+	set     *EventPoint
+}
+
+// Stepper starts the built-in body of t from its current progress. Tasks with
+// their own body cannot be stepped.
+func (t *Task) Stepper() (*Stepper, error) {
+	if !t.builtin {
+		return nil, ErrNotSteppable
+	}
+	return &Stepper{t: t, halfway: len(t.points) > 0}, nil
+}
+
+// Step takes the next step. A task that got StepWait has to be released
+// before Step is called again.
+func (s *Stepper) Step() Step {
+	t := s.t
+	if s.waiting != 0 {
+		t.run.mu.Lock()
+		t.run.waitMask = 0
+		t.run.events &^= s.waiting
+		t.run.mu.Unlock()
+		s.waiting = 0
+		if p := s.set; p != nil {
+			s.set = nil
+			if step, ok := s.setEvents(*p); ok {
+				return step
+			}
+		}
+	}
+	for t.GetProgress() < t.progressLimit {
+		if s.next < len(t.points) && t.points[s.next].At == t.GetProgress() {
+			p := t.points[s.next]
+			s.next++
+			if p.Wait != 0 && s.wait(p.Wait) {
+				s.set = &p
+				return Step{Kind: StepWait, Mask: p.Wait}
+			}
+			if step, ok := s.setEvents(p); ok {
+				return step
+			}
+			continue
+		}
+		if t.GetProgress() == t.progressLimit/2 && !s.halfway {
+			s.halfway = true
+			if t.tType == Extended {
+				if s.wait(DefaultEvent) {
+					return Step{Kind: StepWait, Mask: DefaultEvent}
+				}
+				continue
+			}
+			return Step{Kind: StepEvent, Duration: t.sleepTime}
+		}
+		t.advance()
+		return Step{Kind: StepWork, Duration: t.sleepTime}
+	}
+	return s.done(nil)
+}
+
+// wait makes the task wait for mask unless one of its events is set already,
+// which is cleared right away.
+func (s *Stepper) wait(mask EventMask) bool {
+	t := s.t
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if t.run.events&mask != 0 {
+		t.run.events &^= mask
+		return false
+	}
+	t.run.waitMask = mask
+	s.waiting = mask
+	return true
+}
+
+func (s *Stepper) setEvents(p EventPoint) (Step, bool) {
+	if p.Set == 0 {
+		return Step{}, false
+	}
+	target := p.Target()
+	if target == nil {
+		return Step{}, false
+	}
+	if target.tType != Extended {
+		return s.done(ErrNotExtended), true
+	}
+	target.SetEvent(p.Set)
+	return Step{Kind: StepEvent, Mask: p.Set, Target: target}, true
+}
+
+func (s *Stepper) done(err error) Step {
+	s.t.run.mu.Lock()
+	defer s.t.run.mu.Unlock()
+	s.t.run.err = err
+	return Step{Kind: StepDone, Err: err}
+}
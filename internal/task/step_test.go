@@ -0,0 +1,70 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// steps takes steps until the body is done or waits.
+func steps(s *Stepper) []Step {
+	res := make([]Step, 0)
+	for {
+		step := s.Step()
+		res = append(res, step)
+		if step.Kind == StepDone || step.Kind == StepWait {
+			return res
+		}
+	}
+}
+
+func TestStepper(t *testing.T) {
+	work := Step{Kind: StepWork, Duration: time.Millisecond}
+	ext, err := New(Extended, P2, Suspended, WithProgressLimit(4), WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	basic, err := New(Basic, P1, Suspended, WithProgressLimit(3), WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+
+	extSteps, err := ext.Stepper()
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{work, work, {Kind: StepWait, Mask: DefaultEvent}}, steps(extSteps))
+	assert.Equal(t, DefaultEvent, ext.GetWaitMask())
+
+	basicSteps, err := basic.Stepper()
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{work, {Kind: StepEvent, Duration: time.Millisecond}, work, work, {Kind: StepDone}}, steps(basicSteps))
+	assert.Equal(t, 3, basic.GetProgress())
+
+	ext.SetEvent(DefaultEvent)
+	assert.Equal(t, []Step{work, work, {Kind: StepDone}}, steps(extSteps))
+	assert.Equal(t, EventMask(0), ext.GetEvent())
+	assert.Equal(t, EventMask(0), ext.GetWaitMask())
+
+	custom, err := NewWithFunc(Basic, P1, func(Context) error { return nil })
+	assert.NoError(t, err)
+	_, err = custom.Stepper()
+	assert.ErrorIs(t, err, ErrNotSteppable)
+}
+
+func TestStepperEventPoints(t *testing.T) {
+	work := Step{Kind: StepWork, Duration: time.Millisecond}
+	ext, err := New(Extended, P2, Suspended, WithProgressLimit(2), WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	basic, err := New(Basic, P2, Suspended)
+	assert.NoError(t, err)
+	relay, err := New(Extended, P1, Suspended, WithProgressLimit(2), WithSleepTime(time.Millisecond),
+		WithEventPoints(EventPoint{At: 1, Wait: 0b1, Set: 0b10, Target: func() *Task { return ext }},
+			EventPoint{At: 1, Set: 0b1, Target: func() *Task { return basic }}))
+	assert.NoError(t, err)
+
+	s, err := relay.Stepper()
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{work, {Kind: StepWait, Mask: 0b1}}, steps(s))
+
+	relay.SetEvent(0b1)
+	assert.Equal(t, []Step{{Kind: StepEvent, Mask: 0b10, Target: ext}, {Kind: StepDone, Err: ErrNotExtended}}, steps(s))
+	assert.Equal(t, EventMask(0b10), ext.GetEvent())
+	assert.Equal(t, EventMask(0), relay.GetEvent())
+	assert.ErrorIs(t, relay.Err(), ErrNotExtended)
+}
@@ -1,8 +1,12 @@
 package task
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"runtime"
+	"scheduler/internal/clock"
 	"scheduler/internal/utils"
+	"slices"
 	"sync"
 	"time"
 )
@@ -13,10 +17,6 @@ type TaskType string
 
 type TaskPriority int
 
-const (
-	ArgsStateIndex int = 0
-)
-
 const (
 	Running   TaskState = "running"
 	Ready     TaskState = "ready"
@@ -36,30 +36,258 @@ const (
 	P3
 )
 
+// MaxPriorityLevels bounds the priority levels a scheduler can be configured
+// with, as in OSEK implementations with 256 levels.
+const MaxPriorityLevels = 256
+
+// AnyCore lets a task run on any core of a multi-core scheduler.
+const AnyCore = -1
+
 const (
-	ProgressLimit int           = 5
-	TaskSleepTime time.Duration = time.Duration(500 * time.Millisecond)
+	DefaultProgressLimit  int           = 5
+	DefaultTaskSleepTime  time.Duration = time.Duration(500 * time.Millisecond)
+	DefaultMaxActivations int           = 1
 )
 
 type Task struct {
 	ID            int
 	tType         TaskType
 	priority      TaskPriority
+	ceiling       TaskPriority
 	state         TaskState
 	progress      int
-	interruptChan chan struct{}
+	progressLimit int
+	sleepTime     time.Duration
+	deadline      time.Time
+	affinity      int
+	maxActivation int
+	resources     []string
+	points        []EventPoint
+	body          Body
+	builtin       bool
+	logger        *slog.Logger
+	run           *runState
 	DoneChan      chan struct{}
 	WaitChan      chan struct{}
-	EventChan     chan struct{}
+	YieldChan     chan struct{}
+	EventChan     chan *Task
+	ResourceChan  chan ResourceRequest
+}
+
+// runState is the part of a task shared with its body goroutine. Its mutex
+// also guards the priority, ceiling, state, progress and deadline of the
+// task, which the scheduler, the body and the API change concurrently. Every
+// dispatch by the scheduler starts a new generation with its own preempt
+// channel; the body tracks the generation it runs under.
+type runState struct {
+	mu         sync.Mutex
+	clock      clock.Clock
+	started    bool
+	stopped    chan struct{}
+	parked     bool
+	cancelled  bool
+	gen        int
+	preempt    chan struct{}
+	dispatched chan struct{}
+	cur        int
+	curPreempt chan struct{}
+	events     EventMask
+	waitMask   EventMask
+	err        error
+	pending    int
+}
+
+func newRunState() *runState {
+	return &runState{
+		clock:      clock.Real{},
+		preempt:    make(chan struct{}),
+		dispatched: make(chan struct{}),
+	}
+}
+
+// waitDispatch blocks the body until the task is dispatched again. The body
+// goroutine exits here once the task is cancelled.
+func (r *runState) waitDispatch() {
+	for {
+		r.mu.Lock()
+		if r.cancelled {
+			r.cancelled, r.started = false, false
+			r.mu.Unlock()
+			runtime.Goexit()
+		}
+		if r.gen > r.cur {
+			r.cur, r.curPreempt = r.gen, r.preempt
+			r.mu.Unlock()
+			return
+		}
+		ch := r.dispatched
+		r.parked = true
+		r.mu.Unlock()
+		r.clock.Idle()
+		<-ch
+	}
+}
+
+// wake marks a parked body busy again before it is released. It is called
+// with mu held.
+func (r *runState) wake() {
+	if r.parked {
+		r.parked = false
+		r.clock.Busy()
+	}
+}
+
+func (r *runState) getClock() clock.Clock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clock
+}
+
+// running returns the preempt channel of the current dispatch, waiting for
+// the next dispatch first if the body has been preempted.
+func (r *runState) running() <-chan struct{} {
+	for {
+		r.mu.Lock()
+		p := r.curPreempt
+		r.mu.Unlock()
+		if !utils.IsChannelClosed(p) {
+			return p
+		}
+		r.waitDispatch()
+	}
+}
+
+func (r *runState) done() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.curPreempt
+}
+
+type Option func(*Task)
+
+// WithProgressLimit sets the number of work units the task does per run.
+func WithProgressLimit(limit int) Option {
+	return func(t *Task) {
+		t.progressLimit = limit
+	}
+}
+
+// WithSleepTime sets the duration of a single work unit.
+func WithSleepTime(d time.Duration) Option {
+	return func(t *Task) {
+		t.sleepTime = d
+	}
+}
+
+// WithDeadline sets the absolute deadline used by deadline-driven policies.
+func WithDeadline(deadline time.Time) Option {
+	return func(t *Task) {
+		t.deadline = deadline
+	}
+}
+
+// WithAffinity pins the task to a core of a multi-core scheduler.
+func WithAffinity(core int) Option {
+	return func(t *Task) {
+		t.affinity = core
+	}
+}
+
+// WithMaxActivations sets how many activations of the task may be pending at
+// once, counting the current one.
+func WithMaxActivations(n int) Option {
+	return func(t *Task) {
+		t.maxActivation = n
+	}
+}
+
+// WithResources declares the resources the task may take. The scheduler
+// computes the ceiling priority of a resource from the tasks using it.
+func WithResources(names ...string) Option {
+	return func(t *Task) {
+		t.resources = slices.Clone(names)
+	}
+}
+
+// WithLogger makes the task log to l instead of the logger of its scheduler.
+func WithLogger(l *slog.Logger) Option {
+	return func(t *Task) {
+		t.logger = l
+	}
+}
+
+// WithEventPoints replaces the halfway event of the built-in body with the
+// given points.
+func WithEventPoints(points ...EventPoint) Option {
+	return func(t *Task) {
+		t.points = slices.Clone(points)
+		slices.SortStableFunc(t.points, func(a, b EventPoint) int {
+			return a.At - b.At
+		})
+	}
 }
 
 var nextTaskID = 0
 var mu sync.Mutex
 
-func New(tType TaskType, priority TaskPriority, state TaskState) (*Task, error) {
+func New(tType TaskType, priority TaskPriority, state TaskState, opts ...Option) (*Task, error) {
+	return newTask(tType, priority, state, nil, opts...)
+}
+
+// NewWithFunc creates a suspended task that runs body instead of the
+// built-in progress loop.
+func NewWithFunc(tType TaskType, priority TaskPriority, body Body, opts ...Option) (*Task, error) {
+	if body == nil {
+		return nil, ErrInvalidBody
+	}
+	return newTask(tType, priority, Suspended, body, opts...)
+}
+
+func newTask(tType TaskType, priority TaskPriority, state TaskState, body Body, opts ...Option) (*Task, error) {
 	mu.Lock()
 	defer mu.Unlock()
-	t := Task{ID: nextTaskID}
+	t, err := build(nextTaskID, tType, priority, state, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	nextTaskID++
+	return t, nil
+}
+
+// build gives tasks without a body the built-in one.
+func build(id int, tType TaskType, priority TaskPriority, state TaskState, body Body, opts ...Option) (*Task, error) {
+	t := Task{ID: id, progressLimit: DefaultProgressLimit, sleepTime: DefaultTaskSleepTime, affinity: AnyCore, ceiling: -1, maxActivation: DefaultMaxActivations, body: body, builtin: body == nil, run: newRunState()}
+	if t.builtin {
+		t.body = defaultBody
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	if t.progressLimit <= 0 {
+		return nil, ErrInvalidProgressLimit
+	}
+	if t.sleepTime <= 0 {
+		return nil, ErrInvalidSleepTime
+	}
+	if t.affinity < AnyCore {
+		return nil, ErrInvalidAffinity
+	}
+	if t.maxActivation <= 0 {
+		return nil, ErrInvalidMaxActivations
+	}
+	for i, r := range t.resources {
+		if r == "" || slices.Contains(t.resources[:i], r) {
+			return nil, ErrInvalidResource
+		}
+	}
+	for _, p := range t.points {
+		if p.At < 0 || p.At >= t.progressLimit || (p.Set != 0 && p.Target == nil) {
+			return nil, ErrInvalidEventPoint
+		}
+		if p.Wait != 0 && tType != Extended {
+			return nil, ErrNotExtended
+		}
+	}
 	if err := t.SetPriority(priority); err != nil {
 		return nil, err
 	}
@@ -69,51 +297,244 @@ func New(tType TaskType, priority TaskPriority, state TaskState) (*Task, error)
 	if err := t.SetType(tType); err != nil {
 		return nil, err
 	}
-	nextTaskID++
-	t.interruptChan = make(chan struct{}, 2)
 	t.DoneChan = make(chan struct{})
 	t.WaitChan = make(chan struct{})
-	t.EventChan = make(chan struct{}, 1)
+	t.YieldChan = make(chan struct{})
+	t.EventChan = make(chan *Task)
+	t.ResourceChan = make(chan ResourceRequest)
 	return &t, nil
 }
 
+// Do starts the task body on its first call and resumes it afterwards.
+// It does not wait for the body.
 func (t *Task) Do() {
+	t.run.mu.Lock()
+	t.run.gen++
+	t.run.preempt = make(chan struct{})
+	t.run.wake()
+	close(t.run.dispatched)
+	t.run.dispatched = make(chan struct{})
+	started := t.run.started
+	t.run.started = true
+	if !started {
+		t.run.stopped = make(chan struct{})
+	}
+	clk, stopped := t.run.clock, t.run.stopped
+	t.run.mu.Unlock()
+	if !started {
+		clk.Busy()
+		go t.exec(stopped)
+	}
+}
+
+// Interrupt preempts the current dispatch. Bodies observe it through
+// Context.Done and stop at their next Context call.
+func (t *Task) Interrupt() {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if !utils.IsChannelClosed(t.run.preempt) {
+		close(t.run.preempt)
+	}
+}
+
+// Cancel ends the body of a task the scheduler no longer runs. A body parked
+// in Yield or WaitEvent exits there, running its deferred calls; a preempted
+// body exits at its next Context call. The next Do starts the body anew.
+func (t *Task) Cancel() {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if !t.run.started {
+		return
+	}
+	t.run.cancelled = true
+	t.run.wake()
+	close(t.run.dispatched)
+	t.run.dispatched = make(chan struct{})
+}
+
+func (t *Task) exec(stopped chan struct{}) {
+	defer close(stopped)
+	defer t.run.getClock().Idle()
+	t.run.waitDispatch()
+	err := t.body(&taskContext{task: t})
+	t.run.mu.Lock()
+	t.run.err = err
+	t.run.mu.Unlock()
+	if err != nil {
+		t.logAt(slog.LevelError, "task failed", "error", err)
+	}
+	t.signal(t.DoneChan)
+	t.run.mu.Lock()
+	t.run.started = false
+	t.run.mu.Unlock()
+}
+
+// signal sends to a scheduler channel only while the task is running, so the
+// scheduler never takes it for a signal of another task.
+// The scheduler is marked busy before it can take the signal.
+func (t *Task) signal(ch chan struct{}) {
+	clk := t.run.getClock()
 	for {
+		preempt := t.run.running()
+		clk.Busy()
 		select {
-		case <-t.interruptChan:
-			if t.tType == Extended {
-				log.Printf("ext-task-%d | interruption\n", t.ID)
-			} else {
-				log.Printf("bsc-task-%d | interruption\n", t.ID)
-			}
+		case ch <- struct{}{}:
 			return
-		default:
-			if t.progress >= ProgressLimit {
-				t.DoneChan <- struct{}{}
-				return
-			}
-			if t.tType == Extended && t.progress == ProgressLimit/2 && !utils.IsChannelClosed(t.WaitChan) {
-				log.Printf("ext-task-%d | waiting\n", t.ID)
-				t.WaitChan <- struct{}{}
-				return
-			}
-			if t.tType == Basic && t.progress == ProgressLimit/2 && !utils.IsChannelClosed(t.EventChan) {
-				log.Printf("bsc-task-%d | event\n", t.ID)
-				t.EventChan <- struct{}{}
-			}
-			t.progress++
-			if t.tType == Extended {
-				log.Printf("ext-task-%d | progress: %d/%d | p=%d\n", t.ID, t.progress, ProgressLimit, t.priority)
-			} else {
-				log.Printf("bsc-task-%d | progress: %d/%d | p=%d\n", t.ID, t.progress, ProgressLimit, t.priority)
-			}
-			time.Sleep(time.Duration(TaskSleepTime))
+		case <-preempt:
+			clk.Idle()
 		}
 	}
 }
 
-func (t *Task) Interrupt() {
-	t.interruptChan <- struct{}{}
+// signalEvent hands the event to the scheduler and lets it handle the event
+// before the body goes on.
+func (t *Task) signalEvent(target *Task) {
+	clk := t.run.getClock()
+	for {
+		preempt := t.run.running()
+		clk.Busy()
+		select {
+		case t.EventChan <- target:
+			clk.Sleep(0)
+			return
+		case <-preempt:
+			clk.Idle()
+		}
+	}
+}
+
+// SetClock sets the clock the body runs on. The scheduler sets its own clock
+// on the tasks added to it.
+func (t *Task) SetClock(c clock.Clock) {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.run.clock = c
+}
+
+// InheritLogger makes the task log to l unless it got a logger with
+// WithLogger. The scheduler gives its logger to the tasks added to it; tasks
+// without a logger log nothing.
+func (t *Task) InheritLogger(l *slog.Logger) {
+	if t.logger == nil {
+		t.logger = l
+	}
+}
+
+// LogAttrs are the attributes of every message about the task.
+func (t *Task) LogAttrs() []any {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return []any{
+		slog.Int("task_id", t.ID),
+		slog.String("type", string(t.tType)),
+		slog.Int("priority", int(t.priority)),
+		slog.String("state", string(t.state)),
+		slog.Int("progress", t.progress),
+	}
+}
+
+func (t *Task) logAt(level slog.Level, msg string, args ...any) {
+	if t.logger == nil || !t.logger.Enabled(context.Background(), level) {
+		return
+	}
+	t.logger.Log(context.Background(), level, msg, append(t.LogAttrs(), args...)...)
+}
+
+// QueueActivation queues another run of the task while it is queued or
+// running. It fails with ErrActivationLimit once the max activations are
+// pending.
+func (t *Task) QueueActivation() error {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if t.run.pending+1 >= t.maxActivation {
+		return ErrActivationLimit
+	}
+	t.run.pending++
+	return nil
+}
+
+// NextActivation takes a queued activation and reports whether there was one.
+func (t *Task) NextActivation() bool {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if t.run.pending == 0 {
+		return false
+	}
+	t.run.pending--
+	return true
+}
+
+// GetActivations returns the number of activations queued behind the current one.
+func (t *Task) GetActivations() int {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.run.pending
+}
+
+func (t *Task) GetMaxActivations() int {
+	return t.maxActivation
+}
+
+// Stopped returns a channel that is closed once the body of the last run has
+// exited. It is closed already for tasks whose body never ran.
+func (t *Task) Stopped() <-chan struct{} {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	if t.run.stopped == nil {
+		t.run.stopped = make(chan struct{})
+		close(t.run.stopped)
+	}
+	return t.run.stopped
+}
+
+// Reset makes a done or cancelled task start over: it clears the progress,
+// the events and the error. The body of the last run has to have stopped, so
+// it does not change them again and the next Do runs it anew.
+func (t *Task) Reset() {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.progress = 0
+	t.run.events, t.run.waitMask = 0, 0
+	t.run.err = nil
+}
+
+// Err returns the error returned by the task body of the last run.
+func (t *Task) Err() error {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.run.err
+}
+
+func (t *Task) SetEvent(mask EventMask) {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.run.events |= mask
+}
+
+func (t *Task) ClearEvent(mask EventMask) {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.run.events &^= mask
+}
+
+func (t *Task) GetEvent() EventMask {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.run.events
+}
+
+// GetWaitMask returns the events the task waits for, zero if it does not wait.
+func (t *Task) GetWaitMask() EventMask {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.run.waitMask
+}
+
+// IsReleased reports whether the task waits for events and one of them is set.
+func (t *Task) IsReleased() bool {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.run.events&t.run.waitMask != 0
 }
 
 func (t *Task) SetType(newType TaskType) error {
@@ -132,23 +553,93 @@ func (t *Task) SetPriority(newPriority TaskPriority) error {
 	if !isValidPriority(newPriority) {
 		return ErrInvalidPriority
 	}
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
 	t.priority = newPriority
 	return nil
 }
 
+// GetPriority returns the priority the task runs at: its own priority, raised
+// to the ceiling of the resources it holds.
 func (t *Task) GetPriority() TaskPriority {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return max(t.priority, t.ceiling)
+}
+
+// GetBasePriority returns the priority of the task without resource ceilings.
+func (t *Task) GetBasePriority() TaskPriority {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
 	return t.priority
 }
 
+// SetCeiling raises the priority of the task to p while it holds resources.
+// A negative p drops the ceiling.
+func (t *Task) SetCeiling(p TaskPriority) {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.ceiling = p
+}
+
+// GetResources returns the resources the task may take.
+func (t *Task) GetResources() []string {
+	return t.resources
+}
+
+// UsesResource reports whether the task declared the resource.
+func (t *Task) UsesResource(name string) bool {
+	return slices.Contains(t.resources, name)
+}
+
+func (t *Task) SetDeadline(deadline time.Time) {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.deadline = deadline
+}
+
+// GetDeadline returns the zero time for tasks without a deadline.
+func (t *Task) GetDeadline() time.Time {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.deadline
+}
+
+// GetAffinity returns the core the task is pinned to or AnyCore.
+func (t *Task) GetAffinity() int {
+	return t.affinity
+}
+
+func (t *Task) GetProgress() int {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	return t.progress
+}
+
+// advance counts a work unit done.
+func (t *Task) advance() {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
+	t.progress++
+}
+
+func (t *Task) GetProgressLimit() int {
+	return t.progressLimit
+}
+
 func (t *Task) SetState(newState TaskState) error {
 	if !isValidState(newState) {
 		return ErrInvalidState
 	}
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
 	t.state = newState
 	return nil
 }
 
 func (t *Task) GetState() TaskState {
+	t.run.mu.Lock()
+	defer t.run.mu.Unlock()
 	return t.state
 }
 
@@ -165,19 +656,5 @@ func isValidType(newType TaskType) bool {
 }
 
 func isValidPriority(newP TaskPriority) bool {
-	return newP >= P0 && newP <= P3
-}
-
-func (t *Task) Copy() *Task {
-	return &Task{
-		ID:            t.ID,
-		tType:         t.tType,
-		priority:      t.priority,
-		state:         t.state,
-		progress:      t.progress,
-		interruptChan: make(chan struct{}),
-		DoneChan:      make(chan struct{}),
-		WaitChan:      make(chan struct{}),
-		EventChan:     make(chan struct{}, 1),
-	}
+	return newP >= P0 && newP < MaxPriorityLevels
 }
@@ -1,6 +1,10 @@
 package task
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"testing"
 	"time"
 
@@ -16,6 +20,35 @@ func TestNewTask(t *testing.T) {
 	assert.Equal(t, Ready, task.GetState())
 }
 
+func TestNewTaskWithOptions(t *testing.T) {
+	task, err := New(Basic, P1, Ready, WithProgressLimit(10), WithSleepTime(time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, task.GetProgressLimit())
+	assert.Equal(t, time.Millisecond, task.sleepTime)
+
+	deadline := time.Now().Add(time.Second)
+	task, err = New(Basic, P1, Ready, WithDeadline(deadline))
+	assert.NoError(t, err)
+	assert.Equal(t, deadline, task.GetDeadline())
+	assert.Equal(t, AnyCore, task.GetAffinity())
+
+	task, err = New(Basic, P1, Ready, WithAffinity(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, task.GetAffinity())
+
+	_, err = New(Basic, P1, Ready, WithAffinity(-2))
+	assert.ErrorIs(t, err, ErrInvalidAffinity)
+
+	_, err = New(Basic, P1, Ready, WithProgressLimit(0))
+	assert.ErrorIs(t, err, ErrInvalidProgressLimit)
+
+	_, err = New(Basic, P1, Ready, WithSleepTime(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidSleepTime)
+
+	_, err = New(Basic, P1, Ready, WithMaxActivations(0))
+	assert.ErrorIs(t, err, ErrInvalidMaxActivations)
+}
+
 func TestSetType(t *testing.T) {
 	task, err := New(Basic, P1, Ready)
 	assert.NoError(t, err)
@@ -36,7 +69,11 @@ func TestSetPriority(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, TaskPriority(P2), task.GetPriority())
 
-	err = task.SetPriority(10)
+	err = task.SetPriority(200)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskPriority(200), task.GetPriority())
+
+	err = task.SetPriority(MaxPriorityLevels)
 	assert.Error(t, err)
 }
 
@@ -57,11 +94,11 @@ func TestDo(t *testing.T) {
 	assert.NoError(t, err)
 
 	go task.Do()
-	time.Sleep(TaskSleepTime * 3)
+	time.Sleep(DefaultTaskSleepTime * 3)
 	task.Interrupt()
-	time.Sleep(TaskSleepTime)
+	time.Sleep(DefaultTaskSleepTime)
 
-	assert.GreaterOrEqual(t, task.progress, 2)
+	assert.GreaterOrEqual(t, task.GetProgress(), 2)
 }
 
 func TestDoExtended(t *testing.T) {
@@ -69,8 +106,8 @@ func TestDoExtended(t *testing.T) {
 	assert.NoError(t, err)
 
 	go task.Do()
-	time.Sleep(TaskSleepTime * 3)
-	assert.GreaterOrEqual(t, task.progress, 2)
+	time.Sleep(DefaultTaskSleepTime * 3)
+	assert.GreaterOrEqual(t, task.GetProgress(), 2)
 	select {
 	case <-task.WaitChan:
 	default:
@@ -83,23 +120,328 @@ func TestInterrupt(t *testing.T) {
 	assert.NoError(t, err)
 
 	go task.Do()
-	time.Sleep(TaskSleepTime / 2)
+	time.Sleep(DefaultTaskSleepTime / 2)
 	task.Interrupt()
-	time.Sleep(TaskSleepTime)
+	time.Sleep(DefaultTaskSleepTime)
 
-	assert.Equal(t, 1, task.progress)
+	assert.Equal(t, 1, task.GetProgress())
 }
 
-func TestCopy(t *testing.T) {
-	task, err := New(Basic, P1, Ready)
+func TestNewWithFunc(t *testing.T) {
+	errBody := errors.New("body failed")
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		return errBody
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Suspended, task.GetState())
+
+	task.Do()
+	select {
+	case <-task.DoneChan:
+	case <-time.After(time.Second):
+		t.Fatal("task body did not finish")
+	}
+	assert.ErrorIs(t, task.Err(), errBody)
+
+	_, err = NewWithFunc(Basic, P1, nil)
+	assert.ErrorIs(t, err, ErrInvalidBody)
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	task, err := NewWithFunc(Extended, P2, func(ctx Context) error {
+		return errors.New("body failed")
+	}, WithLogger(logger))
+	assert.NoError(t, err)
+	task.InheritLogger(slog.New(slog.DiscardHandler))
+
+	task.Do()
+	<-task.DoneChan
+	var msg map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &msg))
+	delete(msg, "time")
+	assert.Equal(t, map[string]any{
+		"level": "ERROR", "msg": "task failed", "error": "body failed",
+		"task_id": float64(task.ID), "type": "extended", "priority": float64(P2), "state": "suspended", "progress": float64(0),
+	}, msg)
+
+	silent, err := New(Basic, P1, Suspended)
+	assert.NoError(t, err)
+	silent.logAt(slog.LevelError, "not logged")
+	silent.InheritLogger(logger)
+	assert.Same(t, logger, silent.logger)
+}
+
+func TestContextDone(t *testing.T) {
+	steps := make(chan int)
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		for i := 0; i < 2; i++ {
+			<-ctx.Done()
+			ctx.Yield()
+			steps <- i
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	task.Do()
+	task.Interrupt()
+	select {
+	case <-steps:
+		t.Fatal("preempted task resumed without being dispatched")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	task.Do()
+	assert.Equal(t, 0, <-steps)
+	task.Interrupt()
+	task.Do()
+	assert.Equal(t, 1, <-steps)
+	<-task.DoneChan
+}
+
+func TestContextYield(t *testing.T) {
+	resumed := make(chan struct{})
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		ctx.Yield()
+		close(resumed)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	task.Do()
+	<-task.YieldChan
+	select {
+	case <-resumed:
+		t.Fatal("yielded task resumed without being dispatched")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	task.Do()
+	<-resumed
+	<-task.DoneChan
+}
+
+func TestCancel(t *testing.T) {
+	exited := make(chan struct{})
+	runs := 0
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		defer close(exited)
+		runs++
+		if runs == 1 {
+			ctx.Yield()
+			t.Error("cancelled task resumed")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	task.Do()
+	<-task.YieldChan
+	task.Cancel()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("cancelled task body did not exit")
+	}
+
+	exited = make(chan struct{})
+	task.Do()
+	<-task.DoneChan
+	assert.Equal(t, 2, runs)
+}
+
+func TestActivations(t *testing.T) {
+	task, err := New(Extended, P1, Suspended, WithProgressLimit(2), WithSleepTime(time.Millisecond), WithMaxActivations(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, task.GetMaxActivations())
+	assert.False(t, task.NextActivation())
+	assert.NoError(t, task.QueueActivation())
+	assert.NoError(t, task.QueueActivation())
+	assert.ErrorIs(t, task.QueueActivation(), ErrActivationLimit)
+	assert.Equal(t, 2, task.GetActivations())
+	assert.True(t, task.NextActivation())
+	assert.Equal(t, 1, task.GetActivations())
+
+	task.SetEvent(DefaultEvent)
+	task.Do()
+	<-task.DoneChan
+	assert.Equal(t, 2, task.GetProgress())
+
+	<-task.Stopped()
+	task.Reset()
+	assert.Zero(t, task.GetProgress())
+	assert.Zero(t, task.GetEvent())
+	task.SetEvent(DefaultEvent)
+	task.Do()
+	<-task.DoneChan
+	assert.Equal(t, 2, task.GetProgress())
+}
+
+func TestResources(t *testing.T) {
+	_, err := New(Basic, P1, Suspended, WithResources("a", "a"))
+	assert.ErrorIs(t, err, ErrInvalidResource)
+	_, err = New(Basic, P1, Suspended, WithResources(""))
+	assert.ErrorIs(t, err, ErrInvalidResource)
+
+	task, err := New(Basic, P1, Suspended, WithResources("a", "b"))
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, task.GetResources())
+	assert.True(t, task.UsesResource("b"))
+	assert.False(t, task.UsesResource("c"))
 
-	taskCopy := task.Copy()
-	assert.Equal(t, task.ID, taskCopy.ID)
-	assert.Equal(t, task.GetType(), taskCopy.GetType())
-	assert.Equal(t, task.GetPriority(), taskCopy.GetPriority())
-	assert.Equal(t, task.GetState(), taskCopy.GetState())
-	assert.Equal(t, task.progress, taskCopy.progress)
+	task.SetCeiling(P3)
+	assert.Equal(t, TaskPriority(P3), task.GetPriority())
+	assert.Equal(t, TaskPriority(P1), task.GetBasePriority())
+	assert.Equal(t, TaskPriority(P1), task.Snapshot().Priority)
+	task.SetCeiling(-1)
+	assert.Equal(t, TaskPriority(P1), task.GetPriority())
+}
+
+func TestContextResource(t *testing.T) {
+	var getErr, releaseErr error
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		getErr = ctx.GetResource("a")
+		releaseErr = ctx.ReleaseResource("a")
+		return nil
+	}, WithResources("a"))
+	assert.NoError(t, err)
+
+	task.Do()
+	req := <-task.ResourceChan
+	assert.Equal(t, "a", req.Name)
+	assert.False(t, req.Release)
+	req.Reply(nil)
+	req = <-task.ResourceChan
+	assert.True(t, req.Release)
+	req.Reply(ErrInvalidResource)
+	<-task.DoneChan
+	assert.NoError(t, getErr)
+	assert.ErrorIs(t, releaseErr, ErrInvalidResource)
+}
+
+func TestContextWaitEvent(t *testing.T) {
+	var got EventMask
+	task, err := NewWithFunc(Extended, P2, func(ctx Context) error {
+		ev, err := ctx.WaitEvent(0b110)
+		got = ev
+		return err
+	})
+	assert.NoError(t, err)
+
+	task.Do()
+	<-task.WaitChan
+	assert.False(t, task.IsReleased())
+
+	task.SetEvent(0b001)
+	assert.False(t, task.IsReleased())
+	task.SetEvent(0b100)
+	assert.True(t, task.IsReleased())
+	assert.Equal(t, EventMask(0b101), task.GetEvent())
+
+	task.Do()
+	<-task.DoneChan
+	assert.NoError(t, task.Err())
+	assert.Equal(t, EventMask(0b100), got)
+}
+
+func TestContextSetEvent(t *testing.T) {
+	waiter, err := New(Extended, P2, Suspended)
+	assert.NoError(t, err)
+	basic, err := New(Basic, P2, Suspended)
+	assert.NoError(t, err)
+
+	var setErr, waitErr error
+	task, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		if err := ctx.SetEvent(waiter, 0b10); err != nil {
+			return err
+		}
+		setErr = ctx.SetEvent(basic, 0b10)
+		_, waitErr = ctx.WaitEvent(0b10)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	task.Do()
+	assert.Equal(t, waiter, <-task.EventChan)
+	<-task.DoneChan
+	assert.Equal(t, EventMask(0b10), waiter.GetEvent())
+	assert.ErrorIs(t, setErr, ErrNotExtended)
+	assert.ErrorIs(t, waitErr, ErrNotExtended)
+}
+
+func TestContextClearEvent(t *testing.T) {
+	var got EventMask
+	var clearErr error
+	ext, err := NewWithFunc(Extended, P1, func(ctx Context) error {
+		if _, err := ctx.WaitEvent(0b01); err != nil {
+			return err
+		}
+		if err := ctx.ClearEvent(0b01); err != nil {
+			return err
+		}
+		got = ctx.Task().GetEvent()
+		return nil
+	})
+	assert.NoError(t, err)
+	basic, err := NewWithFunc(Basic, P1, func(ctx Context) error {
+		clearErr = ctx.ClearEvent(0b01)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	ext.SetEvent(0b11)
+	ext.Do()
+	<-ext.DoneChan
+	assert.Equal(t, EventMask(0b10), got)
+
+	basic.Do()
+	<-basic.DoneChan
+	assert.ErrorIs(t, clearErr, ErrNotExtended)
+}
+
+func TestEventPoints(t *testing.T) {
+	ext, err := New(Extended, P2, Suspended, WithProgressLimit(3), WithSleepTime(time.Millisecond),
+		WithEventPoints(EventPoint{At: 1, Wait: 0b10}))
+	assert.NoError(t, err)
+	basic, err := New(Basic, P1, Suspended, WithProgressLimit(2), WithSleepTime(time.Millisecond),
+		WithEventPoints(EventPoint{At: 1, Set: 0b10, Target: func() *Task { return ext }}))
+	assert.NoError(t, err)
+
+	ext.Do()
+	<-ext.WaitChan
+	assert.Equal(t, 1, ext.GetProgress())
+	assert.Equal(t, EventMask(0b10), ext.GetWaitMask())
+
+	basic.Do()
+	assert.Equal(t, ext, <-basic.EventChan)
+	<-basic.DoneChan
+	assert.True(t, ext.IsReleased())
+
+	ext.Do()
+	<-ext.DoneChan
+	assert.NoError(t, ext.Err())
+	assert.Equal(t, 3, ext.GetProgress())
+	assert.Equal(t, EventMask(0), ext.GetEvent())
+
+	tests := []struct {
+		name  string
+		tType TaskType
+		point EventPoint
+		err   error
+	}{
+		{name: "Before the first unit", tType: Extended, point: EventPoint{At: -1}, err: ErrInvalidEventPoint},
+		{name: "After the last unit", tType: Extended, point: EventPoint{At: DefaultProgressLimit}, err: ErrInvalidEventPoint},
+		{name: "Set without target", tType: Basic, point: EventPoint{Set: 1}, err: ErrInvalidEventPoint},
+		{name: "Basic task waits", tType: Basic, point: EventPoint{Wait: 1}, err: ErrNotExtended},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.tType, P1, Suspended, WithEventPoints(tt.point))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
 }
 
 func TestIsValidState(t *testing.T) {
@@ -121,5 +463,7 @@ func TestIsValidPriority(t *testing.T) {
 	assert.True(t, isValidPriority(P1))
 	assert.True(t, isValidPriority(P2))
 	assert.True(t, isValidPriority(P3))
-	assert.False(t, isValidPriority(10))
+	assert.True(t, isValidPriority(MaxPriorityLevels-1))
+	assert.False(t, isValidPriority(MaxPriorityLevels))
+	assert.False(t, isValidPriority(-1))
 }
@@ -0,0 +1,11 @@
+package taskset
+
+import "errors"
+
+var (
+	ErrMissingName        = errors.New("task has no name")
+	ErrDuplicateName      = errors.New("task name is used twice")
+	ErrUnknownTarget      = errors.New("event point sets events on an unknown task")
+	ErrInvalidArrival     = errors.New("arrival and period must not be negative")
+	ErrInvalidActivations = errors.New("activations must not be negative")
+)
@@ -0,0 +1,186 @@
+package taskset
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Feed adds the activations of a set to a scheduler at their arrival times.
+type Feed struct {
+	arrivals []arrival
+	tasks    map[string][]*task.Task
+	mu       sync.Mutex
+	arrived  map[string]*task.Task
+	err      error
+	DoneChan chan struct{}
+}
+
+type arrival struct {
+	at       time.Duration
+	name     string
+	deadline time.Duration
+	task     *task.Task
+}
+
+// NewFeed builds every activation of set. Task IDs follow the arrival order,
+// activations arriving at the same time keep the order of the set.
+func NewFeed(set Set) (*Feed, error) {
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	f := Feed{tasks: make(map[string][]*task.Task), arrived: make(map[string]*task.Task), DoneChan: make(chan struct{})}
+	for _, spec := range set.Tasks {
+		for i := 0; i < spec.activations(); i++ {
+			f.arrivals = append(f.arrivals, arrival{at: spec.Arrival + time.Duration(i)*spec.Period, name: spec.Name, deadline: spec.Deadline})
+		}
+	}
+	slices.SortStableFunc(f.arrivals, func(a, b arrival) int {
+		return cmp.Compare(a.at, b.at)
+	})
+	specs := make(map[string]Spec, len(set.Tasks))
+	for _, spec := range set.Tasks {
+		specs[spec.Name] = spec
+	}
+	for i := range f.arrivals {
+		a := &f.arrivals[i]
+		spec := specs[a.name]
+		t, err := task.New(spec.Type, spec.Priority, task.Suspended, f.options(spec)...)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", spec.Name, err)
+		}
+		a.task = t
+		f.tasks[spec.Name] = append(f.tasks[spec.Name], t)
+	}
+	return &f, nil
+}
+
+// Arrival is a built task and the time after the start of a feed it arrives.
+type Arrival struct {
+	At   time.Duration
+	Task *task.Task
+}
+
+// NewFeedOf feeds tasks that have been built elsewhere, such as generated
+// ones. They have no names.
+func NewFeedOf(arrivals []Arrival) *Feed {
+	f := Feed{tasks: make(map[string][]*task.Task), arrived: make(map[string]*task.Task), DoneChan: make(chan struct{})}
+	for _, a := range arrivals {
+		f.arrivals = append(f.arrivals, arrival{at: a.At, task: a.Task})
+	}
+	slices.SortStableFunc(f.arrivals, func(a, b arrival) int {
+		return cmp.Compare(a.at, b.at)
+	})
+	return &f
+}
+
+func (f *Feed) options(s Spec) []task.Option {
+	opts := make([]task.Option, 0)
+	if s.WorkUnits != 0 {
+		opts = append(opts, task.WithProgressLimit(s.WorkUnits))
+	}
+	if s.UnitTime != 0 {
+		opts = append(opts, task.WithSleepTime(s.UnitTime))
+	}
+	if s.Affinity != nil {
+		opts = append(opts, task.WithAffinity(*s.Affinity))
+	}
+	points := make([]task.EventPoint, 0, len(s.Events))
+	for _, e := range s.Events {
+		p := task.EventPoint{At: e.At, Wait: e.Wait, Set: e.Set}
+		if e.Task != "" {
+			name := e.Task
+			p.Target = func() *task.Task { return f.latest(name) }
+		}
+		points = append(points, p)
+	}
+	if len(points) > 0 {
+		opts = append(opts, task.WithEventPoints(points...))
+	}
+	return opts
+}
+
+// latest returns the latest activation of the named task that has arrived.
+func (f *Feed) latest(name string) *task.Task {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.arrived[name]
+}
+
+// Tasks returns the activations of the named task in arrival order.
+func (f *Feed) Tasks(name string) []*task.Task {
+	return f.tasks[name]
+}
+
+// All returns every activation in arrival order.
+func (f *Feed) All() []*task.Task {
+	res := make([]*task.Task, 0, len(f.arrivals))
+	for _, a := range f.arrivals {
+		res = append(res, a.task)
+	}
+	return res
+}
+
+// Run adds the activations arriving at once before it returns and the others
+// from a goroutine, holding s meanwhile so it does not stop when idle.
+// DoneChan is closed after the last activation has been added, ctx is done or
+// adding failed.
+func (f *Feed) Run(ctx context.Context, s *scheduler.Scheduler) {
+	release := s.Hold()
+	clk := s.Config().Clock
+	start := clk.Now()
+	next := 0
+	for next < len(f.arrivals) && f.arrivals[next].at == 0 && f.err == nil {
+		f.err = f.add(s, f.arrivals[next], start)
+		next++
+	}
+	if f.err != nil || next == len(f.arrivals) {
+		release()
+		close(f.DoneChan)
+		return
+	}
+	clk.Busy()
+	go func() {
+		defer close(f.DoneChan)
+		defer release()
+		defer clk.Idle()
+		for _, a := range f.arrivals[next:] {
+			timer := clk.NewTimer(start.Add(a.at).Sub(clk.Now()))
+			clk.Idle()
+			select {
+			case <-ctx.Done():
+				clk.Busy()
+				timer.Stop()
+				f.err = ctx.Err()
+				return
+			case <-timer.C():
+			}
+			if f.err = f.add(s, a, start); f.err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (f *Feed) add(s *scheduler.Scheduler, a arrival, start time.Time) error {
+	if a.deadline != 0 {
+		a.task.SetDeadline(start.Add(a.at + a.deadline))
+	}
+	f.mu.Lock()
+	f.arrived[a.name] = a.task
+	f.mu.Unlock()
+	if err := s.AddNewTask(a.task); err != nil {
+		return fmt.Errorf("task %q: %w", a.name, err)
+	}
+	return nil
+}
+
+// Err returns why the feed stopped early. It is valid once DoneChan is closed.
+func (f *Feed) Err() error {
+	return f.err
+}
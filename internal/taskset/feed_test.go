@@ -0,0 +1,155 @@
+package taskset
+
+import (
+	"context"
+	"scheduler/internal/clock"
+	"scheduler/internal/scheduler"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runFeed runs the set in path to the end and returns the feed and the trace.
+func runFeed(t *testing.T, path string, opts ...scheduler.Option) (*Feed, []trace.Event) {
+	set, err := LoadFile(path)
+	assert.NoError(t, err)
+	feed, err := NewFeed(set)
+	assert.NoError(t, err)
+	sink := scheduler.NewRingSink(scheduler.DefaultRingSize)
+	opts = append([]scheduler.Option{scheduler.WithTickDuration(5 * time.Millisecond), scheduler.WithIdleExit(4), scheduler.WithDumpSink(sink)}, opts...)
+	s, err := scheduler.New(opts...)
+	assert.NoError(t, err)
+
+	feed.Run(context.Background(), s)
+	s.Run(context.Background())
+	select {
+	case <-s.StopChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+	<-feed.DoneChan
+	assert.NoError(t, feed.Err())
+	return feed, sink.Events()
+}
+
+func executionOrder(events []trace.Event) []int {
+	res := make([]int, 0)
+	for _, e := range events {
+		if e.To == task.Running && (len(res) == 0 || res[len(res)-1] != e.TaskID) {
+			res = append(res, e.TaskID)
+		}
+	}
+	return res
+}
+
+func TestFeed_Waiting(t *testing.T) {
+	feed, events := runFeed(t, "testdata/waiting.yaml")
+
+	first, waiter, setter := feed.Tasks("first")[0], feed.Tasks("waiter")[0], feed.Tasks("setter")[0]
+	assert.Equal(t, []int{first.ID, waiter.ID, setter.ID, waiter.ID, setter.ID}, executionOrder(events))
+	for _, tk := range feed.All() {
+		assert.Equal(t, task.Suspended, tk.GetState())
+		assert.Equal(t, task.DefaultProgressLimit, tk.GetProgress())
+	}
+}
+
+func TestFeed_Periodic(t *testing.T) {
+	start := time.Now()
+	feed, events := runFeed(t, "testdata/periodic.json")
+
+	sensor := feed.Tasks("sensor")[0]
+	triggers := feed.Tasks("trigger")
+	assert.Len(t, triggers, 2)
+	assert.Equal(t, []*task.Task{sensor, triggers[0], triggers[1]}, feed.All())
+	assert.Equal(t, []int{sensor.ID, triggers[0].ID, sensor.ID, triggers[0].ID, triggers[1].ID}, executionOrder(events))
+	assert.Equal(t, 3, sensor.GetProgress())
+	for i, tk := range triggers {
+		assert.Equal(t, 2, tk.GetProgress())
+		assert.WithinDuration(t, start.Add(time.Duration(30+60*i)*time.Millisecond+time.Second), tk.GetDeadline(), 20*time.Millisecond)
+	}
+
+	// The second trigger arrives after the first has finished.
+	for _, e := range events {
+		if e.TaskID == triggers[1].ID && e.From == "" {
+			assert.GreaterOrEqual(t, e.Time.Sub(events[0].Time), 80*time.Millisecond)
+		}
+	}
+}
+
+func TestFeed_VirtualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed, events := runFeed(t, "testdata/periodic.json", scheduler.WithClock(clock.NewVirtual(start)))
+
+	triggers := feed.Tasks("trigger")
+	for i, tk := range triggers {
+		arrival := start.Add(time.Duration(30+60*i) * time.Millisecond)
+		assert.Equal(t, arrival.Add(time.Second), tk.GetDeadline())
+		for _, e := range events {
+			if e.TaskID == tk.ID && e.From == "" {
+				assert.Equal(t, arrival, e.Time)
+			}
+		}
+	}
+	assert.Equal(t, start.Add(100*time.Millisecond), events[len(events)-1].Time)
+}
+
+func TestFeed_Stop(t *testing.T) {
+	feed, err := NewFeed(Set{Tasks: []Spec{{Name: "late", Type: task.Basic, Arrival: time.Hour}}})
+	assert.NoError(t, err)
+	s, err := scheduler.New(scheduler.WithTickDuration(time.Millisecond), scheduler.WithIdleExit(2))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	feed.Run(ctx, s)
+	s.Run(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-s.StopChan:
+		t.Fatal("scheduler stopped before the last arrival")
+	default:
+	}
+
+	cancel()
+	<-feed.DoneChan
+	assert.ErrorIs(t, feed.Err(), context.Canceled)
+	<-s.StopChan
+	assert.Equal(t, task.Suspended, feed.Tasks("late")[0].GetState())
+}
+
+func TestNewFeed(t *testing.T) {
+	_, err := NewFeed(Set{Tasks: []Spec{{Name: "a", Type: task.Basic, WorkUnits: -1}}})
+	assert.ErrorIs(t, err, task.ErrInvalidProgressLimit)
+	_, err = NewFeed(Set{Tasks: []Spec{{Name: "a", Type: task.Basic, Events: []Event{{Wait: 1}}}}})
+	assert.ErrorIs(t, err, task.ErrNotExtended)
+
+	feed, err := NewFeed(Set{Tasks: []Spec{{Name: "a", Type: task.Basic, Priority: task.P3}}})
+	assert.NoError(t, err)
+	s, err := scheduler.New(scheduler.WithPriorityLevels(2))
+	assert.NoError(t, err)
+	feed.Run(context.Background(), s)
+	<-feed.DoneChan
+	assert.ErrorIs(t, feed.Err(), scheduler.ErrInvalidPriority)
+}
+
+func TestNewFeedOf(t *testing.T) {
+	build := func(at time.Duration) Arrival {
+		tk, err := task.New(task.Basic, task.P1, task.Suspended, task.WithProgressLimit(1), task.WithSleepTime(time.Millisecond))
+		assert.NoError(t, err)
+		return Arrival{At: at, Task: tk}
+	}
+	late, early := build(20*time.Millisecond), build(0)
+	feed := NewFeedOf([]Arrival{late, early})
+	assert.Equal(t, []*task.Task{early.Task, late.Task}, feed.All())
+
+	s, err := scheduler.New(scheduler.WithTickDuration(time.Millisecond), scheduler.WithIdleExit(2))
+	assert.NoError(t, err)
+	feed.Run(context.Background(), s)
+	s.Run(context.Background())
+	<-s.StopChan
+	<-feed.DoneChan
+	assert.NoError(t, feed.Err())
+	assert.Equal(t, 1, late.Task.GetProgress())
+}
@@ -0,0 +1,133 @@
+// Package taskset reads scenarios of named tasks from YAML or JSON files and
+// feeds them into a scheduler at their arrival times.
+package taskset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"scheduler/internal/task"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set is a scenario. JSON files are read as YAML, which they are a subset of.
+//
+//	tasks:
+//	  - name: sensor
+//	    type: extended
+//	    priority: 2
+//	    work_units: 4
+//	    unit_time: 10ms
+//	    events:
+//	      - {at: 2, wait: 1}
+//	  - name: trigger
+//	    type: basic
+//	    priority: 1
+//	    arrival: 50ms
+//	    activations: 3
+//	    period: 100ms
+//	    events:
+//	      - {at: 0, set: 1, task: sensor}
+type Set struct {
+	Tasks []Spec `yaml:"tasks"`
+}
+
+// Spec describes a task and its activations. Unset fields select the task
+// defaults.
+type Spec struct {
+	Name     string            `yaml:"name"`
+	Type     task.TaskType     `yaml:"type"`
+	Priority task.TaskPriority `yaml:"priority"`
+	// Arrival is the time after the start of the feed the first activation
+	// is added to the scheduler.
+	Arrival time.Duration `yaml:"arrival"`
+	// Activations is the number of task instances, one if unset. They arrive
+	// Period apart.
+	Activations int           `yaml:"activations"`
+	Period      time.Duration `yaml:"period"`
+	WorkUnits   int           `yaml:"work_units"`
+	UnitTime    time.Duration `yaml:"unit_time"`
+	// Deadline is relative to the arrival of each activation.
+	Deadline time.Duration `yaml:"deadline"`
+	Affinity *int          `yaml:"affinity"`
+	Events   []Event       `yaml:"events"`
+}
+
+// Event is an event point of the task body, see task.EventPoint. Set events
+// go to the latest activation of the task named Task that has arrived.
+type Event struct {
+	At   int            `yaml:"at"`
+	Wait task.EventMask `yaml:"wait"`
+	Set  task.EventMask `yaml:"set"`
+	Task string         `yaml:"task"`
+}
+
+func (s Spec) activations() int {
+	if s.Activations == 0 {
+		return 1
+	}
+	return s.Activations
+}
+
+// Load reads a set and validates it.
+func Load(r io.Reader) (Set, error) {
+	var set Set
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&set); err != nil && !errors.Is(err, io.EOF) {
+		return Set{}, err
+	}
+	return set, set.Validate()
+}
+
+func LoadFile(path string) (Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Set{}, err
+	}
+	defer f.Close()
+	set, err := Load(f)
+	if err != nil {
+		return Set{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Validate checks the names, arrivals and event targets of the set. The task
+// options are checked when the tasks are built.
+func (s Set) Validate() error {
+	specs := make(map[string]Spec, len(s.Tasks))
+	for _, spec := range s.Tasks {
+		if spec.Name == "" {
+			return ErrMissingName
+		}
+		if _, ok := specs[spec.Name]; ok {
+			return fmt.Errorf("task %q: %w", spec.Name, ErrDuplicateName)
+		}
+		specs[spec.Name] = spec
+	}
+	for _, spec := range s.Tasks {
+		if spec.Arrival < 0 || spec.Period < 0 {
+			return fmt.Errorf("task %q: %w", spec.Name, ErrInvalidArrival)
+		}
+		if spec.Activations < 0 {
+			return fmt.Errorf("task %q: %w", spec.Name, ErrInvalidActivations)
+		}
+		for _, e := range spec.Events {
+			if e.Task == "" {
+				continue
+			}
+			target, ok := specs[e.Task]
+			if !ok {
+				return fmt.Errorf("task %q: %w: %q", spec.Name, ErrUnknownTarget, e.Task)
+			}
+			if target.Type != task.Extended {
+				return fmt.Errorf("task %q: %w: %q", spec.Name, task.ErrNotExtended, e.Task)
+			}
+		}
+	}
+	return nil
+}
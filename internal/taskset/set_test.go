@@ -0,0 +1,54 @@
+package taskset
+
+import (
+	"scheduler/internal/task"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFile(t *testing.T) {
+	set, err := LoadFile("testdata/periodic.json")
+	assert.NoError(t, err)
+	assert.Len(t, set.Tasks, 2)
+
+	trigger := set.Tasks[1]
+	assert.Equal(t, "trigger", trigger.Name)
+	assert.Equal(t, task.Basic, trigger.Type)
+	assert.Equal(t, 30*time.Millisecond, trigger.Arrival)
+	assert.Equal(t, 2, trigger.Activations)
+	assert.Equal(t, 60*time.Millisecond, trigger.Period)
+	assert.Equal(t, time.Second, trigger.Deadline)
+	assert.Equal(t, []Event{{At: 1, Set: 2, Task: "sensor"}}, trigger.Events)
+
+	_, err = LoadFile("testdata/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{name: "Empty", data: ""},
+		{name: "Missing name", data: "tasks: [{type: basic}]", err: ErrMissingName},
+		{name: "Duplicate name", data: "tasks: [{name: a, type: basic}, {name: a, type: basic}]", err: ErrDuplicateName},
+		{name: "Negative arrival", data: "tasks: [{name: a, type: basic, arrival: -1s}]", err: ErrInvalidArrival},
+		{name: "Negative period", data: "tasks: [{name: a, type: basic, period: -1s}]", err: ErrInvalidArrival},
+		{name: "Negative activations", data: "tasks: [{name: a, type: basic, activations: -1}]", err: ErrInvalidActivations},
+		{name: "Unknown target", data: "tasks: [{name: a, type: basic, events: [{set: 1, task: b}]}]", err: ErrUnknownTarget},
+		{name: "Basic target", data: "tasks: [{name: a, type: basic, events: [{set: 1, task: a}]}]", err: task.ErrNotExtended},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	_, err := Load(strings.NewReader("tasks: [{name: a, priorty: 1}]"))
+	assert.ErrorContains(t, err, "priorty")
+}
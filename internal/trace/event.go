@@ -0,0 +1,40 @@
+package trace
+
+import (
+	"fmt"
+	"scheduler/internal/task"
+	"time"
+)
+
+// NoCore marks events of transitions that do not involve a core.
+const NoCore = -1
+
+// Event is a task state transition. Queues is the scheduler queues right
+// after it, for sinks that asked for them.
+type Event struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	TaskID   int               `json:"task_id"`
+	Priority task.TaskPriority `json:"priority"`
+	// From is empty for tasks added to the scheduler.
+	From   task.TaskState `json:"from,omitempty"`
+	To     task.TaskState `json:"to"`
+	Core   int            `json:"core"`
+	Queues *Queues        `json:"queues,omitempty"`
+}
+
+// Queues is a copy of the scheduler queues. Ready and Waiting are indexed by
+// priority; Running is indexed by core and holds nil for idle cores.
+type Queues struct {
+	Running   []*task.Snapshot  `json:"running"`
+	Ready     [][]task.Snapshot `json:"ready"`
+	Suspended []task.Snapshot   `json:"suspended"`
+	Waiting   [][]task.Snapshot `json:"waiting"`
+}
+
+func (e Event) String() string {
+	if e.From == "" {
+		return fmt.Sprintf("task -> %s | ID=%d", e.To, e.TaskID)
+	}
+	return fmt.Sprintf("task %s -> %s | ID=%d", e.From, e.To, e.TaskID)
+}
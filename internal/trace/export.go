@@ -0,0 +1,136 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"scheduler/internal/task"
+	"slices"
+	"time"
+)
+
+// WriteJSONL writes one event per line.
+func WriteJSONL(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads events written by WriteJSONL or a scheduler WriterSink.
+func ReadJSONL(r io.Reader) ([]Event, error) {
+	res := make([]Event, 0)
+	dec := json.NewDecoder(r)
+	for {
+		var e Event
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+}
+
+const (
+	corePid = 0
+	taskPid = 1
+)
+
+type chromeEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type chromeTrace struct {
+	TraceEvents     []chromeEvent `json:"traceEvents"`
+	DisplayTimeUnit string        `json:"displayTimeUnit"`
+}
+
+type interval struct {
+	name  string
+	start time.Time
+	args  map[string]any
+}
+
+// WriteChrome writes events in the Chrome trace-event format, which Perfetto
+// and chrome://tracing show as a Gantt chart. Every core gets a track with the
+// tasks it ran and every task a track with the states it went through.
+func WriteChrome(w io.Writer, events []Event) error {
+	res := chromeTrace{TraceEvents: make([]chromeEvent, 0), DisplayTimeUnit: "ms"}
+	if len(events) == 0 {
+		return json.NewEncoder(w).Encode(res)
+	}
+	start, end := events[0].Time, events[len(events)-1].Time
+	us := func(t time.Time) float64 {
+		return float64(t.Sub(start).Nanoseconds()) / 1e3
+	}
+	spans := make([]chromeEvent, 0)
+	slice := func(pid, tid int, iv interval, until time.Time) {
+		spans = append(spans, chromeEvent{
+			Name: iv.name, Cat: "state", Ph: "X", Ts: us(iv.start), Dur: us(until) - us(iv.start),
+			Pid: pid, Tid: tid, Args: iv.args,
+		})
+	}
+
+	meta := []chromeEvent{
+		{Name: "process_name", Ph: "M", Pid: corePid, Args: map[string]any{"name": "cores"}},
+		{Name: "process_name", Ph: "M", Pid: taskPid, Args: map[string]any{"name": "tasks"}},
+	}
+	cores := make(map[int]*interval)
+	tasks := make(map[int]*interval)
+	for _, e := range events {
+		if _, ok := tasks[e.TaskID]; !ok {
+			meta = append(meta, chromeEvent{Name: "thread_name", Ph: "M", Pid: taskPid, Tid: e.TaskID,
+				Args: map[string]any{"name": fmt.Sprintf("task-%d (p%d)", e.TaskID, e.Priority)}})
+		} else if iv := tasks[e.TaskID]; iv != nil {
+			slice(taskPid, e.TaskID, *iv, e.Time)
+		}
+		tasks[e.TaskID] = &interval{name: string(e.To), start: e.Time}
+
+		if e.Core == NoCore {
+			continue
+		}
+		if _, ok := cores[e.Core]; !ok {
+			meta = append(meta, chromeEvent{Name: "thread_name", Ph: "M", Pid: corePid, Tid: e.Core,
+				Args: map[string]any{"name": fmt.Sprintf("core %d", e.Core)}})
+		}
+		if e.From == task.Running && cores[e.Core] != nil {
+			slice(corePid, e.Core, *cores[e.Core], e.Time)
+			cores[e.Core] = nil
+		}
+		if e.To == task.Running {
+			cores[e.Core] = &interval{
+				name:  fmt.Sprintf("task-%d", e.TaskID),
+				start: e.Time,
+				args:  map[string]any{"task_id": e.TaskID, "priority": e.Priority},
+			}
+		}
+	}
+	// Close what is still open at the end of the trace.
+	for _, c := range slices.Sorted(maps.Keys(cores)) {
+		if iv := cores[c]; iv != nil {
+			slice(corePid, c, *iv, end)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(tasks)) {
+		if iv := tasks[id]; iv.name != string(task.Suspended) {
+			slice(taskPid, id, *iv, end)
+		}
+	}
+
+	res.TraceEvents = append(meta, spans...)
+	return json.NewEncoder(w).Encode(res)
+}
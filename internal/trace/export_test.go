@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"scheduler/internal/task"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents() []Event {
+	start := time.Unix(0, 0).UTC()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	transitions := []struct {
+		ms       int
+		from, to task.TaskState
+		core     int
+	}{
+		{0, "", task.Suspended, NoCore},
+		{10, task.Suspended, task.Ready, NoCore},
+		{20, task.Ready, task.Running, 0},
+		{30, task.Running, task.Waiting, 0},
+		{40, task.Waiting, task.Ready, NoCore},
+		{50, task.Ready, task.Running, 1},
+		{70, task.Running, task.Suspended, 1},
+	}
+	res := make([]Event, 0, len(transitions))
+	for i, v := range transitions {
+		res = append(res, Event{
+			Seq: uint64(i + 1), Time: at(v.ms), TaskID: 1, Priority: task.P2,
+			From: v.from, To: v.to, Core: v.core,
+			Queues: &Queues{Running: []*task.Snapshot{nil, nil}, Ready: [][]task.Snapshot{}},
+		})
+	}
+	return res
+}
+
+func TestEventString(t *testing.T) {
+	events := testEvents()
+	assert.Equal(t, "task -> suspended | ID=1", events[0].String())
+	assert.Equal(t, "task ready -> running | ID=1", events[2].String())
+}
+
+func TestWriteJSONL(t *testing.T) {
+	events := testEvents()
+	var buf bytes.Buffer
+	assert.NoError(t, WriteJSONL(&buf, events))
+
+	dec := json.NewDecoder(&buf)
+	for _, expected := range events {
+		var e Event
+		assert.NoError(t, dec.Decode(&e))
+		assert.Equal(t, expected, e)
+	}
+	assert.False(t, dec.More())
+}
+
+func TestReadJSONL(t *testing.T) {
+	events := testEvents()
+	var buf bytes.Buffer
+	assert.NoError(t, WriteJSONL(&buf, events))
+
+	got, err := ReadJSONL(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, events, got)
+
+	got, err = ReadJSONL(bytes.NewBufferString(""))
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = ReadJSONL(bytes.NewBufferString("{\"seq\": 1}\n{"))
+	assert.Error(t, err)
+}
+
+func TestWriteChrome(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteChrome(&buf, testEvents()))
+
+	var res chromeTrace
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, "ms", res.DisplayTimeUnit)
+
+	spans := make(map[[2]int][]chromeEvent)
+	threads := make(map[[2]int]string)
+	for _, e := range res.TraceEvents {
+		switch e.Ph {
+		case "X":
+			key := [2]int{e.Pid, e.Tid}
+			spans[key] = append(spans[key], e)
+		case "M":
+			if e.Name == "thread_name" {
+				threads[[2]int{e.Pid, e.Tid}] = e.Args["name"].(string)
+			}
+		}
+	}
+
+	assert.Equal(t, "core 0", threads[[2]int{corePid, 0}])
+	assert.Equal(t, "core 1", threads[[2]int{corePid, 1}])
+	assert.Equal(t, "task-1 (p2)", threads[[2]int{taskPid, 1}])
+
+	core0 := spans[[2]int{corePid, 0}]
+	assert.Len(t, core0, 1)
+	assert.Equal(t, "task-1", core0[0].Name)
+	assert.Equal(t, 20000.0, core0[0].Ts)
+	assert.Equal(t, 10000.0, core0[0].Dur)
+	core1 := spans[[2]int{corePid, 1}]
+	assert.Len(t, core1, 1)
+	assert.Equal(t, 50000.0, core1[0].Ts)
+	assert.Equal(t, 20000.0, core1[0].Dur)
+
+	states := make([]string, 0)
+	for _, e := range spans[[2]int{taskPid, 1}] {
+		states = append(states, e.Name)
+	}
+	assert.Equal(t, []string{"suspended", "ready", "running", "waiting", "ready", "running"}, states)
+}
+
+func TestWriteChrome_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteChrome(&buf, nil))
+	assert.JSONEq(t, `{"traceEvents":[],"displayTimeUnit":"ms"}`, buf.String())
+}
@@ -0,0 +1,5 @@
+package validate
+
+import "errors"
+
+var ErrUnknownInvariant = errors.New("unknown invariant")
@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"fmt"
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"slices"
+)
+
+// transitions are the transitions a task may make. Cancelled tasks go from
+// their queue straight to suspended.
+var transitions = map[task.TaskState][]task.TaskState{
+	"":             {task.Suspended},
+	task.Suspended: {task.Ready, task.Suspended},
+	task.Ready:     {task.Running, task.Suspended},
+	task.Running:   {task.Ready, task.Waiting, task.Suspended},
+	task.Waiting:   {task.Ready, task.Suspended},
+}
+
+// Default returns the invariants every trace keeps, with maxReady the
+// capacity of all ready queues together.
+func Default(maxReady int) []Invariant {
+	return []Invariant{Sequence, AddedSuspended, Transitions, ReadyLimit(maxReady), RunningLimit}
+}
+
+// All returns all invariants, including those that hold only for some
+// configurations.
+func All(maxReady int) []Invariant {
+	return append(Default(maxReady), ReadyPosition)
+}
+
+// Named returns the invariants of All with the given names, in that order.
+func Named(maxReady int, names ...string) ([]Invariant, error) {
+	all := All(maxReady)
+	res := make([]Invariant, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(all, func(inv Invariant) bool { return inv.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownInvariant, name)
+		}
+		res = append(res, all[i])
+	}
+	return res, nil
+}
+
+// Sequence checks that sequence numbers increase by one, so no event is
+// missing. A trace may start at any number, as ring sinks keep the last
+// events only.
+var Sequence = Invariant{Name: "sequence", New: func() Check {
+	var seq uint64
+	first := true
+	return func(e trace.Event, report Report) {
+		switch {
+		case first:
+		case e.Seq <= seq:
+			report("sequence number does not follow %d", seq)
+		case e.Seq == seq+2:
+			report("event %d is missing", seq+1)
+		case e.Seq > seq+2:
+			report("events %d to %d are missing", seq+1, e.Seq-1)
+		}
+		first, seq = false, e.Seq
+	}
+}}
+
+// AddedSuspended checks that tasks are first seen being added as suspended.
+var AddedSuspended = Invariant{Name: "added-suspended", New: func() Check {
+	seen := make(map[int]bool)
+	return func(e trace.Event, report Report) {
+		if !seen[e.TaskID] && e.From != "" {
+			report("task first seen in %s, not added as suspended", e.From)
+		}
+		seen[e.TaskID] = true
+	}
+}}
+
+// Transitions checks that tasks move from the state they are in along the
+// allowed transitions.
+var Transitions = Invariant{Name: "transitions", New: func() Check {
+	states := make(map[int]task.TaskState)
+	return func(e trace.Event, report Report) {
+		state, seen := states[e.TaskID]
+		switch {
+		case seen && e.From != state:
+			report("transition from %s, but the task was %s", e.From, state)
+		case !slices.Contains(transitions[e.From], e.To):
+			report("invalid transition from %q to %s", e.From, e.To)
+		}
+		states[e.TaskID] = e.To
+	}
+}}
+
+// ReadyLimit checks that the ready queues hold at most max tasks together.
+// Like the other queue checks, it skips events without queues.
+func ReadyLimit(max int) Invariant {
+	return Stateless("ready-limit", func(e trace.Event, report Report) {
+		if e.Queues == nil {
+			return
+		}
+		ready := 0
+		for _, queue := range e.Queues.Ready {
+			ready += len(queue)
+		}
+		if ready > max {
+			report("%d ready tasks exceed the limit of %d", ready, max)
+		}
+	})
+}
+
+// RunningLimit checks that no more tasks are running than there are cores.
+var RunningLimit = Stateless("running-limit", func(e trace.Event, report Report) {
+	if e.Queues == nil || e.Queues.Ready == nil {
+		return
+	}
+	running := 0
+	for _, queues := range [][][]task.Snapshot{e.Queues.Ready, e.Queues.Waiting, {e.Queues.Suspended}} {
+		for _, queue := range queues {
+			for _, t := range queue {
+				if t.State == task.Running {
+					running++
+				}
+			}
+		}
+	}
+	for _, t := range e.Queues.Running {
+		if t != nil && t.State == task.Running {
+			running++
+		}
+	}
+	if running > len(e.Queues.Running) {
+		report("%d running tasks exceed %d cores", running, len(e.Queues.Running))
+	}
+})
+
+// ReadyPosition checks that preempted and released tasks go to the front of
+// their ready queue and admitted tasks to the back. It holds for shared
+// queues only: traces merge partitioned queues by priority.
+var ReadyPosition = Stateless("ready-position", func(e trace.Event, report Report) {
+	if e.To != task.Ready || e.Queues == nil || int(e.Priority) >= len(e.Queues.Ready) {
+		return
+	}
+	queue := e.Queues.Ready[e.Priority]
+	switch e.From {
+	case task.Waiting, task.Running:
+		if len(queue) == 0 || queue[0].ID != e.TaskID {
+			report("task is not at the front of its ready queue")
+		}
+	case task.Suspended:
+		// The task may already be running again.
+		if !slices.ContainsFunc(queue, func(v task.Snapshot) bool { return v.ID == e.TaskID }) {
+			return
+		}
+		if queue[len(queue)-1].ID != e.TaskID {
+			report("task is not at the back of its ready queue")
+		}
+	}
+})
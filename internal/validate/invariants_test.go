@@ -0,0 +1,90 @@
+package validate
+
+import (
+	"scheduler/internal/task"
+	"scheduler/internal/trace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func messages(res []Violation) []string {
+	out := make([]string, 0, len(res))
+	for _, v := range res {
+		out = append(out, v.Invariant+": "+v.Message)
+	}
+	return out
+}
+
+func TestDefault_Transitions(t *testing.T) {
+	at := time.Unix(0, 0)
+	events := []trace.Event{
+		{Seq: 1, Time: at, TaskID: 1, To: task.Suspended},
+		{Seq: 2, Time: at, TaskID: 1, From: task.Suspended, To: task.Running},
+		{Seq: 2, Time: at, TaskID: 2, From: task.Ready, To: task.Running},
+		{Seq: 3, Time: at, TaskID: 1, From: task.Waiting, To: task.Ready},
+	}
+	assert.Equal(t, []string{
+		`transitions: invalid transition from "suspended" to running`,
+		"sequence: sequence number does not follow 2",
+		"added-suspended: task first seen in ready, not added as suspended",
+		"transitions: transition from waiting, but the task was running",
+	}, messages(Trace(events, Default(1)...)))
+}
+
+func TestDefault_Queues(t *testing.T) {
+	running := task.Snapshot{ID: 1, State: task.Running}
+	ready := task.Snapshot{ID: 2, State: task.Ready}
+	e := trace.Event{Seq: 1, TaskID: 1, To: task.Suspended, Queues: &trace.Queues{
+		Ready:     [][]task.Snapshot{{ready, ready}, {running}},
+		Waiting:   [][]task.Snapshot{{}, {}},
+		Suspended: []task.Snapshot{running},
+		Running:   []*task.Snapshot{&running, nil},
+	}}
+	assert.Equal(t, []string{
+		"ready-limit: 3 ready tasks exceed the limit of 2",
+		"running-limit: 3 running tasks exceed 2 cores",
+	}, messages(Trace([]trace.Event{e}, Default(2)...)))
+
+	// Events with empty or no queues are not checked.
+	e.Queues = &trace.Queues{}
+	assert.Empty(t, Trace([]trace.Event{e}, Default(0)...))
+	e.Queues = nil
+	assert.Empty(t, Trace([]trace.Event{e}, append(Default(0), ReadyPosition)...))
+}
+
+func TestReadyPosition(t *testing.T) {
+	a, b := task.Snapshot{ID: 1}, task.Snapshot{ID: 2}
+	queues := &trace.Queues{Ready: [][]task.Snapshot{{}, {a, b}}}
+	events := []trace.Event{
+		{Seq: 1, TaskID: 1, Priority: task.P1, From: task.Running, To: task.Ready, Queues: queues},
+		{Seq: 2, TaskID: 2, Priority: task.P1, From: task.Waiting, To: task.Ready, Queues: queues},
+		{Seq: 3, TaskID: 2, Priority: task.P1, From: task.Suspended, To: task.Ready, Queues: queues},
+		{Seq: 4, TaskID: 1, Priority: task.P1, From: task.Suspended, To: task.Ready, Queues: queues},
+		{Seq: 5, TaskID: 3, Priority: task.P1, From: task.Suspended, To: task.Ready, Queues: queues},
+	}
+	res := Trace(events, ReadyPosition)
+	assert.Equal(t, []string{
+		"ready-position: task is not at the front of its ready queue",
+		"ready-position: task is not at the back of its ready queue",
+	}, messages(res))
+	assert.Equal(t, []uint64{2, 4}, []uint64{res[0].Seq, res[1].Seq})
+}
+
+func TestSequence_Gaps(t *testing.T) {
+	events := []trace.Event{{Seq: 7}, {Seq: 8}, {Seq: 11}, {Seq: 13}}
+	res := Trace(events, Sequence)
+	assert.Equal(t, []string{"sequence: events 9 to 10 are missing", "sequence: event 12 is missing"}, messages(res))
+	assert.Equal(t, uint64(11), res[0].Seq)
+}
+
+func TestNamed(t *testing.T) {
+	invariants, err := Named(1, "ready-position", "sequence")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ready-position", "sequence"}, []string{invariants[0].Name, invariants[1].Name})
+	assert.Len(t, All(1), len(Default(1))+1)
+
+	_, err = Named(1, "sequence", "fairness")
+	assert.ErrorIs(t, err, ErrUnknownInvariant)
+}
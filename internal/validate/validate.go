@@ -0,0 +1,92 @@
+// Package validate checks scheduler traces against invariants. It runs on
+// traces loaded from files as well as on live runs, as a dump sink.
+package validate
+
+import (
+	"fmt"
+	"scheduler/internal/trace"
+	"sync"
+	"time"
+)
+
+// Violation is an event that breaks an invariant.
+type Violation struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	TaskID    int       `json:"task_id"`
+	Invariant string    `json:"invariant"`
+	Message   string    `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("seq=%d time=%s task=%d %s: %s", v.Seq, v.Time.Format(time.RFC3339Nano), v.TaskID, v.Invariant, v.Message)
+}
+
+// Report reports a violation by the event being checked.
+type Report func(format string, args ...any)
+
+// Check checks the events of one trace in order.
+type Check func(e trace.Event, report Report)
+
+// Invariant makes a new check for every trace, so checks can keep state
+// between the events of a trace.
+type Invariant struct {
+	Name string
+	New  func() Check
+}
+
+// Stateless makes an invariant of a check that looks at single events.
+func Stateless(name string, check Check) Invariant {
+	return Invariant{Name: name, New: func() Check { return check }}
+}
+
+// Validator checks events as they come. It is a dump sink, so it can check
+// a live run.
+type Validator struct {
+	mu         sync.Mutex
+	names      []string
+	checks     []Check
+	violations []Violation
+}
+
+func New(invariants ...Invariant) *Validator {
+	v := Validator{violations: make([]Violation, 0)}
+	for _, inv := range invariants {
+		v.names = append(v.names, inv.Name)
+		v.checks = append(v.checks, inv.New())
+	}
+	return &v
+}
+
+// Dump checks e against all invariants.
+func (v *Validator) Dump(e trace.Event) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, check := range v.checks {
+		check(e, func(format string, args ...any) {
+			v.violations = append(v.violations, Violation{
+				Seq:       e.Seq,
+				Time:      e.Time,
+				TaskID:    e.TaskID,
+				Invariant: v.names[i],
+				Message:   fmt.Sprintf(format, args...),
+			})
+		})
+	}
+}
+
+// Violations returns all violations so far in event order.
+func (v *Validator) Violations() []Violation {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]Violation(nil), v.violations...)
+}
+
+// Trace returns all violations of events in event order.
+func Trace(events []trace.Event, invariants ...Invariant) []Violation {
+	v := New(invariants...)
+	for _, e := range events {
+		v.Dump(e)
+	}
+	return v.Violations()
+}
@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"scheduler/internal/trace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace_Custom(t *testing.T) {
+	at := time.Unix(0, 0).UTC()
+	events := []trace.Event{
+		{Seq: 1, Time: at, TaskID: 1},
+		{Seq: 2, Time: at.Add(time.Second), TaskID: 2},
+		{Seq: 3, Time: at.Add(2 * time.Second), TaskID: 3},
+	}
+	odd := Stateless("odd", func(e trace.Event, report Report) {
+		if e.TaskID%2 == 1 {
+			report("task %d is odd", e.TaskID)
+		}
+	})
+	count := Invariant{Name: "count", New: func() Check {
+		n := 0
+		return func(e trace.Event, report Report) {
+			if n++; n > 2 {
+				report("more than 2 events")
+			}
+		}
+	}}
+
+	res := Trace(events, odd, count)
+	assert.Equal(t, []Violation{
+		{Seq: 1, Time: at, TaskID: 1, Invariant: "odd", Message: "task 1 is odd"},
+		{Seq: 3, Time: at.Add(2 * time.Second), TaskID: 3, Invariant: "odd", Message: "task 3 is odd"},
+		{Seq: 3, Time: at.Add(2 * time.Second), TaskID: 3, Invariant: "count", Message: "more than 2 events"},
+	}, res)
+	assert.Equal(t, "seq=1 time=1970-01-01T00:00:00Z task=1 odd: task 1 is odd", res[0].String())
+
+	// Every trace gets new checks.
+	assert.Len(t, Trace(events, count), 1)
+	assert.Empty(t, Trace(nil, odd))
+}
+
+func TestValidator_Dump(t *testing.T) {
+	v := New(Sequence)
+	v.Dump(trace.Event{Seq: 2})
+	assert.Empty(t, v.Violations())
+	v.Dump(trace.Event{Seq: 1})
+	res := v.Violations()
+	assert.Len(t, res, 1)
+
+	// Violations is a copy.
+	res[0].Message = ""
+	assert.Equal(t, "sequence number does not follow 2", v.Violations()[0].Message)
+}